@@ -27,8 +27,66 @@ const (
 // This is separate from domain.Result to decouple the CLI from server internals.
 type DomainResult struct {
 	Domain    string `json:"domain"`
+	Unicode   string `json:"unicode,omitempty"`
 	Available bool   `json:"available"`
+	Status    string `json:"status,omitempty"`
 	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+
+	// Confidence and Attempts are passed through untouched for --json output
+	Confidence string          `json:"confidence,omitempty"`
+	Attempts   json.RawMessage `json:"attempts,omitempty"`
+}
+
+// displayName returns the name to print for a result: the Unicode form of
+// internationalized names (e.g. "münchen.de" rather than "xn--mnchen-3ya.de").
+func displayName(r DomainResult) string {
+	if r.Unicode != "" {
+		return r.Unicode
+	}
+	return r.Domain
+}
+
+// statusLabel returns the display label for a result, showing lifecycle
+// states like "REDEMPTION PERIOD" instead of a plain AVAILABLE/TAKEN.
+func statusLabel(r DomainResult, fallback string) string {
+	switch r.Status {
+	case "", "available", "taken", "unknown", "error":
+		return fallback
+	default:
+		return strings.ToUpper(strings.ReplaceAll(r.Status, "_", " "))
+	}
+}
+
+// errorExitCodes maps the server's error codes to process exit codes, so
+// scripts can tell failures apart without parsing messages.
+// 0 means a domain is available and 1 that it is taken.
+var errorExitCodes = map[string]int{
+	"invalid_domain":       3,
+	"unsupported_tld":      4,
+	"timeout":              5,
+	"rate_limited":         6,
+	"upstream_unavailable": 7,
+	"parse_error":          8,
+	"cancelled":            9,
+}
+
+// exitCodeUnknownError is used for errors without a recognized code.
+const exitCodeUnknownError = 10
+
+// exitCodeFor returns the exit code reporting a result: 0 available,
+// 1 taken, or the error's code from errorExitCodes.
+func exitCodeFor(r DomainResult) int {
+	switch {
+	case r.Available:
+		return 0
+	case r.Error == "":
+		return 1
+	}
+	if code, ok := errorExitCodes[r.ErrorCode]; ok {
+		return code
+	}
+	return exitCodeUnknownError
 }
 
 type CheckResponse struct {
@@ -52,7 +110,8 @@ Options:
   -s <server>    Server URL (default: %s)
   -j             Output raw JSON
   -a             Show only available domains
-  -q             Quiet mode (exit code only: 0=available, 1=taken/error)
+  -q             Quiet mode (exit code only, see below)
+  -c             Consensus mode (query every source, flag disagreements)
   -h             Show this help
 
 Examples:
@@ -61,6 +120,17 @@ Examples:
   domaincheck -f domains.txt
   echo -e "trucore\npriment\naxient" | domaincheck -
   domaincheck -a trucore priment axient   # Only show available
+  domaincheck -c trucore.com              # Verify before purchasing
+
+Exit codes:
+  0  available (at least one domain)
+  1  taken (no domain available)
+  3  invalid domain        7  upstream unavailable
+  4  unsupported TLD       8  unparseable upstream response
+  5  timeout               9  cancelled
+  6  rate limited         10  other error
+  With several domains and none available, the first failed domain's error
+  code is used. Quiet mode (-q) reports the first domain only.
 
 `, defaultServer)
 	os.Exit(1)
@@ -75,6 +145,7 @@ func main() {
 	jsonOutput := false
 	onlyAvailable := false
 	quiet := false
+	consensus := false
 	var domains []string
 	var inputFile string
 
@@ -103,6 +174,8 @@ func main() {
 			onlyAvailable = true
 		case "-q", "--quiet":
 			quiet = true
+		case "-c", "--consensus":
+			consensus = true
 		case "-f", "--file":
 			if i+1 >= len(args) {
 				fmt.Fprintln(os.Stderr, "Error: -f requires filename")
@@ -161,8 +234,9 @@ func main() {
 	for _, d := range domains {
 		normalized, err := domain.Normalize(d)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: invalid domain format: %s (%v)\n", d, err)
-			os.Exit(1)
+			// err names the rule the input breaks, e.g. "label longer than 63 characters"
+			fmt.Fprintf(os.Stderr, "Error: %q: %v\n", d, err)
+			os.Exit(errorExitCodes["invalid_domain"])
 		}
 		normalizedDomains = append(normalizedDomains, normalized.Full)
 	}
@@ -183,8 +257,12 @@ func main() {
 	if timeout > maxTimeout {
 		timeout = maxTimeout
 	}
+	endpoint := server + "/check"
+	if consensus {
+		endpoint += "?mode=consensus"
+	}
 	client := &http.Client{Timeout: time.Duration(timeout) * time.Second}
-	resp, err := client.Post(server+"/check", "application/json", bytes.NewBuffer(jsonData))
+	resp, err := client.Post(endpoint, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error connecting to server: %v\n", err)
 		fmt.Fprintln(os.Stderr, "Make sure the server is running: go run cmd/server/main.go")
@@ -213,8 +291,8 @@ func main() {
 	// Output results
 	if quiet {
 		// Exit code based on first domain availability
-		if len(result.Results) > 0 && result.Results[0].Available {
-			os.Exit(0)
+		if len(result.Results) > 0 {
+			os.Exit(exitCodeFor(result.Results[0]))
 		}
 		os.Exit(1)
 	}
@@ -255,11 +333,15 @@ func main() {
 		}
 
 		if r.Available {
-			fmt.Printf("✓ %-*s AVAILABLE\n", domainDisplayWidth, r.Domain)
+			fmt.Printf("✓ %-*s %s\n", domainDisplayWidth, displayName(r), statusLabel(r, "AVAILABLE"))
 		} else if r.Error != "" {
-			fmt.Printf("? %-*s ERROR: %s\n", domainDisplayWidth, r.Domain, r.Error)
+			if r.ErrorCode != "" {
+				fmt.Printf("? %-*s ERROR [%s]: %s\n", domainDisplayWidth, displayName(r), r.ErrorCode, r.Error)
+			} else {
+				fmt.Printf("? %-*s ERROR: %s\n", domainDisplayWidth, displayName(r), r.Error)
+			}
 		} else {
-			fmt.Printf("✗ %-*s TAKEN\n", domainDisplayWidth, r.Domain)
+			fmt.Printf("✗ %-*s %s\n", domainDisplayWidth, displayName(r), statusLabel(r, "TAKEN"))
 		}
 	}
 
@@ -269,8 +351,14 @@ func main() {
 			result.Checked, result.Available, result.Taken, result.Errors)
 	}
 
-	// Exit with error if no domains available
+	// Exit with error if no domains available, reporting why the first
+	// failed domain failed (taken otherwise)
 	if result.Available == 0 {
+		for _, r := range result.Results {
+			if r.Error != "" {
+				os.Exit(exitCodeFor(r))
+			}
+		}
 		os.Exit(1)
 	}
 }
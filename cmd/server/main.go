@@ -7,11 +7,17 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"domaincheck/internal/checker"
 	"domaincheck/internal/domain"
 	"domaincheck/internal/server"
+	"domaincheck/internal/store"
 )
 
 func main() {
@@ -28,11 +34,183 @@ func main() {
 		server.SetBaseURL(baseURL)
 	}
 
+	// Configure the Public Suffix List used to find the registrable part of
+	// names like "shop.example.co.uk". Without PUBLIC_SUFFIX_LIST the embedded
+	// ICANN section is used; point it at a downloaded public_suffix_list.dat
+	// to pick up rules added since the build.
+	if path := os.Getenv("PUBLIC_SUFFIX_LIST"); path != "" {
+		list, err := domain.LoadSuffixList(path)
+		if err != nil {
+			fatalf("Public suffix list: %v", err)
+		}
+		domain.SetSuffixList(list)
+		log.Printf("Public suffix list loaded: %d rules", list.Len())
+	}
+
+	// Configure the registry of delegated TLDs. Names under other TLDs are
+	// rejected before any lookup. Without TLD_REGISTRY the embedded snapshot
+	// is used; point it at a fresh copy of IANA's tlds-alpha-by-domain.txt to
+	// pick up new delegations.
+	if path := os.Getenv("TLD_REGISTRY"); path != "" {
+		registry, err := domain.LoadTLDRegistry(path)
+		if err != nil {
+			fatalf("TLD registry: %v", err)
+		}
+		domain.SetTLDRegistry(registry)
+		log.Printf("TLD registry loaded: %d TLDs (version %s)", registry.Len(), registry.Version())
+	}
+
+	// Configure the RDAP bootstrap registry (RFC 9224).
+	// By default the official registry is fetched from IANA at startup, so
+	// every TLD with RDAP is routed there; the embedded snapshot is only a
+	// fallback. RDAP_BOOTSTRAP_URL=embedded skips the fetch.
+	bootstrapCfg := checker.BootstrapConfig{
+		File:      os.Getenv("RDAP_BOOTSTRAP_FILE"),
+		URL:       os.Getenv("RDAP_BOOTSTRAP_URL"),
+		CachePath: os.Getenv("RDAP_BOOTSTRAP_CACHE"),
+	}
+	switch bootstrapCfg.URL {
+	case "", "iana":
+		bootstrapCfg.URL = checker.IANABootstrapURL
+	case "embedded":
+		bootstrapCfg.URL = ""
+	}
+	b, err := checker.LoadBootstrap(context.Background(), bootstrapCfg)
+	if err != nil {
+		log.Printf("RDAP bootstrap: %v", err)
+	}
+	checker.SetBootstrap(b)
+	published := b.Publication()
+	if published == "" {
+		published = "unknown"
+	}
+	log.Printf("RDAP bootstrap loaded: %d TLDs (published %s)", b.Len(), published)
+
+	// Configure per-upstream rate limits, in queries per second per host.
+	// Defaults: RDAP 5/s, WHOIS 1/s, DNS 50/s; a negative rate disables limiting.
+	checker.SetRateLimiter(checker.NewRateLimiter(checker.RateLimitConfig{
+		RDAP:  checker.RateLimit{Rate: envFloat("RATE_LIMIT_RDAP")},
+		WHOIS: checker.RateLimit{Rate: envFloat("RATE_LIMIT_WHOIS")},
+		DNS:   checker.RateLimit{Rate: envFloat("RATE_LIMIT_DNS")},
+	}))
+
+	// Configure retries of transient upstream failures and the per-upstream
+	// circuit breakers. Defaults: 3 tries; breakers open after 5 consecutive
+	// failures for 30s. BREAKER_THRESHOLD=-1 disables breakers.
+	checker.SetRetryConfig(checker.RetryConfig{Attempts: envInt("RETRY_ATTEMPTS")})
+	checker.SetBreakers(checker.NewBreakers(checker.BreakerConfig{
+		Threshold: envInt("BREAKER_THRESHOLD"),
+		Cooldown:  envDuration("BREAKER_COOLDOWN"),
+	}))
+
+	// Configure the persistent result store. When STORE_PATH is set, every
+	// checked result is appended to that file and the cache is warmed from it,
+	// so results survive restarts.
+	var resultStore store.Store
+	if path := os.Getenv("STORE_PATH"); path != "" {
+		fs, err := store.Open(path)
+		if err != nil {
+			fatalf("Result store: %v", err)
+		}
+		atExit(func() {
+			if err := fs.Close(); err != nil {
+				log.Printf("Result store: %v", err)
+			}
+		})
+		resultStore = fs
+		server.SetStore(fs)
+		log.Printf("Result store opened: %s (%d domains)", path, fs.Len())
+	}
+
+	// Configure the result cache. Sizes and TTLs default to 10000 entries,
+	// 1h for taken, 5m for available and 30s for errors; CACHE_SIZE=0 disables it.
+	if size := os.Getenv("CACHE_SIZE"); size == "0" {
+		checker.SetCache(nil)
+		log.Printf("Result cache disabled")
+	} else {
+		cacheCfg := checker.CacheConfig{
+			TakenTTL:     envDuration("CACHE_TAKEN_TTL"),
+			AvailableTTL: envDuration("CACHE_AVAILABLE_TTL"),
+			ErrorTTL:     envDuration("CACHE_ERROR_TTL"),
+			Store:        resultStore,
+		}
+		if size != "" {
+			n, err := strconv.Atoi(size)
+			if err != nil {
+				fatalf("Invalid CACHE_SIZE %q: %v", size, err)
+			}
+			cacheCfg.Size = n
+		}
+		checker.SetCache(checker.NewCache(cacheCfg))
+	}
+
+	// Configure the offline zone-file source. ZONE_FILES (comma-separated
+	// paths of plain or gzipped zone files) are indexed into ZONE_INDEX_DIR at
+	// startup; an existing index there is used as is when ZONE_FILES is unset.
+	var zones *checker.ZoneIndex
+	if dir := os.Getenv("ZONE_INDEX_DIR"); dir != "" {
+		if files := os.Getenv("ZONE_FILES"); files != "" {
+			infos, err := checker.BuildZoneIndex(dir, strings.Split(files, ",")...)
+			if err != nil {
+				fatalf("Zone index: %v", err)
+			}
+			for _, info := range infos {
+				log.Printf("Zone index built: %s (%d names, serial %d)", info.Zone, info.Names, info.Serial)
+			}
+		}
+		idx, err := checker.OpenZoneIndex(dir)
+		if err != nil {
+			fatalf("Zone index: %v", err)
+		}
+		defer idx.Close()
+		zones = idx
+		log.Printf("Zone index opened: %s (%d zones)", dir, len(idx.Zones()))
+	}
+
+	// Configure per-stage timeouts and the User-Agent sent to RDAP servers.
+	// Defaults: DNS 3s, RDAP 10s, WHOIS 10s, "domaincheck/1.0".
+	// RECORD_DIR saves each check's upstream traffic for bug reports;
+	// REPLAY_DIR serves checks from such recordings instead of the upstreams.
+	checker.SetDefault(checker.New(
+		checker.WithDNSTimeout(envDuration("DNS_TIMEOUT")),
+		checker.WithRDAPTimeout(envDuration("RDAP_TIMEOUT")),
+		checker.WithWHOISTimeout(envDuration("WHOIS_TIMEOUT")),
+		checker.WithUserAgent(os.Getenv("USER_AGENT")),
+		checker.WithRecording(os.Getenv("RECORD_DIR")),
+		checker.WithReplay(os.Getenv("REPLAY_DIR")),
+		checker.WithZoneIndex(zones),
+	))
+
+	// Configure the shared check scheduler: at most SCHEDULER_CONCURRENCY
+	// checks (default 20) run at once across all requests, queued fairly per
+	// client with single checks ahead of bulk ones. Behind a reverse proxy,
+	// CLIENT_IP_HEADER (e.g. X-Forwarded-For) identifies the clients; it is
+	// only read from requests sent by the TRUSTED_PROXIES addresses.
+	server.SetScheduler(server.NewScheduler(server.SchedulerConfig{
+		Concurrency: envInt("SCHEDULER_CONCURRENCY"),
+	}))
+	proxies, err := server.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		fatalf("TRUSTED_PROXIES: %v", err)
+	}
+	if header := os.Getenv("CLIENT_IP_HEADER"); header != "" && len(proxies) == 0 {
+		log.Printf("CLIENT_IP_HEADER is ignored: no TRUSTED_PROXIES configured")
+	}
+	server.SetClientIPHeader(os.Getenv("CLIENT_IP_HEADER"))
+	server.SetTrustedProxies(proxies)
+
+	// The /admin endpoints are only served with ADMIN_TOKEN set, to clients
+	// sending "Authorization: Bearer $ADMIN_TOKEN"
+	server.SetAdminToken(os.Getenv("ADMIN_TOKEN"))
+
 	// Register HTTP handlers from internal/server package
 	http.HandleFunc("/", server.DashboardHandler)
 	http.HandleFunc("/check", server.CheckDomainsHandler)
 	http.HandleFunc("/check/", server.CheckSingleDomainHandler)
 	http.HandleFunc("/health", server.HealthHandler)
+	http.HandleFunc("/tlds", server.TLDsHandler)
+	http.HandleFunc("/admin/breakers", server.BreakersHandler)
+	http.HandleFunc("/admin/scheduler", server.SchedulerHandler)
 
 	log.Printf("Domain checker service starting on port %s", port)
 	log.Printf("Endpoints:")
@@ -40,6 +218,9 @@ func main() {
 	log.Printf("  POST /check         - Check multiple domains (JSON body: {\"domains\": [...]})")
 	log.Printf("  GET  /check/{domain} - Check single domain")
 	log.Printf("  GET  /health        - Health check")
+	log.Printf("  GET  /tlds          - Supported top-level domains")
+	log.Printf("  GET  /admin/breakers - Upstream circuit breaker states (needs ADMIN_TOKEN)")
+	log.Printf("  GET  /admin/scheduler - Check scheduler queues and wait times (needs ADMIN_TOKEN)")
 
 	// Interactive mode: Read from stdin for convenience
 	go func() {
@@ -49,7 +230,7 @@ func main() {
 			line := strings.TrimSpace(scanner.Text())
 			if line == "quit" || line == "exit" {
 				log.Println("Exiting interactive mode")
-				os.Exit(0)
+				exit(0)
 			}
 			if line == "" {
 				continue
@@ -58,7 +239,7 @@ func main() {
 			// Normalize and check domain
 			d, err := domain.Normalize(line)
 			if err != nil {
-				fmt.Printf("? %s - ERROR: invalid domain format\n", line)
+				fmt.Printf("? %s - ERROR: %v\n", line, err)
 				continue
 			}
 
@@ -68,17 +249,98 @@ func main() {
 				if err != nil {
 					errorMsg = err.Error()
 				}
-				fmt.Printf("? %s - ERROR: %s\n", result.Domain.Full, errorMsg)
+				fmt.Printf("? %s - ERROR: %s\n", result.Domain.Display(), errorMsg)
 			} else if result.Available {
-				fmt.Printf("✓ %s - AVAILABLE (via %s)\n", result.Domain.Full, result.Source)
+				fmt.Printf("✓ %s - %s (via %s)\n", result.Domain.Display(), strings.ToUpper(result.Status.String()), result.Source)
 			} else {
-				fmt.Printf("✗ %s - TAKEN (via %s)\n", result.Domain.Full, result.Source)
+				fmt.Printf("✗ %s - %s (via %s)\n", result.Domain.Display(), strings.ToUpper(result.Status.String()), result.Source)
 			}
 		}
 	}()
 
+	// Close the store on Ctrl-C and on termination by a process manager
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		log.Printf("Received %v, shutting down", <-signals)
+		exit(0)
+	}()
+
 	// Start HTTP server
 	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Server failed: %v", err)
+		fatalf("Server failed: %v", err)
+	}
+}
+
+// exitFuncs holds the functions registered with atExit.
+var exitFuncs struct {
+	sync.Mutex
+	fns []func()
+}
+
+// atExit registers fn to run before the process exits. Deferred calls in
+// main never run: the server only stops through os.Exit (directly or via
+// log.Fatal), so resources needing a clean close register here instead.
+func atExit(fn func()) {
+	exitFuncs.Lock()
+	exitFuncs.fns = append(exitFuncs.fns, fn)
+	exitFuncs.Unlock()
+}
+
+// exit runs the atExit functions, most recent first, then exits with code.
+// Concurrent callers wait for the first one to finish.
+func exit(code int) {
+	exitFuncs.Lock()
+	for i := len(exitFuncs.fns) - 1; i >= 0; i-- {
+		exitFuncs.fns[i]()
+	}
+	os.Exit(code)
+}
+
+// fatalf is log.Fatalf that runs the atExit functions first.
+func fatalf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+	exit(1)
+}
+
+// envDuration parses a duration environment variable such as "10m".
+// Unset variables return 0 (use the default); invalid values are fatal.
+func envDuration(name string) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		fatalf("Invalid %s %q: %v", name, value, err)
+	}
+	return d
+}
+
+// envFloat parses a numeric environment variable such as "2.5".
+// Unset variables return 0 (use the default); invalid values are fatal.
+func envFloat(name string) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		fatalf("Invalid %s %q: %v", name, value, err)
+	}
+	return f
+}
+
+// envInt parses an integer environment variable.
+// Unset variables return 0 (use the default); invalid values are fatal.
+func envInt(name string) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		fatalf("Invalid %s %q: %v", name, value, err)
 	}
+	return n
 }
@@ -1,3 +1,7 @@
 module domaincheck
 
 go 1.21
+
+require golang.org/x/net v0.25.0
+
+require golang.org/x/text v0.15.0 // indirect
@@ -0,0 +1,299 @@
+package checker
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IANABootstrapURL is the official RFC 9224 bootstrap registry for domain names.
+const IANABootstrapURL = "https://data.iana.org/rdap/dns.json"
+
+const (
+	// defaultBootstrapTTL is how long an on-disk bootstrap cache is considered fresh
+	defaultBootstrapTTL = 24 * time.Hour
+
+	// maxBootstrapSize limits the bootstrap document size (IANA's is ~100KB)
+	maxBootstrapSize = 5 << 20
+)
+
+// embeddedBootstrapJSON is the RDAP bootstrap compiled into the binary. It is
+// used when no refresh source is configured or the refresh fails, so it must
+// be a verbatim copy of IANA's dns.json, refreshed with
+// "make update-rdap-bootstrap". The copy currently checked in is a hand-picked
+// subset without a publication date; TestEmbeddedBootstrap fails until it is
+// replaced.
+//
+//go:embed data/rdap_dns.json
+var embeddedBootstrapJSON []byte
+
+// bootstrapFile is the RFC 9224 bootstrap document format.
+//
+// Each service entry is a pair of arrays: the TLDs it covers and the RDAP base URLs.
+type bootstrapFile struct {
+	Description string       `json:"description"`
+	Publication string       `json:"publication"`
+	Version     string       `json:"version"`
+	Services    [][][]string `json:"services"`
+}
+
+// Bootstrap maps TLDs to RDAP base URLs as published in an RFC 9224 registry.
+// A Bootstrap is immutable after construction and safe for concurrent use.
+type Bootstrap struct {
+	servers     map[string]string
+	publication string
+}
+
+// ParseBootstrap parses an RFC 9224 dns.json document.
+//
+// When a service lists several base URLs, the first HTTPS URL is preferred.
+// Base URLs are normalized to end with "/".
+func ParseBootstrap(data []byte) (*Bootstrap, error) {
+	var f bootstrapFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse RDAP bootstrap: %w", err)
+	}
+	if len(f.Services) == 0 {
+		return nil, fmt.Errorf("RDAP bootstrap contains no services")
+	}
+
+	b := &Bootstrap{
+		servers:     make(map[string]string),
+		publication: f.Publication,
+	}
+
+	for _, service := range f.Services {
+		if len(service) != 2 || len(service[1]) == 0 {
+			continue
+		}
+		base := pickBaseURL(service[1])
+		for _, tld := range service[0] {
+			b.servers[strings.ToLower(tld)] = base
+		}
+	}
+
+	return b, nil
+}
+
+// pickBaseURL selects the preferred base URL from a service entry.
+func pickBaseURL(urls []string) string {
+	chosen := urls[0]
+	for _, u := range urls {
+		if strings.HasPrefix(u, "https://") {
+			chosen = u
+			break
+		}
+	}
+	if !strings.HasSuffix(chosen, "/") {
+		chosen += "/"
+	}
+	return chosen
+}
+
+// EmbeddedBootstrap returns the bootstrap snapshot compiled into the binary.
+func EmbeddedBootstrap() *Bootstrap {
+	b, err := ParseBootstrap(embeddedBootstrapJSON)
+	if err != nil {
+		// The snapshot is validated by tests; a failure here is a build defect
+		panic(fmt.Sprintf("embedded RDAP bootstrap is invalid: %v", err))
+	}
+	return b
+}
+
+// Lookup returns the RDAP base URL for a zone (e.g. "com").
+//
+// Following RFC 9224 section 4, the longest matching label sequence wins:
+// "co.uk" is tried before "uk".
+func (b *Bootstrap) Lookup(zone string) (string, bool) {
+	zone = strings.ToLower(strings.Trim(zone, "."))
+	for zone != "" {
+		if base, ok := b.servers[zone]; ok {
+			return base, true
+		}
+		dot := strings.IndexByte(zone, '.')
+		if dot < 0 {
+			break
+		}
+		zone = zone[dot+1:]
+	}
+	return "", false
+}
+
+// Publication returns the publication timestamp of the bootstrap document,
+// or "" when it has none.
+func (b *Bootstrap) Publication() string {
+	return b.publication
+}
+
+// Len returns the number of TLDs with a known RDAP server.
+func (b *Bootstrap) Len() int {
+	return len(b.servers)
+}
+
+// BootstrapConfig controls where LoadBootstrap reads the registry from.
+type BootstrapConfig struct {
+	// File is a local dns.json to load. It takes precedence over URL.
+	File string
+
+	// URL is a remote dns.json to fetch (usually IANABootstrapURL)
+	URL string
+
+	// CachePath is where a fetched registry is stored on disk. Empty disables caching.
+	CachePath string
+
+	// TTL is how long the on-disk cache is used before refreshing (default 24h)
+	TTL time.Duration
+
+	// Client is the HTTP client used for URL (default: 30s timeout)
+	Client *http.Client
+}
+
+// LoadBootstrap loads the RDAP bootstrap registry according to cfg.
+//
+// Resolution order:
+//  1. A fresh on-disk cache (younger than TTL) is used as-is
+//  2. Otherwise the registry is loaded from File or fetched from URL and
+//     written to the cache
+//  3. If that fails, a stale cache is used, then the embedded snapshot
+//
+// LoadBootstrap always returns a usable registry. A non-nil error reports why
+// the configured source could not be used.
+func LoadBootstrap(ctx context.Context, cfg BootstrapConfig) (*Bootstrap, error) {
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = defaultBootstrapTTL
+	}
+
+	// Step 1: Fresh cache
+	if cfg.CachePath != "" {
+		if info, err := os.Stat(cfg.CachePath); err == nil && time.Since(info.ModTime()) < ttl {
+			if b, err := readBootstrapFile(cfg.CachePath); err == nil {
+				return b, nil
+			}
+		}
+	}
+
+	if cfg.File == "" && cfg.URL == "" {
+		return EmbeddedBootstrap(), nil
+	}
+
+	// Step 2: Configured source
+	data, err := fetchBootstrap(ctx, cfg)
+	if err == nil {
+		var b *Bootstrap
+		if b, err = ParseBootstrap(data); err == nil {
+			if cfg.CachePath != "" {
+				if cacheErr := writeFileAtomic(cfg.CachePath, data); cacheErr != nil {
+					return b, fmt.Errorf("failed to write RDAP bootstrap cache: %w", cacheErr)
+				}
+			}
+			return b, nil
+		}
+	}
+
+	// Step 3: Fallbacks
+	if cfg.CachePath != "" {
+		if b, cacheErr := readBootstrapFile(cfg.CachePath); cacheErr == nil {
+			return b, fmt.Errorf("using stale RDAP bootstrap cache: %w", err)
+		}
+	}
+	return EmbeddedBootstrap(), fmt.Errorf("using embedded RDAP bootstrap: %w", err)
+}
+
+// fetchBootstrap reads the raw registry from the configured file or URL.
+func fetchBootstrap(ctx context.Context, cfg BootstrapConfig) ([]byte, error) {
+	if cfg.File != "" {
+		f, err := os.Open(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open RDAP bootstrap file: %w", err)
+		}
+		defer f.Close()
+		return io.ReadAll(io.LimitReader(f, maxBootstrapSize))
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RDAP bootstrap request: %w", err)
+	}
+	req.Header.Set("User-Agent", "domaincheck/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("RDAP bootstrap request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("RDAP bootstrap server returned unexpected status: %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxBootstrapSize))
+}
+
+// readBootstrapFile loads and parses a bootstrap document from disk.
+func readBootstrapFile(path string) (*Bootstrap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBootstrap(data)
+}
+
+// writeFileAtomic writes data via a temporary file so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// bootstrapRegistry holds the registry used by RDAPCheck.
+var bootstrapRegistry = struct {
+	sync.RWMutex
+	current *Bootstrap
+}{
+	current: EmbeddedBootstrap(),
+}
+
+// SetBootstrap replaces the registry used by RDAPCheck.
+// This should be called at startup after LoadBootstrap.
+func SetBootstrap(b *Bootstrap) {
+	if b == nil {
+		return
+	}
+	bootstrapRegistry.Lock()
+	bootstrapRegistry.current = b
+	bootstrapRegistry.Unlock()
+}
+
+// currentBootstrap returns the registry used by RDAPCheck.
+func currentBootstrap() *Bootstrap {
+	bootstrapRegistry.RLock()
+	defer bootstrapRegistry.RUnlock()
+	return bootstrapRegistry.current
+}
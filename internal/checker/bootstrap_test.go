@@ -0,0 +1,204 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testBootstrapJSON = `{
+  "description": "test bootstrap",
+  "publication": "2026-01-01T00:00:00Z",
+  "services": [
+    [["test", "example"], ["http://rdap.example.test/rdap", "https://rdap.example.test/rdap/"]],
+    [["co.uk"], ["https://rdap.co-uk.test/"]],
+    [["uk"], ["https://rdap.uk.test/"]]
+  ],
+  "version": "1.0"
+}`
+
+func TestParseBootstrap(t *testing.T) {
+	b, err := ParseBootstrap([]byte(testBootstrapJSON))
+	if err != nil {
+		t.Fatalf("ParseBootstrap() error = %v", err)
+	}
+
+	tests := []struct {
+		zone   string
+		want   string
+		wantOK bool
+	}{
+		{"test", "https://rdap.example.test/rdap/", true},    // HTTPS preferred
+		{"EXAMPLE", "https://rdap.example.test/rdap/", true}, // case-insensitive
+		{"co.uk", "https://rdap.co-uk.test/", true},          // longest match
+		{"example.co.uk", "https://rdap.co-uk.test/", true},  // label walk
+		{"org.uk", "https://rdap.uk.test/", true},            // parent fallback
+		{"com", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.zone, func(t *testing.T) {
+			got, ok := b.Lookup(tt.zone)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Lookup(%q) = %q, %v; want %q, %v", tt.zone, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	if b.Publication() != "2026-01-01T00:00:00Z" {
+		t.Errorf("Publication() = %q", b.Publication())
+	}
+}
+
+func TestParseBootstrapInvalid(t *testing.T) {
+	inputs := []string{
+		"",
+		"not json",
+		`{"services": []}`,
+	}
+	for _, input := range inputs {
+		if _, err := ParseBootstrap([]byte(input)); err == nil {
+			t.Errorf("ParseBootstrap(%q) expected error", input)
+		}
+	}
+}
+
+func TestEmbeddedBootstrap(t *testing.T) {
+	b := EmbeddedBootstrap()
+
+	// The legacy hard-coded servers must still resolve
+	for _, tld := range []string{"com", "net", "org"} {
+		if _, ok := b.Lookup(tld); !ok {
+			t.Errorf("embedded bootstrap missing .%s", tld)
+		}
+	}
+	// IANA's registry covers well over 1000 TLDs
+	if b.Len() < 1000 || b.Publication() == "" {
+		t.Errorf("embedded bootstrap has %d TLDs, published %q: want a verbatim copy of IANA's dns.json (make update-rdap-bootstrap)", b.Len(), b.Publication())
+	}
+}
+
+// bootstrapServer returns a test server serving testBootstrapJSON and a hit counter.
+func bootstrapServer(t *testing.T, status int) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte(testBootstrapJSON))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestLoadBootstrapFromURL(t *testing.T) {
+	srv, hits := bootstrapServer(t, http.StatusOK)
+	cache := filepath.Join(t.TempDir(), "rdap", "dns.json")
+	cfg := BootstrapConfig{URL: srv.URL, CachePath: cache, TTL: time.Hour}
+
+	b, err := LoadBootstrap(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("LoadBootstrap() error = %v", err)
+	}
+	if _, ok := b.Lookup("test"); !ok {
+		t.Error("LoadBootstrap() result missing .test from server")
+	}
+	if _, err := os.Stat(cache); err != nil {
+		t.Errorf("cache file not written: %v", err)
+	}
+
+	// Second load must come from the fresh cache
+	if _, err := LoadBootstrap(context.Background(), cfg); err != nil {
+		t.Fatalf("LoadBootstrap() from cache error = %v", err)
+	}
+	if n := atomic.LoadInt32(hits); n != 1 {
+		t.Errorf("server hit %d times, want 1 (cache should be used)", n)
+	}
+}
+
+func TestLoadBootstrapExpiredCache(t *testing.T) {
+	srv, hits := bootstrapServer(t, http.StatusOK)
+	cache := filepath.Join(t.TempDir(), "dns.json")
+	if err := os.WriteFile(cache, embeddedBootstrapJSON, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(cache, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := LoadBootstrap(context.Background(), BootstrapConfig{URL: srv.URL, CachePath: cache, TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("LoadBootstrap() error = %v", err)
+	}
+	if atomic.LoadInt32(hits) != 1 {
+		t.Error("expired cache should trigger a refresh")
+	}
+	if _, ok := b.Lookup("test"); !ok {
+		t.Error("LoadBootstrap() should return the refreshed registry")
+	}
+}
+
+func TestLoadBootstrapFallbacks(t *testing.T) {
+	srv, _ := bootstrapServer(t, http.StatusInternalServerError)
+
+	t.Run("stale cache used when refresh fails", func(t *testing.T) {
+		cache := filepath.Join(t.TempDir(), "dns.json")
+		if err := os.WriteFile(cache, []byte(testBootstrapJSON), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-48 * time.Hour)
+		os.Chtimes(cache, old, old)
+
+		b, err := LoadBootstrap(context.Background(), BootstrapConfig{URL: srv.URL, CachePath: cache, TTL: time.Hour})
+		if err == nil {
+			t.Error("LoadBootstrap() should report the refresh failure")
+		}
+		if _, ok := b.Lookup("test"); !ok {
+			t.Error("LoadBootstrap() should fall back to the stale cache")
+		}
+	})
+
+	t.Run("embedded snapshot used without cache", func(t *testing.T) {
+		b, err := LoadBootstrap(context.Background(), BootstrapConfig{URL: srv.URL})
+		if err == nil {
+			t.Error("LoadBootstrap() should report the refresh failure")
+		}
+		if _, ok := b.Lookup("com"); !ok {
+			t.Error("LoadBootstrap() should fall back to the embedded snapshot")
+		}
+	})
+
+	t.Run("no source configured", func(t *testing.T) {
+		b, err := LoadBootstrap(context.Background(), BootstrapConfig{})
+		if err != nil {
+			t.Errorf("LoadBootstrap() error = %v", err)
+		}
+		if b.Len() != EmbeddedBootstrap().Len() {
+			t.Error("LoadBootstrap() without source should return the embedded snapshot")
+		}
+	})
+}
+
+func TestLoadBootstrapFromFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dns.json")
+	if err := os.WriteFile(file, []byte(testBootstrapJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := LoadBootstrap(context.Background(), BootstrapConfig{File: file, URL: "http://127.0.0.1:1/unused"})
+	if err != nil {
+		t.Fatalf("LoadBootstrap() error = %v", err)
+	}
+	if _, ok := b.Lookup("example"); !ok {
+		t.Error("LoadBootstrap() should load the local file")
+	}
+}
@@ -0,0 +1,229 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting an upstream whose circuit
+// breaker is open. The pipeline treats it like any source error and moves on
+// to the next source.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Default breaker settings used when a BreakerConfig field is zero.
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// BreakerConfig configures the circuit breakers.
+type BreakerConfig struct {
+	// Threshold is the number of consecutive failed tries (retries included)
+	// that opens an upstream's breaker (default 5). Negative disables breakers.
+	Threshold int
+
+	// Cooldown is how long a breaker stays open before one probe query is let
+	// through (default 30s)
+	Cooldown time.Duration
+}
+
+// Breaker states, as reported by BreakerStatus.State.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// Breakers keeps one circuit breaker per upstream host (RDAP server, WHOIS
+// server, DNS server).
+//
+// A breaker opens after Threshold consecutive failures; while open, queries
+// fail immediately with ErrCircuitOpen instead of waiting for a dead server to
+// time out. After Cooldown it turns half-open and lets a single probe through:
+// success closes it, failure opens it for another Cooldown.
+//
+// Breakers is safe for concurrent use.
+type Breakers struct {
+	cfg BreakerConfig
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// circuit is the state of one upstream's breaker.
+type circuit struct {
+	failures int       // consecutive failures
+	openedAt time.Time // zero while closed
+	probing  bool      // a half-open probe is in flight
+	lastErr  string
+}
+
+// BreakerStatus is a snapshot of one upstream's breaker.
+type BreakerStatus struct {
+	// Upstream identifies the host, prefixed by protocol (e.g. "rdap/rdap.verisign.com")
+	Upstream string `json:"upstream"`
+
+	// State is BreakerClosed, BreakerOpen or BreakerHalfOpen
+	State string `json:"state"`
+
+	// Failures is the number of consecutive failed queries
+	Failures int `json:"failures"`
+
+	// OpenedAt is when the breaker last opened; nil while closed
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+
+	// LastError is the most recent failure
+	LastError string `json:"last_error,omitempty"`
+}
+
+// NewBreakers creates the breaker set. See BreakerConfig for defaults.
+func NewBreakers(cfg BreakerConfig) *Breakers {
+	if cfg.Threshold == 0 {
+		cfg.Threshold = defaultBreakerThreshold
+	}
+	cfg.Cooldown = durationOr(cfg.Cooldown, defaultBreakerCooldown)
+	return &Breakers{
+		cfg:      cfg,
+		circuits: make(map[string]*circuit),
+	}
+}
+
+// Allow reports whether a query to an upstream may be sent.
+//
+// Returns:
+//   - nil when the breaker is closed, or when it is half-open and this call
+//     is the probe (the caller must then report its outcome with Record)
+//   - an error wrapping ErrCircuitOpen otherwise
+func (b *Breakers) Allow(protocol, host string) error {
+	if b == nil || b.cfg.Threshold < 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[protocol+"/"+host]
+	if !ok || c.openedAt.IsZero() {
+		return nil
+	}
+	if time.Since(c.openedAt) >= b.cfg.Cooldown && !c.probing {
+		c.probing = true
+		return nil
+	}
+	return fmt.Errorf("%w: %s %s (%d consecutive failures, last: %s)", ErrCircuitOpen, protocol, host, c.failures, c.lastErr)
+}
+
+// Record reports the outcome of a query allowed by Allow: nil for any answer
+// (even a negative one), the error when the upstream failed to answer.
+func (b *Breakers) Record(protocol, host string, err error) {
+	if b == nil || b.cfg.Threshold < 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := protocol + "/" + host
+	c, ok := b.circuits[key]
+	if !ok {
+		if err == nil {
+			return
+		}
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+
+	c.probing = false
+	if err == nil {
+		c.failures = 0
+		c.openedAt = time.Time{}
+		return
+	}
+
+	c.failures++
+	c.lastErr = err.Error()
+	if c.failures >= b.cfg.Threshold {
+		// Opening again after a failed probe restarts the cooldown
+		c.openedAt = time.Now()
+	}
+}
+
+// release ends a half-open probe whose outcome says nothing about the
+// upstream (e.g. the caller gave up), so the next query can probe instead.
+func (b *Breakers) release(protocol, host string) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c, ok := b.circuits[protocol+"/"+host]; ok {
+		c.probing = false
+	}
+}
+
+// Status returns a snapshot of every upstream that has failed at least once,
+// sorted by upstream.
+func (b *Breakers) Status() []BreakerStatus {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(b.circuits))
+	for key, c := range b.circuits {
+		s := BreakerStatus{
+			Upstream:  key,
+			State:     BreakerClosed,
+			Failures:  c.failures,
+			LastError: c.lastErr,
+		}
+		if !c.openedAt.IsZero() {
+			openedAt := c.openedAt
+			s.OpenedAt = &openedAt
+			s.State = BreakerOpen
+			if time.Since(c.openedAt) >= b.cfg.Cooldown {
+				s.State = BreakerHalfOpen
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Upstream < statuses[j].Upstream })
+	return statuses
+}
+
+// defaultBreakers is the breaker set used by the RDAP, WHOIS and DNS clients.
+var defaultBreakers = struct {
+	sync.RWMutex
+	breakers *Breakers
+}{
+	breakers: NewBreakers(BreakerConfig{}),
+}
+
+// SetBreakers replaces the circuit breakers used for all upstream queries.
+// A nil set disables circuit breaking.
+// This should be called at startup to tune thresholds.
+func SetBreakers(b *Breakers) {
+	defaultBreakers.Lock()
+	defaultBreakers.breakers = b
+	defaultBreakers.Unlock()
+}
+
+// currentBreakers returns the breakers for upstream queries, or nil when disabled.
+func currentBreakers() *Breakers {
+	defaultBreakers.RLock()
+	defer defaultBreakers.RUnlock()
+	return defaultBreakers.breakers
+}
+
+// BreakerStatuses returns the state of the circuit breakers in use,
+// for the server's admin endpoint.
+func BreakerStatuses() []BreakerStatus {
+	return currentBreakers().Status()
+}
@@ -0,0 +1,68 @@
+package checker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreakers(t *testing.T) {
+	b := NewBreakers(BreakerConfig{Threshold: 2, Cooldown: 20 * time.Millisecond})
+	failure := errors.New("connection refused")
+
+	b.Record("whois", "whois.example", failure)
+	if err := b.Allow("whois", "whois.example"); err != nil {
+		t.Fatalf("Allow() after 1 failure = %v, want nil", err)
+	}
+	b.Record("whois", "whois.example", failure)
+	if err := b.Allow("whois", "whois.example"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow() after 2 failures = %v, want ErrCircuitOpen", err)
+	}
+	if err := b.Allow("whois", "other.example"); err != nil {
+		t.Errorf("Allow() on another host = %v, want nil", err)
+	}
+
+	status := b.Status()
+	if len(status) != 1 || status[0].State != BreakerOpen || status[0].Failures != 2 || status[0].OpenedAt == nil {
+		t.Errorf("Status() = %+v, want one open breaker", status)
+	}
+
+	// After the cooldown exactly one probe goes through
+	time.Sleep(25 * time.Millisecond)
+	if s := b.Status(); s[0].State != BreakerHalfOpen {
+		t.Errorf("Status() after cooldown = %v, want half-open", s[0].State)
+	}
+	if err := b.Allow("whois", "whois.example"); err != nil {
+		t.Fatalf("probe Allow() = %v, want nil", err)
+	}
+	if err := b.Allow("whois", "whois.example"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("second Allow() while probing = %v, want ErrCircuitOpen", err)
+	}
+
+	// A failed probe reopens, a successful one closes
+	b.Record("whois", "whois.example", failure)
+	if err := b.Allow("whois", "whois.example"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Allow() after failed probe = %v, want ErrCircuitOpen", err)
+	}
+	time.Sleep(25 * time.Millisecond)
+	b.Allow("whois", "whois.example")
+	b.Record("whois", "whois.example", nil)
+	if s := b.Status(); s[0].State != BreakerClosed || s[0].Failures != 0 {
+		t.Errorf("Status() after successful probe = %+v, want closed", s[0])
+	}
+}
+
+func TestBreakersDisabled(t *testing.T) {
+	b := NewBreakers(BreakerConfig{Threshold: -1})
+	for i := 0; i < 10; i++ {
+		b.Record("rdap", "rdap.example", errors.New("timeout"))
+	}
+	if err := b.Allow("rdap", "rdap.example"); err != nil {
+		t.Errorf("Allow() with breakers disabled = %v, want nil", err)
+	}
+
+	var none *Breakers
+	if err := none.Allow("rdap", "rdap.example"); err != nil {
+		t.Errorf("nil Breakers Allow() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,280 @@
+package checker
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"domaincheck/internal/domain"
+	"domaincheck/internal/store"
+)
+
+// Default cache settings used when a CacheConfig field is zero.
+const (
+	defaultCacheSize         = 10000
+	defaultCacheTakenTTL     = time.Hour
+	defaultCacheAvailableTTL = 5 * time.Minute
+	defaultCacheErrorTTL     = 30 * time.Second
+)
+
+// CacheConfig configures a result Cache.
+//
+// Zero values select the defaults; a negative TTL disables caching of that
+// class of results.
+type CacheConfig struct {
+	// Size is the maximum number of cached domains (default 10000).
+	// The least recently used entry is evicted when the cache is full.
+	Size int
+
+	// TakenTTL applies to every decided, non-registrable status:
+	// taken, pending delete, redemption, reserved, blocked (default 1h)
+	TakenTTL time.Duration
+
+	// AvailableTTL applies to available and premium results (default 5m).
+	// Kept short because an available name can be registered at any moment.
+	AvailableTTL time.Duration
+
+	// ErrorTTL applies to failed checks (default 30s), so a flapping upstream
+	// is not hammered by retries
+	ErrorTTL time.Duration
+
+	// Store, when set, is consulted on a memory miss so results survive
+	// restarts. A stored result is served while younger than its TTL
+	// (measured from its CheckedAt); stored errors are never served.
+	// The cache only reads from Store: the owner of the store (the server)
+	// persists results.
+	Store store.Store
+}
+
+// Cache is a bounded LRU cache of check results keyed by normalized domain.
+// A Cache is safe for concurrent use.
+type Cache struct {
+	cfg CacheConfig
+
+	mu    sync.Mutex
+	ll    *list.List // front = most recently used
+	items map[string]*list.Element
+}
+
+// cacheEntry is a cached result with the error Check returned for it.
+type cacheEntry struct {
+	key     string
+	result  domain.Result
+	err     error
+	stored  time.Time
+	expires time.Time
+}
+
+// NewCache creates a result cache. See CacheConfig for defaults.
+func NewCache(cfg CacheConfig) *Cache {
+	if cfg.Size <= 0 {
+		cfg.Size = defaultCacheSize
+	}
+	// Negative TTLs (disabled) are kept as-is
+	if cfg.TakenTTL == 0 {
+		cfg.TakenTTL = defaultCacheTakenTTL
+	}
+	if cfg.AvailableTTL == 0 {
+		cfg.AvailableTTL = defaultCacheAvailableTTL
+	}
+	if cfg.ErrorTTL == 0 {
+		cfg.ErrorTTL = defaultCacheErrorTTL
+	}
+	return &Cache{
+		cfg:   cfg,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// ttl returns how long a result may be served from the cache; 0 means not at all.
+func (c *Cache) ttl(r domain.Result) time.Duration {
+	var ttl time.Duration
+	switch {
+	case r.Status == domain.StatusError:
+		ttl = c.cfg.ErrorTTL
+	case r.Status.Registrable():
+		ttl = c.cfg.AvailableTTL
+	case r.Status == domain.StatusUnknown || r.Status == domain.StatusConflict:
+		// Never cache undecided answers
+		return 0
+	default:
+		ttl = c.cfg.TakenTTL
+	}
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// Get returns the cached result for a domain (by its normalized Full name).
+//
+// The returned result has Cached set and Age set to the time since it was checked.
+func (c *Cache) Get(key string) (domain.Result, bool) {
+	result, _, ok := c.lookup(key)
+	return result, ok
+}
+
+// lookup is Get that also returns the error the original check returned.
+// On a memory miss it falls back to the persistent store, if configured.
+func (c *Cache) lookup(key string) (domain.Result, error, bool) {
+	if result, err, ok := c.lookupMemory(key); ok {
+		return result, err, true
+	}
+	if c.cfg.Store == nil {
+		return domain.Result{}, nil, false
+	}
+
+	// Read the store outside c.mu: it may hit the disk
+	stored, ok, err := c.cfg.Store.Get(key)
+	if err != nil || !ok || stored.Status == domain.StatusError || stored.CheckedAt.IsZero() {
+		return domain.Result{}, nil, false
+	}
+	ttl := c.ttl(stored)
+	expires := stored.CheckedAt.Add(ttl)
+	now := time.Now()
+	if ttl <= 0 || !now.Before(expires) {
+		return domain.Result{}, nil, false
+	}
+
+	c.insert(&cacheEntry{
+		key:     key,
+		result:  stored,
+		stored:  stored.CheckedAt,
+		expires: expires,
+	})
+
+	stored.Cached = true
+	stored.Age = now.Sub(stored.CheckedAt)
+	return stored, nil, true
+}
+
+// lookupMemory serves a key from the in-memory LRU only.
+func (c *Cache) lookupMemory(key string) (domain.Result, error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return domain.Result{}, nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	now := time.Now()
+	if !now.Before(entry.expires) {
+		c.removeElement(elem)
+		return domain.Result{}, nil, false
+	}
+	c.ll.MoveToFront(elem)
+
+	result := entry.result
+	result.Cached = true
+	result.Age = now.Sub(entry.stored)
+	return result, entry.err, true
+}
+
+// Put stores a check result and the error Check returned for it.
+// Results whose class has caching disabled are ignored.
+func (c *Cache) Put(r domain.Result, err error) {
+	ttl := c.ttl(r)
+	if ttl <= 0 || r.Domain.Full == "" {
+		return
+	}
+
+	now := time.Now()
+	c.insert(&cacheEntry{
+		key:     r.Domain.Full,
+		result:  r,
+		err:     err,
+		stored:  now,
+		expires: now.Add(ttl),
+	})
+}
+
+// insert adds or replaces an entry, evicting the least recently used ones.
+func (c *Cache) insert(entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[entry.key]; ok {
+		elem.Value = entry
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[entry.key] = c.ll.PushFront(entry)
+	for c.ll.Len() > c.cfg.Size {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// Len returns the number of cached entries, including expired ones not yet evicted.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// removeElement drops an entry. The caller must hold c.mu.
+func (c *Cache) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*cacheEntry).key)
+}
+
+// freshKey is the context key marking a request that must bypass the cache.
+type freshKey struct{}
+
+// WithFresh returns a context that makes Check skip cached results.
+// The fresh result still replaces the cached one.
+func WithFresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshKey{}, true)
+}
+
+// isFresh reports whether ctx was marked with WithFresh.
+func isFresh(ctx context.Context) bool {
+	fresh, _ := ctx.Value(freshKey{}).(bool)
+	return fresh
+}
+
+// defaultCache is the cache used by Check.
+var defaultCache = struct {
+	sync.RWMutex
+	cache *Cache
+}{
+	cache: NewCache(CacheConfig{}),
+}
+
+// SetCache replaces the cache used by Check. A nil cache disables caching.
+// This should be called at startup to tune size and TTLs.
+func SetCache(c *Cache) {
+	defaultCache.Lock()
+	defaultCache.cache = c
+	defaultCache.Unlock()
+}
+
+// currentCache returns the cache used by Check, or nil when disabled.
+func currentCache() *Cache {
+	defaultCache.RLock()
+	defer defaultCache.RUnlock()
+	return defaultCache.cache
+}
+
+// cachedCheck serves a check from cache when possible and stores fresh results.
+// Results of checks cut short by the caller's context are not stored.
+func cachedCheck(ctx context.Context, cache *Cache, d domain.Domain, check func(context.Context, domain.Domain) (domain.Result, error)) (domain.Result, error) {
+	if cache == nil {
+		return check(ctx, d)
+	}
+
+	if !isFresh(ctx) {
+		if result, err, ok := cache.lookup(d.Full); ok {
+			return result, err
+		}
+	}
+
+	result, err := check(ctx, d)
+	if ctx.Err() == nil {
+		cache.Put(result, err)
+	}
+	return result, err
+}
@@ -0,0 +1,164 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"domaincheck/internal/domain"
+	"domaincheck/internal/store"
+)
+
+func cacheResult(name string, status domain.Status) domain.Result {
+	return domain.Result{
+		Domain:    domain.Domain{Full: name + ".com", Name: name, TLD: "com"},
+		Status:    status,
+		Available: status.Registrable(),
+		Source:    "rdap",
+	}
+}
+
+func TestCacheGetPut(t *testing.T) {
+	c := NewCache(CacheConfig{})
+
+	if _, ok := c.Get("example.com"); ok {
+		t.Fatal("Get() on empty cache reported a hit")
+	}
+
+	c.Put(cacheResult("example", domain.StatusTaken), nil)
+	got, ok := c.Get("example.com")
+	if !ok {
+		t.Fatal("Get() after Put() reported a miss")
+	}
+	if !got.Cached || got.Status != domain.StatusTaken || got.Source != "rdap" {
+		t.Errorf("Get() = %+v, want cached taken result", got)
+	}
+	if got.Age < 0 {
+		t.Errorf("Get() Age = %v, want >= 0", got.Age)
+	}
+}
+
+func TestCacheTTLByStatus(t *testing.T) {
+	c := NewCache(CacheConfig{
+		TakenTTL:     time.Hour,
+		AvailableTTL: time.Nanosecond,
+		ErrorTTL:     -1, // disabled
+	})
+
+	c.Put(cacheResult("taken", domain.StatusRedemptionPeriod), nil)
+	c.Put(cacheResult("free", domain.StatusAvailable), nil)
+	c.Put(cacheResult("broken", domain.StatusError), errors.New("boom"))
+	c.Put(cacheResult("unsure", domain.StatusConflict), nil)
+	time.Sleep(time.Millisecond)
+
+	tests := []struct {
+		key     string
+		wantHit bool
+	}{
+		{"taken.com", true},
+		{"free.com", false},   // expired
+		{"broken.com", false}, // disabled
+		{"unsure.com", false}, // never cached
+	}
+	for _, tt := range tests {
+		if _, ok := c.Get(tt.key); ok != tt.wantHit {
+			t.Errorf("Get(%s) hit = %v, want %v", tt.key, ok, tt.wantHit)
+		}
+	}
+}
+
+func TestCacheLRUEviction(t *testing.T) {
+	c := NewCache(CacheConfig{Size: 2})
+
+	c.Put(cacheResult("a", domain.StatusTaken), nil)
+	c.Put(cacheResult("b", domain.StatusTaken), nil)
+	c.Get("a.com") // a is now most recently used
+	c.Put(cacheResult("c", domain.StatusTaken), nil)
+
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+	if _, ok := c.Get("b.com"); ok {
+		t.Error("least recently used entry b.com was not evicted")
+	}
+	for _, key := range []string{"a.com", "c.com"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("Get(%s) missing after eviction", key)
+		}
+	}
+}
+
+func TestCachedCheck(t *testing.T) {
+	c := NewCache(CacheConfig{})
+	d := domain.Domain{Full: "example.com", Name: "example", TLD: "com"}
+
+	calls := 0
+	check := func(ctx context.Context, d domain.Domain) (domain.Result, error) {
+		calls++
+		return domain.Result{Domain: d, Status: domain.StatusError, Error: "all checks failed"}, errors.New("upstream down")
+	}
+
+	_, err1 := cachedCheck(context.Background(), c, d, check)
+	r2, err2 := cachedCheck(context.Background(), c, d, check)
+	if calls != 1 {
+		t.Errorf("check called %d times, want 1 (second call cached)", calls)
+	}
+	if !r2.Cached || err2 == nil || err2.Error() != err1.Error() {
+		t.Errorf("cached call = %+v, %v; want cached result with original error", r2, err2)
+	}
+
+	r3, _ := cachedCheck(WithFresh(context.Background()), c, d, check)
+	if calls != 2 || r3.Cached {
+		t.Errorf("WithFresh: calls = %d, cached = %v; want a new check", calls, r3.Cached)
+	}
+
+	// Results of cancelled checks are not stored
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	other := domain.Domain{Full: "other.com", Name: "other", TLD: "com"}
+	cachedCheck(ctx, c, other, check)
+	if _, ok := c.Get("other.com"); ok {
+		t.Error("result of a cancelled check was cached")
+	}
+}
+
+func TestCacheStoreReadThrough(t *testing.T) {
+	s, err := store.Open(filepath.Join(t.TempDir(), "results.jsonl"))
+	if err != nil {
+		t.Fatalf("store.Open() error = %v", err)
+	}
+	defer s.Close()
+
+	now := time.Now()
+	stored := func(name string, status domain.Status, age time.Duration) {
+		r := cacheResult(name, status)
+		r.CheckedAt = now.Add(-age)
+		if err := s.Put(r); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	stored("taken", domain.StatusTaken, 10*time.Minute)
+	stored("stale", domain.StatusAvailable, 10*time.Minute) // older than AvailableTTL
+	stored("broken", domain.StatusError, time.Second)
+
+	c := NewCache(CacheConfig{Store: s})
+
+	got, ok := c.Get("taken.com")
+	if !ok || !got.Cached || got.Status != domain.StatusTaken {
+		t.Fatalf("Get(taken.com) = %+v, %v; want stored taken result", got, ok)
+	}
+	if got.Age < 10*time.Minute {
+		t.Errorf("Get(taken.com) Age = %v, want age since the stored check", got.Age)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want stored hit loaded into memory", c.Len())
+	}
+
+	for _, key := range []string{"stale.com", "broken.com", "missing.com"} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("Get(%s) served from store, want miss", key)
+		}
+	}
+}
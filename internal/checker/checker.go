@@ -4,11 +4,218 @@ package checker
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
+	"sync"
 	"time"
 
 	"domaincheck/internal/domain"
 )
 
+// Checker checks domain availability with its own settings: per-stage
+// timeouts, HTTP client, resolver, WHOIS servers, cache, pipeline. Create one
+// with New and functional options:
+//
+//	c := checker.New(
+//	    checker.WithRDAPTimeout(5*time.Second),
+//	    checker.WithTransport(transport),
+//	    checker.WithUserAgent("example-bot/2.0"),
+//	)
+//	result, err := c.Check(ctx, d)
+//
+// Settings that are not given fall back to the package-level ones at call
+// time (SetCache, SetBootstrap, SetWHOISClient, SetDNSClient), so New() with
+// no options behaves exactly like the package-level Check. Rate limiting,
+// retries and circuit breakers protect upstreams and stay process-wide
+// (see SetRateLimiter, SetRetryConfig, SetBreakers).
+//
+// A Checker is safe for concurrent use. Concurrent checks of the same domain
+// through the same Checker share one pipeline run.
+type Checker struct {
+	dnsTimeout   time.Duration
+	rdapTimeout  time.Duration
+	whoisTimeout time.Duration
+	httpClient   *http.Client
+	userAgent    string
+	resolver     *net.Resolver
+	dnsClient    *DNSClient
+	whoisClient  *WHOISClient
+	whoisServers map[string]string
+	bootstrap    *Bootstrap
+	cache        *Cache
+	cacheSet     bool // WithCache was given, even with nil
+	pipeline     *Pipeline
+	zones        *ZoneIndex
+	recordDir    string
+	replayDir    string
+
+	flights *flightGroup
+}
+
+// New creates a Checker. See the With* options; later options override
+// earlier ones.
+func New(opts ...Option) *Checker {
+	c := &Checker{flights: &flightGroup{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.pipeline == nil {
+		c.pipeline = c.defaultPipeline()
+	}
+	return c
+}
+
+// defaultPipeline builds DefaultPipeline's stages with the checker's settings.
+func (c *Checker) defaultPipeline() *Pipeline {
+	dns := dnsSource{resolver: c.resolver, timeout: c.dnsTimeout}
+	if c.resolver != nil {
+		// What a TLD wildcards depends on who we ask
+		dns.wildcards = newWildcardDetector(wildcardTTL)
+	}
+
+	dnsClient := c.dnsClient
+	if dnsClient == nil && c.resolver != nil {
+		dnsClient = &DNSClient{Resolver: c.resolver}
+	}
+
+	whoisClient := c.whoisClient
+	if c.whoisTimeout > 0 || c.whoisServers != nil {
+		// Copy rather than modify a client the caller may share
+		base := whoisClient
+		if base == nil {
+			base = &WHOISClient{}
+		}
+		whoisClient = &WHOISClient{
+			ConnectTimeout:   base.ConnectTimeout,
+			ReadTimeout:      base.ReadTimeout,
+			Timeout:          base.Timeout,
+			MaxResponseSize:  base.MaxResponseSize,
+			Servers:          base.Servers,
+			IANAServer:       base.IANAServer,
+			DisableReferrals: base.DisableReferrals,
+		}
+		if c.whoisTimeout > 0 {
+			whoisClient.Timeout = c.whoisTimeout
+		}
+		if c.whoisServers != nil {
+			whoisClient.Servers = c.whoisServers
+		}
+	}
+
+	rdap := rdapClient{
+		http:      c.httpClient,
+		userAgent: c.userAgent,
+		timeout:   c.rdapTimeout,
+		bootstrap: c.bootstrap,
+	}
+
+	p := NewPipeline(
+		Stage{Source: dns, Stop: StopOnTaken},
+		Stage{Source: authDNSSource{client: dnsClient}, Stop: StopOnTaken},
+		Stage{Source: rdapSource{client: rdap}, Stop: StopOnAnswer},
+		Stage{Source: whoisSource{client: whoisClient}, Stop: StopOnAnswer},
+	)
+	if c.zones != nil {
+		// Local and free: screen delegated names before any upstream query
+		p = p.InsertBefore("dns", Stage{Source: NewZoneSource(c.zones), Stop: StopOnTaken})
+	}
+	return p
+}
+
+// Pipeline returns the pipeline the checker runs.
+func (c *Checker) Pipeline() *Pipeline {
+	return c.pipeline
+}
+
+// currentCache returns the checker's cache, or nil when caching is disabled.
+func (c *Checker) currentCache() *Cache {
+	if c.cacheSet {
+		return c.cache
+	}
+	return currentCache()
+}
+
+// Check checks a domain with the checker's settings. It behaves like the
+// package-level Check, which documents the checking flow.
+func (c *Checker) Check(ctx context.Context, d domain.Domain) (domain.Result, error) {
+	if c.recordDir != "" || c.replayDir != "" {
+		return c.tapedCheck(ctx, d, c.pipeline.Check)
+	}
+	return cachedCheck(ctx, c.currentCache(), d, c.sharedCheck)
+}
+
+// sharedCheck runs the pipeline, coalescing concurrent calls per domain.
+func (c *Checker) sharedCheck(ctx context.Context, d domain.Domain) (domain.Result, error) {
+	return c.flights.do(ctx, "check:"+d.Full, d, c.pipeline.Check)
+}
+
+// CheckConsensus checks a domain with every source of the checker's pipeline
+// and cross-checks their answers, like the package-level CheckConsensus.
+func (c *Checker) CheckConsensus(ctx context.Context, d domain.Domain) (domain.Result, error) {
+	if c.recordDir != "" || c.replayDir != "" {
+		return c.tapedCheck(ctx, d, c.pipeline.CheckConsensus)
+	}
+	return c.flights.do(ctx, "consensus:"+d.Full, d, c.pipeline.CheckConsensus)
+}
+
+// tapedCheck runs check with its upstream traffic replayed from the replay
+// directory, or recorded to the recording directory. Such checks bypass the
+// cache and are not coalesced, so each one sees exactly its own traffic.
+//
+// A check without a recording to replay fails with an error wrapping
+// fs.ErrNotExist. A recording that cannot be saved is reported as the error,
+// alongside the check's unchanged result, when the check itself succeeded.
+func (c *Checker) tapedCheck(ctx context.Context, d domain.Domain, check func(context.Context, domain.Domain) (domain.Result, error)) (domain.Result, error) {
+	start := time.Now()
+
+	if c.replayDir != "" {
+		rec, err := LoadRecording(recordingPath(c.replayDir, d.Full))
+		if err != nil {
+			return failResult(domain.Result{Domain: d, CheckedAt: start}, fmt.Errorf("replay: %w", err), "", start)
+		}
+		return check(withTape(ctx, &tape{rec: rec, replay: true}), d)
+	}
+
+	t := &tape{rec: &Recording{Domain: d.Full, RecordedAt: start.UTC()}}
+	result, err := check(withTape(ctx, t), d)
+
+	t.mu.Lock()
+	t.rec.setResult(result)
+	saveErr := t.rec.Save(recordingPath(c.recordDir, d.Full))
+	t.mu.Unlock()
+	if saveErr != nil && err == nil {
+		err = fmt.Errorf("recording not saved: %w", saveErr)
+	}
+	return result, err
+}
+
+// defaultChecker is the Checker behind the package-level Check and CheckConsensus.
+var defaultChecker = struct {
+	sync.RWMutex
+	checker *Checker
+}{
+	checker: New(),
+}
+
+// SetDefault replaces the Checker used by the package-level Check and
+// CheckConsensus. This should be called at startup to tune timeouts or the
+// HTTP transport for the whole program.
+func SetDefault(c *Checker) {
+	if c == nil {
+		return
+	}
+	defaultChecker.Lock()
+	defaultChecker.checker = c
+	defaultChecker.Unlock()
+}
+
+// currentChecker returns the Checker used by the package-level functions.
+func currentChecker() *Checker {
+	defaultChecker.RLock()
+	defer defaultChecker.RUnlock()
+	return defaultChecker.checker
+}
+
 // Check orchestrates the domain availability checking process.
 //
 // The checking flow is optimized for speed and reliability:
@@ -18,25 +225,42 @@ import (
 //     - If records exist → domain is definitely registered → DONE
 //     - If no records → might be available, need further checks
 //
-//  2. RDAP Check (fast, structured, ~500ms):
+//  2. Authoritative DNS (~100ms):
+//     - Asks the TLD's nameservers whether the domain is delegated
+//     - If delegated → domain is registered → DONE
+//     - NXDOMAIN → recorded as evidence, continue
+//
+//  3. RDAP Check (fast, structured, ~500ms):
 //     - Queries RDAP server for domain registration data
 //     - Returns structured JSON response
 //     - If supported TLD → use RDAP result → DONE
 //     - If unsupported TLD → fall back to WHOIS
 //
-//  3. WHOIS Check (slow, unstructured, ~1-2s):
-//     - Executes whois command and parses text output
+//  4. WHOIS Check (slow, unstructured, ~1-2s):
+//     - Queries the registry's WHOIS server over TCP port 43 (RFC 3912),
+//     following registrar referrals, and parses the text output
 //     - Works with any TLD but less reliable
 //     - Last resort fallback
 //
 // The function returns a domain.Result containing:
 //   - Status: StatusAvailable, StatusTaken, or StatusError
 //   - Available: convenience boolean
-//   - Source: which protocol provided the answer ("dns", "rdap", "whois")
+//   - Source: which protocol provided the answer ("dns", "dns-auth", "rdap", "whois")
 //   - CheckedAt: timestamp when check started
 //   - Duration: total time taken
 //   - Error: error message if any
 //
+// Results are cached (see SetCache and CacheConfig); a cache hit has Cached
+// set and Age filled in. Use WithFresh to force a new check.
+//
+// Concurrent calls for the same domain share one pipeline run and receive the
+// same Result, so duplicate names in a batch cost a single lookup.
+//
+// The sequence is implemented by DefaultPipeline. Callers that need to add,
+// remove or reorder sources should build their own Pipeline from Sources.
+// Callers that need other timeouts, an HTTP transport or a resolver of their
+// own should create a Checker with New.
+//
 // Context Handling:
 // The context is propagated to all sub-checks. If the context is cancelled or
 // times out, the check will abort and return an error.
@@ -55,69 +279,5 @@ import (
 //	    fmt.Println("Domain is available!")
 //	}
 func Check(ctx context.Context, d domain.Domain) (domain.Result, error) {
-	start := time.Now()
-
-	result := domain.Result{
-		Domain:    d,
-		CheckedAt: start,
-	}
-
-	// Step 1: DNS Pre-Filter
-	// This is the fastest check - if domain has DNS records, it's definitely registered
-	_, shouldSkip, err := DNSFilter(ctx, d)
-	if err != nil {
-		// DNS filter failed - continue with RDAP/WHOIS to be thorough
-		// Don't return error, just log it internally and continue
-	} else if shouldSkip {
-		// Domain has DNS records - it's taken, no need for RDAP/WHOIS
-		result.Status = domain.StatusTaken
-		result.Available = false
-		result.Source = "dns"
-		result.Duration = time.Since(start)
-		return result, nil
-	}
-
-	// Step 2: Try RDAP Check
-	// DNS said "no records" (might be available), so check RDAP for definitive answer
-	available, err := RDAPCheck(ctx, d)
-	if err == nil {
-		// RDAP succeeded
-		if available {
-			result.Status = domain.StatusAvailable
-			result.Available = true
-		} else {
-			result.Status = domain.StatusTaken
-			result.Available = false
-		}
-		result.Source = "rdap"
-		result.Duration = time.Since(start)
-		return result, nil
-	}
-
-	// RDAP failed - could be unsupported TLD or server error
-	// Fall back to WHOIS as last resort
-
-	// Step 3: WHOIS Fallback
-	available, err = WHOISCheck(ctx, d)
-	if err != nil {
-		// WHOIS also failed - return error
-		result.Status = domain.StatusError
-		result.Available = false
-		result.Error = fmt.Sprintf("all checks failed, last error: %v", err)
-		result.Source = "whois"
-		result.Duration = time.Since(start)
-		return result, fmt.Errorf("domain check failed: %w", err)
-	}
-
-	// WHOIS succeeded
-	if available {
-		result.Status = domain.StatusAvailable
-		result.Available = true
-	} else {
-		result.Status = domain.StatusTaken
-		result.Available = false
-	}
-	result.Source = "whois"
-	result.Duration = time.Since(start)
-	return result, nil
+	return currentChecker().Check(ctx, d)
 }
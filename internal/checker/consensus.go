@@ -0,0 +1,131 @@
+package checker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+// consensusSource is the Result.Source reported when sources disagree.
+const consensusSource = "consensus"
+
+// CheckConsensus checks a domain with every source of the default pipeline in
+// parallel and cross-checks their answers. See Pipeline.CheckConsensus.
+//
+// It is slower and heavier on upstreams than Check, and meant as a final
+// verification step (e.g. right before purchasing a name). Concurrent calls
+// for the same domain share one run, as with Check.
+func CheckConsensus(ctx context.Context, d domain.Domain) (domain.Result, error) {
+	return currentChecker().CheckConsensus(ctx, d)
+}
+
+// CheckConsensus runs every stage that supports the domain's TLD concurrently,
+// ignoring stop rules, and compares the verdicts.
+//
+// Outcome:
+//   - All decided sources agree on whether the name is registrable → the
+//     verdict with the highest confidence wins (earliest stage on ties);
+//     confidence is high when at least two sources agreed
+//   - Sources disagree → Status is domain.StatusConflict, Available is false,
+//     Source is "consensus" and confidence is low
+//   - No source decided → StatusError, as with Check
+//
+// Every verdict is reported in Result.Attempts, in stage order.
+func (p *Pipeline) CheckConsensus(ctx context.Context, d domain.Domain) (domain.Result, error) {
+	start := time.Now()
+
+	result := domain.Result{
+		Domain:    d,
+		CheckedAt: start,
+	}
+
+	// Don't start any upstream query once the caller has given up
+	if err := ctx.Err(); err != nil {
+		return failResult(result, err, "", start)
+	}
+
+	var stages []Stage
+	for _, stage := range p.stages {
+		if stage.Source.Supports(d.Zone()) {
+			stages = append(stages, stage)
+		}
+	}
+
+	verdicts := make([]Verdict, len(stages))
+	errs := make([]error, len(stages))
+	result.Attempts = make([]domain.Attempt, len(stages))
+
+	var wg sync.WaitGroup
+	for i, stage := range stages {
+		wg.Add(1)
+		go func(i int, source Source) {
+			defer wg.Done()
+			started := time.Now()
+			verdicts[i], errs[i] = source.Check(ctx, d)
+			result.Attempts[i] = newAttempt(source.Name(), verdicts[i], errs[i], time.Since(started))
+		}(i, stage.Source)
+	}
+	wg.Wait()
+
+	var (
+		lastErr    error
+		lastSource string
+		best       = -1
+		agreeing   int
+		conflict   bool
+	)
+	for i, v := range verdicts {
+		lastSource = stages[i].Source.Name()
+		if errs[i] != nil {
+			lastErr = errs[i]
+			continue
+		}
+		if !v.Decided() {
+			continue
+		}
+		if best < 0 {
+			best, agreeing = i, 1
+			continue
+		}
+		if v.Status.Registrable() != verdicts[best].Status.Registrable() {
+			conflict = true
+			continue
+		}
+		agreeing++
+		if effectiveConfidence(v) > effectiveConfidence(verdicts[best]) {
+			best = i
+		}
+	}
+
+	switch {
+	case conflict:
+		result = finishResult(result, Verdict{Status: domain.StatusConflict}, consensusSource, start)
+		result.Registration = firstRegistration(verdicts)
+		result.Confidence = domain.ConfidenceLow
+		return result, nil
+
+	case best >= 0:
+		result = finishResult(result, verdicts[best], stages[best].Source.Name(), start)
+		if agreeing > 1 {
+			result.Confidence = domain.ConfidenceHigh
+		}
+		if result.Registration == nil {
+			result.Registration = firstRegistration(verdicts)
+		}
+		return result, nil
+	}
+
+	return failResult(result, lastErr, lastSource, start)
+}
+
+// firstRegistration returns the first registration details any verdict carried.
+func firstRegistration(verdicts []Verdict) *domain.Registration {
+	for _, v := range verdicts {
+		if v.Registration != nil {
+			return v.Registration
+		}
+	}
+	return nil
+}
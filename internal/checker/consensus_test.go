@@ -0,0 +1,131 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"domaincheck/internal/domain"
+)
+
+func TestPipelineCheckConsensus(t *testing.T) {
+	d := domain.Domain{Full: "example.com", Name: "example", TLD: "com"}
+	reg := &domain.Registration{Registrar: "Example Registrar"}
+
+	tests := []struct {
+		name           string
+		stages         []Stage
+		wantStatus     domain.Status
+		wantSource     string
+		wantConfidence domain.Confidence
+		wantRegistrar  string
+		wantErr        bool
+	}{
+		{
+			name: "agreement raises confidence",
+			stages: []Stage{
+				{Source: fakeSource{name: "dns", verdict: verdictUnknown}},
+				{Source: fakeSource{name: "rdap", verdict: Verdict{Status: domain.StatusAvailable, Confidence: domain.ConfidenceHigh}}},
+				{Source: fakeSource{name: "whois", verdict: verdictAvailable}},
+			},
+			wantStatus:     domain.StatusAvailable,
+			wantSource:     "rdap",
+			wantConfidence: domain.ConfidenceHigh,
+		},
+		{
+			name: "highest confidence source wins",
+			stages: []Stage{
+				{Source: fakeSource{name: "whois", verdict: verdictTaken}},
+				{Source: fakeSource{name: "rdap", verdict: Verdict{Status: domain.StatusRedemptionPeriod, Registration: reg, Confidence: domain.ConfidenceHigh}}},
+			},
+			wantStatus:     domain.StatusRedemptionPeriod,
+			wantSource:     "rdap",
+			wantConfidence: domain.ConfidenceHigh,
+			wantRegistrar:  "Example Registrar",
+		},
+		{
+			name: "disagreement is a conflict",
+			stages: []Stage{
+				{Source: fakeSource{name: "dns", verdict: Verdict{Status: domain.StatusTaken, Confidence: domain.ConfidenceHigh}}},
+				{Source: fakeSource{name: "rdap", verdict: Verdict{Status: domain.StatusTaken, Registration: reg}}},
+				{Source: fakeSource{name: "whois", verdict: verdictAvailable}},
+			},
+			wantStatus:     domain.StatusConflict,
+			wantSource:     "consensus",
+			wantConfidence: domain.ConfidenceLow,
+			wantRegistrar:  "Example Registrar",
+		},
+		{
+			name: "errors do not count as disagreement",
+			stages: []Stage{
+				{Source: fakeSource{name: "rdap", err: errors.New("boom")}},
+				{Source: fakeSource{name: "whois", verdict: verdictTaken}},
+			},
+			wantStatus:     domain.StatusTaken,
+			wantSource:     "whois",
+			wantConfidence: domain.ConfidenceMedium,
+		},
+		{
+			name: "all sources fail",
+			stages: []Stage{
+				{Source: fakeSource{name: "rdap", err: errors.New("first")}},
+				{Source: fakeSource{name: "whois", err: errors.New("second")}},
+			},
+			wantStatus: domain.StatusError,
+			wantSource: "whois",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewPipeline(tt.stages...).CheckConsensus(context.Background(), d)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckConsensus() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("CheckConsensus() Status = %v, want %v", result.Status, tt.wantStatus)
+			}
+			if result.Source != tt.wantSource {
+				t.Errorf("CheckConsensus() Source = %q, want %q", result.Source, tt.wantSource)
+			}
+			if result.Confidence != tt.wantConfidence {
+				t.Errorf("CheckConsensus() Confidence = %v, want %v", result.Confidence, tt.wantConfidence)
+			}
+			if result.Available != tt.wantStatus.Registrable() {
+				t.Errorf("CheckConsensus() Available = %v inconsistent with Status %v", result.Available, result.Status)
+			}
+			if len(result.Attempts) != len(tt.stages) {
+				t.Errorf("CheckConsensus() recorded %d attempts, want %d", len(result.Attempts), len(tt.stages))
+			}
+			registrar := ""
+			if result.Registration != nil {
+				registrar = result.Registration.Registrar
+			}
+			if registrar != tt.wantRegistrar {
+				t.Errorf("CheckConsensus() Registrar = %q, want %q", registrar, tt.wantRegistrar)
+			}
+		})
+	}
+}
+
+// TestPipelineCheckConsensusIgnoresStopRules verifies every supported source is queried
+func TestPipelineCheckConsensusIgnoresStopRules(t *testing.T) {
+	var first, second, skipped int
+	p := NewPipeline(
+		Stage{Source: fakeSource{name: "a", verdict: verdictTaken, calls: &first}, Stop: StopOnAnswer},
+		Stage{Source: fakeSource{name: "b", verdict: verdictTaken, calls: &second}, Stop: StopOnAnswer},
+		Stage{Source: fakeSource{name: "c", tlds: []string{"org"}, calls: &skipped}},
+	)
+
+	if _, err := p.CheckConsensus(context.Background(), domain.Domain{Full: "example.com", TLD: "com"}); err != nil {
+		t.Fatalf("CheckConsensus() unexpected error: %v", err)
+	}
+	if first != 1 || second != 1 {
+		t.Errorf("sources called %d and %d times, want 1 each", first, second)
+	}
+	if skipped != 0 {
+		t.Errorf("unsupported source called %d times, want 0", skipped)
+	}
+}
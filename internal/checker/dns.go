@@ -3,8 +3,8 @@ package checker
 
 import (
 	"context"
+	"errors"
 	"net"
-	"time"
 
 	"domaincheck/internal/domain"
 )
@@ -14,6 +14,7 @@ import (
 //
 // Logic:
 //   - If domain HAS DNS records (A/AAAA/MX/NS) → likely registered → shouldSkip=true
+//     (record types a wildcard TLD returns for every name are ignored)
 //   - If domain has NO DNS records → might be available or parked → shouldSkip=false (need RDAP/WHOIS)
 //   - If DNS lookup errors → be cautious → shouldSkip=false (need RDAP/WHOIS)
 //
@@ -22,34 +23,77 @@ import (
 //   - shouldSkip: true if we can skip RDAP/WHOIS (domain definitely has records)
 //   - err: any unexpected errors (nil for normal operation)
 func DNSFilter(ctx context.Context, d domain.Domain) (likelyAvailable bool, shouldSkip bool, err error) {
-	// Set timeout for DNS lookups
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
+	found, _, _ := dnsProbe(ctx, d)
+	if found {
+		return false, true, nil
+	}
+
+	// No DNS records found → might be available, but need RDAP/WHOIS to be sure
+	// (could be registered but not configured)
+	return true, false, nil
+}
+
+// dnsProbe looks up A/AAAA, MX and NS records for the domain with the
+// system resolver. Record types the TLD answers for any name (registry
+// wildcards, see wildcardDetector) are not trusted and skipped.
+//
+// Returns:
+//   - found: true if any trusted record exists
+//   - signal: the record type found ("A/AAAA records", ...) or "no records",
+//     noting wildcard types that were ignored
+//   - lookupErr: when nothing was found, the last lookup failure other than
+//     "no such host" (timeouts, SERVFAIL), so callers can tell "no records"
+//     from "could not ask"
+func dnsProbe(ctx context.Context, d domain.Domain) (found bool, signal string, lookupErr error) {
+	return dnsSource{}.probe(ctx, d)
+}
+
+// probeDNS is dnsProbe with an explicit resolver, skipping the record types
+// in wild (see wildcardDetector.types). The caller bounds the lookups with ctx.
+func probeDNS(ctx context.Context, resolver dnsResolver, wild wildcardTypes, d domain.Domain) (found bool, signal string, lookupErr error) {
 
-	resolver := &net.Resolver{}
+	// record remembers real failures; NXDOMAIN/NODATA is the normal "no records" answer
+	record := func(err error) {
+		var dnsErr *net.DNSError
+		if err == nil || (errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+			return
+		}
+		lookupErr = err
+	}
 
 	// Check A/AAAA records (IP addresses)
-	ips, err := resolver.LookupIP(ctx, "ip", d.Full)
-	if err == nil && len(ips) > 0 {
-		// Domain has IP records → definitely registered
-		return false, true, nil
+	if !wild.IP {
+		ips, err := resolver.LookupIP(ctx, "ip", d.Full)
+		if err == nil && len(ips) > 0 {
+			// Domain has IP records → definitely registered
+			return true, "A/AAAA records", nil
+		}
+		record(err)
 	}
 
 	// Check MX records (mail servers)
-	mxRecords, err := resolver.LookupMX(ctx, d.Full)
-	if err == nil && len(mxRecords) > 0 {
-		// Domain has MX records → definitely registered
-		return false, true, nil
+	if !wild.MX {
+		mxRecords, err := resolver.LookupMX(ctx, d.Full)
+		if err == nil && len(mxRecords) > 0 {
+			// Domain has MX records → definitely registered
+			return true, "MX records", nil
+		}
+		record(err)
 	}
 
 	// Check NS records (nameservers)
-	nsRecords, err := resolver.LookupNS(ctx, d.Full)
-	if err == nil && len(nsRecords) > 0 {
-		// Domain has NS records → definitely registered
-		return false, true, nil
+	if !wild.NS {
+		nsRecords, err := resolver.LookupNS(ctx, d.Full)
+		if err == nil && len(nsRecords) > 0 {
+			// Domain has NS records → definitely registered
+			return true, "NS records", nil
+		}
+		record(err)
 	}
 
-	// No DNS records found → might be available, but need RDAP/WHOIS to be sure
-	// (could be registered but not configured)
-	return true, false, nil
+	signal = "no A/AAAA/MX/NS records"
+	if wild.any() {
+		signal = "no trusted records (wildcard TLD, ignored " + wild.String() + ")"
+	}
+	return false, signal, lookupErr
 }
@@ -0,0 +1,321 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+const (
+	// defaultDNSTimeout bounds a single query exchange
+	defaultDNSTimeout = 2 * time.Second
+
+	// maxTLDServers caps how many authoritative servers are tried per TLD
+	maxTLDServers = 4
+
+	// maxDNSReferrals bounds how far below the TLD the client follows referrals
+	// (e.g. uk → co.uk for example.co.uk)
+	maxDNSReferrals = 3
+)
+
+// DNSClient queries the authoritative nameservers of a TLD directly over the
+// DNS wire protocol, bypassing the system resolver and its caches.
+//
+// Unlike net.Resolver it reports the response code, so "no such name"
+// (NXDOMAIN) can be told apart from "name exists" and from server failures.
+//
+// The zero value is ready to use: the TLD's nameservers are discovered with
+// the system resolver once and cached for the lifetime of the client.
+// A DNSClient is safe for concurrent use.
+type DNSClient struct {
+	// Timeout bounds each query exchange, including a TCP retry (default 2s)
+	Timeout time.Duration
+
+	// Servers overrides discovery: TLD → authoritative server addresses ("ip" or "ip:port")
+	Servers map[string][]string
+
+	// Resolver is used to discover TLD nameservers (default net.DefaultResolver)
+	Resolver *net.Resolver
+
+	mu         sync.Mutex
+	discovered map[string][]string
+}
+
+// Delegation is what a parent zone's authoritative servers say about a name.
+type Delegation struct {
+	// Server is the address of the server that answered
+	Server string
+
+	// Rcode is the response code (DNSRcodeSuccess, DNSRcodeNameError, ...)
+	Rcode int
+
+	// Nameservers are the delegated NS targets; empty when the name is not delegated
+	Nameservers []string
+}
+
+// Delegated reports whether the parent zone delegates the name, which only
+// happens for registered domains.
+func (d Delegation) Delegated() bool {
+	return len(d.Nameservers) > 0
+}
+
+// Signal describes the answer for the Result evidence trail
+// (e.g. "NS delegation: ns1.example.com, ns2.example.com" or "NXDOMAIN from 192.0.2.1:53").
+func (d Delegation) Signal() string {
+	if d.Delegated() {
+		return "NS delegation: " + strings.Join(d.Nameservers, ", ")
+	}
+	if d.Rcode == DNSRcodeSuccess {
+		return "NOERROR without delegation from " + d.Server
+	}
+	return dnsRcodeName(d.Rcode) + " from " + d.Server
+}
+
+// Delegation asks the TLD's authoritative servers whether the domain is delegated.
+//
+// Servers are tried in order until one gives a usable answer; SERVFAIL,
+// REFUSED and network errors move on to the next server. Referrals to zones
+// below the TLD (e.g. co.uk) are followed using glue or the resolver.
+//
+// Returns:
+//   - Delegation: the authoritative answer, including the rcode
+//   - err: when no server could be queried or every server failed
+func (c *DNSClient) Delegation(ctx context.Context, d domain.Domain) (Delegation, error) {
+	// Start at the TLD: referrals lead down to zones of public suffixes (co.uk)
+	servers, err := c.ServersFor(ctx, d.TLD)
+	if err != nil {
+		return Delegation{}, err
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(d.Full, "."))
+	for hop := 0; ; hop++ {
+		msg, server, err := c.exchangeAny(ctx, servers, name)
+		if err != nil {
+			return Delegation{}, err
+		}
+
+		result := Delegation{Server: server, Rcode: msg.Rcode}
+		if msg.Rcode != DNSRcodeSuccess {
+			return result, nil
+		}
+
+		// A delegation is an NS set owned by the name itself, either as a
+		// referral (authority section) or an answer (when served from the same zone)
+		owner := ""
+		var targets []string
+		for _, rr := range append(msg.Answer, msg.Authority...) {
+			if rr.Type != DNSTypeNS {
+				continue
+			}
+			if owner == "" {
+				owner = rr.Name
+			}
+			if rr.Name == owner {
+				targets = append(targets, rr.Data)
+			}
+		}
+
+		switch {
+		case owner == name:
+			result.Nameservers = targets
+			return result, nil
+
+		case owner != "" && strings.HasSuffix(name, "."+owner) && hop < maxDNSReferrals:
+			// Referral to an intermediate zone (e.g. co.uk): ask its servers instead
+			servers = c.referralServers(ctx, msg, targets)
+			if len(servers) == 0 {
+				return Delegation{}, fmt.Errorf("no address for referral to %s", owner)
+			}
+
+		default:
+			return result, nil
+		}
+	}
+}
+
+// exchangeAny sends an NS query to each server until one returns a usable response.
+func (c *DNSClient) exchangeAny(ctx context.Context, servers []string, name string) (*DNSMessage, string, error) {
+	var lastErr error
+	for _, server := range servers {
+		if err := ctx.Err(); err != nil {
+			return nil, "", dnsError(ctx, err)
+		}
+
+		msg, err := c.Exchange(ctx, server, name, DNSTypeNS)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		switch msg.Rcode {
+		case DNSRcodeSuccess, DNSRcodeNameError:
+			return msg, server, nil
+		default:
+			// SERVFAIL, REFUSED, ... say nothing about the name; try another server
+			lastErr = fmt.Errorf("%s from %s", msg.RcodeName(), server)
+		}
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no authoritative servers to query")
+	}
+	return nil, "", lastErr
+}
+
+// referralServers returns addresses for the referral targets, preferring glue records.
+func (c *DNSClient) referralServers(ctx context.Context, msg *DNSMessage, targets []string) []string {
+	var servers []string
+	for _, target := range targets {
+		for _, rr := range msg.Additional {
+			if rr.Name == target && (rr.Type == DNSTypeA || rr.Type == DNSTypeAAAA) && rr.Data != "" {
+				servers = append(servers, net.JoinHostPort(rr.Data, "53"))
+			}
+		}
+	}
+	if len(servers) == 0 {
+		servers = c.resolveServers(ctx, targets)
+	}
+	if len(servers) > maxTLDServers {
+		servers = servers[:maxTLDServers]
+	}
+	return servers
+}
+
+// ServersFor returns the authoritative server addresses for a TLD.
+//
+// Overrides in Servers win; otherwise the TLD's NS records are looked up with
+// the resolver, their addresses resolved, and the result cached.
+func (c *DNSClient) ServersFor(ctx context.Context, tld string) ([]string, error) {
+	tld = strings.ToLower(tld)
+	if tld == "" {
+		return nil, fmt.Errorf("no TLD to look up nameservers for")
+	}
+
+	if t := tapeFrom(ctx); t != nil {
+		return t.dnsServers(tld, func() ([]string, error) { return c.serversFor(ctx, tld) })
+	}
+	return c.serversFor(ctx, tld)
+}
+
+// serversFor is ServersFor for a lowercase, non-empty TLD.
+func (c *DNSClient) serversFor(ctx context.Context, tld string) ([]string, error) {
+	if servers, ok := c.Servers[tld]; ok {
+		return servers, nil
+	}
+
+	c.mu.Lock()
+	servers, ok := c.discovered[tld]
+	c.mu.Unlock()
+	if ok {
+		return servers, nil
+	}
+
+	nsRecords, err := c.resolver().LookupNS(ctx, tld+".")
+	if err != nil {
+		return nil, fmt.Errorf("TLD nameserver discovery failed: %w", err)
+	}
+	hosts := make([]string, 0, len(nsRecords))
+	for _, ns := range nsRecords {
+		hosts = append(hosts, ns.Host)
+	}
+
+	servers = c.resolveServers(ctx, hosts)
+	if len(servers) == 0 {
+		return nil, withCode(ErrUnsupportedTLD, fmt.Errorf("no nameserver address found for TLD: %s", tld))
+	}
+	if len(servers) > maxTLDServers {
+		servers = servers[:maxTLDServers]
+	}
+
+	c.mu.Lock()
+	if c.discovered == nil {
+		c.discovered = make(map[string][]string)
+	}
+	c.discovered[tld] = servers
+	c.mu.Unlock()
+
+	return servers, nil
+}
+
+// resolveServers resolves nameserver host names to "ip:53" addresses,
+// one address per host (IPv4 preferred).
+func (c *DNSClient) resolveServers(ctx context.Context, hosts []string) []string {
+	if tapeFrom(ctx).replaying() {
+		// Replayed answers don't depend on the server: skip the lookups
+		servers := make([]string, 0, len(hosts))
+		for _, host := range hosts {
+			servers = append(servers, net.JoinHostPort(host, "53"))
+		}
+		return servers
+	}
+
+	var servers []string
+	for _, host := range hosts {
+		addrs, err := c.resolver().LookupIPAddr(ctx, host)
+		if err != nil || len(addrs) == 0 {
+			continue
+		}
+		pick := addrs[0].IP
+		for _, a := range addrs {
+			if a.IP.To4() != nil {
+				pick = a.IP
+				break
+			}
+		}
+		servers = append(servers, net.JoinHostPort(pick.String(), "53"))
+		if len(servers) == maxTLDServers {
+			break
+		}
+	}
+	return servers
+}
+
+// resolver returns the resolver used for discovery.
+func (c *DNSClient) resolver() *net.Resolver {
+	if c.Resolver != nil {
+		return c.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// defaultDNSClient is the client used by DNSDelegation.
+var defaultDNSClient = struct {
+	sync.RWMutex
+	client *DNSClient
+}{
+	client: &DNSClient{},
+}
+
+// SetDNSClient replaces the client used by DNSDelegation.
+// This should be called at startup to tune timeouts or pin servers.
+func SetDNSClient(c *DNSClient) {
+	if c == nil {
+		return
+	}
+	defaultDNSClient.Lock()
+	defaultDNSClient.client = c
+	defaultDNSClient.Unlock()
+}
+
+// currentDNSClient returns the client used by DNSDelegation.
+func currentDNSClient() *DNSClient {
+	defaultDNSClient.RLock()
+	defer defaultDNSClient.RUnlock()
+	return defaultDNSClient.client
+}
+
+// DNSDelegation asks the TLD's authoritative nameservers whether the domain
+// is delegated (see DNSClient.Delegation).
+//
+// A delegation in the parent zone is a stronger "taken" signal than A/MX
+// records: it exists for every registered domain that has nameservers, is not
+// affected by local resolver caching or search domains, and cannot be faked by
+// wildcard records. A missing delegation (NXDOMAIN) does not prove the name is
+// available: registered names on hold have no delegation either.
+func DNSDelegation(ctx context.Context, d domain.Domain) (Delegation, error) {
+	return currentDNSClient().Delegation(ctx, d)
+}
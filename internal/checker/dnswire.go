@@ -0,0 +1,420 @@
+package checker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// DNS record types used by the authoritative client (RFC 1035, RFC 3596).
+const (
+	DNSTypeA    uint16 = 1
+	DNSTypeNS   uint16 = 2
+	DNSTypeSOA  uint16 = 6
+	DNSTypeMX   uint16 = 15
+	DNSTypeAAAA uint16 = 28
+
+	dnsTypeOPT uint16 = 41
+	dnsClassIN uint16 = 1
+)
+
+// DNS response codes (RFC 1035 section 4.1.1).
+const (
+	DNSRcodeSuccess        = 0 // NOERROR
+	DNSRcodeFormatError    = 1 // FORMERR
+	DNSRcodeServerFailure  = 2 // SERVFAIL
+	DNSRcodeNameError      = 3 // NXDOMAIN
+	DNSRcodeNotImplemented = 4 // NOTIMP
+	DNSRcodeRefused        = 5 // REFUSED
+)
+
+const (
+	// dnsUDPSize is the EDNS0 payload size we advertise (the DNS flag day 2020 value)
+	dnsUDPSize = 1232
+
+	// dnsMaxCompressionJumps bounds pointer chasing in malformed names
+	dnsMaxCompressionJumps = 32
+)
+
+// DNSRecord is a resource record from a DNS response.
+type DNSRecord struct {
+	Name string `json:"name"`
+	Type uint16 `json:"type"`
+	TTL  uint32 `json:"ttl"`
+
+	// Data is the record data in presentation form for A, AAAA, NS, MX
+	// (exchange host) and SOA (primary server); empty for other types
+	Data string `json:"data,omitempty"`
+}
+
+// DNSQuestion is an entry of a DNS message's question section.
+type DNSQuestion struct {
+	Name string `json:"name"`
+	Type uint16 `json:"type"`
+}
+
+// DNSMessage is a DNS message, usually a parsed response.
+type DNSMessage struct {
+	ID            uint16        `json:"id"`
+	Rcode         int           `json:"rcode"`
+	Authoritative bool          `json:"authoritative,omitempty"`
+	Truncated     bool          `json:"truncated,omitempty"`
+	Question      []DNSQuestion `json:"question,omitempty"`
+	Answer        []DNSRecord   `json:"answer,omitempty"`
+	Authority     []DNSRecord   `json:"authority,omitempty"`
+	Additional    []DNSRecord   `json:"additional,omitempty"`
+}
+
+// RcodeName returns the mnemonic of the response code (e.g. "NXDOMAIN").
+func (m *DNSMessage) RcodeName() string {
+	return dnsRcodeName(m.Rcode)
+}
+
+// dnsRcodeName returns the mnemonic of a response code.
+func dnsRcodeName(rcode int) string {
+	switch rcode {
+	case DNSRcodeSuccess:
+		return "NOERROR"
+	case DNSRcodeFormatError:
+		return "FORMERR"
+	case DNSRcodeServerFailure:
+		return "SERVFAIL"
+	case DNSRcodeNameError:
+		return "NXDOMAIN"
+	case DNSRcodeNotImplemented:
+		return "NOTIMP"
+	case DNSRcodeRefused:
+		return "REFUSED"
+	default:
+		return fmt.Sprintf("RCODE%d", rcode)
+	}
+}
+
+// Exchange sends a single non-recursive query for name/qtype to server
+// ("ip" or "ip:port") and returns the parsed response.
+//
+// The query goes over UDP first; a truncated answer (TC bit) is retried over TCP.
+// Like WHOIS queries, exchanges are rate limited per server, and timeouts are
+// retried with backoff and count against the server's circuit breaker.
+func (c *DNSClient) Exchange(ctx context.Context, server, name string, qtype uint16) (*DNSMessage, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+
+	query := func() (*DNSMessage, error) {
+		var msg *DNSMessage
+		err := callUpstream(ctx, "dns", hostOf(server), func(ctx context.Context) error {
+			var err error
+			msg, err = c.exchangeOnce(ctx, server, name, qtype)
+			return err
+		})
+		return msg, err
+	}
+	if t := tapeFrom(ctx); t != nil {
+		return t.exchange(server, name, qtype, query)
+	}
+	return query()
+}
+
+// exchangeOnce performs a single query (UDP, then TCP if truncated) with its own timeout.
+func (c *DNSClient) exchangeOnce(ctx context.Context, server, name string, qtype uint16) (*DNSMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, durationOr(c.Timeout, defaultDNSTimeout))
+	defer cancel()
+
+	id := uint16(rand.Intn(1 << 16))
+	query, err := buildDNSQuery(id, name, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := dnsExchangeConn(ctx, "udp", server, id, query)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Truncated {
+		return dnsExchangeConn(ctx, "tcp", server, id, query)
+	}
+	return msg, nil
+}
+
+// dnsExchangeConn performs one query/response exchange over UDP or TCP.
+func dnsExchangeConn(ctx context.Context, network, server string, id uint16, query []byte) (*DNSMessage, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, dnsError(ctx, fmt.Errorf("dns connect to %s failed: %w", server, err))
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	// Unblock reads immediately if the context is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	var response []byte
+	if network == "tcp" {
+		// RFC 1035 4.2.2: TCP messages carry a two byte length prefix
+		framed := make([]byte, 2+len(query))
+		binary.BigEndian.PutUint16(framed, uint16(len(query)))
+		copy(framed[2:], query)
+		if _, err := conn.Write(framed); err != nil {
+			return nil, dnsError(ctx, fmt.Errorf("dns query to %s failed: %w", server, err))
+		}
+
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return nil, dnsError(ctx, fmt.Errorf("dns read from %s failed: %w", server, err))
+		}
+		response = make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, response); err != nil {
+			return nil, dnsError(ctx, fmt.Errorf("dns read from %s failed: %w", server, err))
+		}
+	} else {
+		if _, err := conn.Write(query); err != nil {
+			return nil, dnsError(ctx, fmt.Errorf("dns query to %s failed: %w", server, err))
+		}
+
+		buf := make([]byte, 65535)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return nil, dnsError(ctx, fmt.Errorf("dns read from %s failed: %w", server, err))
+			}
+			// Ignore stray datagrams that don't answer our query
+			if n >= 2 && binary.BigEndian.Uint16(buf) == id {
+				response = buf[:n]
+				break
+			}
+		}
+	}
+
+	msg, err := ParseDNSMessage(response)
+	if err != nil {
+		return nil, withCode(ErrParse, fmt.Errorf("invalid DNS response from %s: %w", server, err))
+	}
+	if msg.ID != id {
+		return nil, withCode(ErrParse, fmt.Errorf("invalid DNS response from %s: ID mismatch", server))
+	}
+	return msg, nil
+}
+
+// dnsError reports context expiry as a timeout instead of a network error.
+func dnsError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("dns timeout: %w", ctx.Err())
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("dns cancelled: %w", ctx.Err())
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("dns timeout: %w", err)
+	}
+	return err
+}
+
+// buildDNSQuery encodes a query with a single question and an EDNS0 OPT record.
+// Recursion is not requested: authoritative servers answer from their own zone.
+func buildDNSQuery(id uint16, name string, qtype uint16) ([]byte, error) {
+	msg := make([]byte, 12, 64)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[4:], 1)  // QDCOUNT
+	binary.BigEndian.PutUint16(msg[10:], 1) // ARCOUNT (OPT)
+
+	msg, err := appendDNSName(msg, name)
+	if err != nil {
+		return nil, err
+	}
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+
+	// OPT pseudo-record (RFC 6891): root name, type, UDP size as class, TTL 0, no data
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, dnsTypeOPT)
+	msg = binary.BigEndian.AppendUint16(msg, dnsUDPSize)
+	msg = binary.BigEndian.AppendUint32(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, 0)
+
+	return msg, nil
+}
+
+// appendDNSName appends name in uncompressed wire format.
+func appendDNSName(msg []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if len(name) > 253 {
+		return nil, fmt.Errorf("DNS name too long: %s", name)
+	}
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, fmt.Errorf("invalid DNS name: %s", name)
+			}
+			msg = append(msg, byte(len(label)))
+			msg = append(msg, label...)
+		}
+	}
+	return append(msg, 0), nil
+}
+
+// ParseDNSMessage decodes a DNS message (query or response). EDNS0 OPT
+// records are dropped, and record data is only kept for the types listed in
+// DNSRecord.
+func ParseDNSMessage(b []byte) (*DNSMessage, error) {
+	if len(b) < 12 {
+		return nil, fmt.Errorf("message too short")
+	}
+
+	flags := binary.BigEndian.Uint16(b[2:])
+	msg := &DNSMessage{
+		ID:            binary.BigEndian.Uint16(b[0:]),
+		Rcode:         int(flags & 0x000f),
+		Authoritative: flags&0x0400 != 0,
+		Truncated:     flags&0x0200 != 0,
+	}
+	qdcount := int(binary.BigEndian.Uint16(b[4:]))
+	ancount := int(binary.BigEndian.Uint16(b[6:]))
+	nscount := int(binary.BigEndian.Uint16(b[8:]))
+	arcount := int(binary.BigEndian.Uint16(b[10:]))
+
+	off := 12
+	for i := 0; i < qdcount; i++ {
+		name, next, err := readDNSName(b, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next + 4 // QTYPE + QCLASS
+		if off > len(b) {
+			return nil, fmt.Errorf("truncated question")
+		}
+		msg.Question = append(msg.Question, DNSQuestion{Name: name, Type: binary.BigEndian.Uint16(b[next:])})
+	}
+
+	sections := []struct {
+		count int
+		dst   *[]DNSRecord
+	}{
+		{ancount, &msg.Answer},
+		{nscount, &msg.Authority},
+		{arcount, &msg.Additional},
+	}
+	for _, section := range sections {
+		for i := 0; i < section.count; i++ {
+			rr, next, err := readDNSRecord(b, off)
+			if err != nil {
+				// A truncated UDP answer may end mid-record; keep what we have
+				if msg.Truncated {
+					return msg, nil
+				}
+				return nil, err
+			}
+			off = next
+			if rr.Type != dnsTypeOPT {
+				*section.dst = append(*section.dst, rr)
+			}
+		}
+	}
+
+	return msg, nil
+}
+
+// readDNSRecord decodes the resource record starting at off.
+func readDNSRecord(b []byte, off int) (DNSRecord, int, error) {
+	name, off, err := readDNSName(b, off)
+	if err != nil {
+		return DNSRecord{}, 0, err
+	}
+	if off+10 > len(b) {
+		return DNSRecord{}, 0, fmt.Errorf("truncated record")
+	}
+
+	rr := DNSRecord{
+		Name: name,
+		Type: binary.BigEndian.Uint16(b[off:]),
+		TTL:  binary.BigEndian.Uint32(b[off+4:]),
+	}
+	rdlen := int(binary.BigEndian.Uint16(b[off+8:]))
+	off += 10
+	if off+rdlen > len(b) {
+		return DNSRecord{}, 0, fmt.Errorf("truncated record data")
+	}
+	rdata := b[off : off+rdlen]
+
+	switch rr.Type {
+	case DNSTypeA:
+		if rdlen == net.IPv4len {
+			rr.Data = net.IP(rdata).String()
+		}
+	case DNSTypeAAAA:
+		if rdlen == net.IPv6len {
+			rr.Data = net.IP(rdata).String()
+		}
+	case DNSTypeNS, DNSTypeSOA:
+		// Names in RDATA may be compressed against the whole message
+		if rdlen > 0 {
+			if rr.Data, _, err = readDNSName(b, off); err != nil {
+				return DNSRecord{}, 0, err
+			}
+		}
+	case DNSTypeMX:
+		if rdlen > 2 {
+			if rr.Data, _, err = readDNSName(b, off+2); err != nil {
+				return DNSRecord{}, 0, err
+			}
+		}
+	}
+
+	return rr, off + rdlen, nil
+}
+
+// readDNSName decodes a possibly compressed name (RFC 1035 4.1.4) at off.
+// It returns the lowercase name without trailing dot and the offset after it.
+func readDNSName(b []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if off >= len(b) {
+			return "", 0, fmt.Errorf("truncated name")
+		}
+		length := int(b[off])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.ToLower(strings.Join(labels, ".")), next, nil
+
+		case length&0xc0 == 0xc0:
+			if off+1 >= len(b) {
+				return "", 0, fmt.Errorf("truncated name pointer")
+			}
+			if jumps++; jumps > dnsMaxCompressionJumps {
+				return "", 0, fmt.Errorf("name compression loop")
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(b[off:]) & 0x3fff)
+
+		case length&0xc0 != 0:
+			return "", 0, fmt.Errorf("unsupported label type")
+
+		default:
+			if off+1+length > len(b) {
+				return "", 0, fmt.Errorf("truncated label")
+			}
+			labels = append(labels, string(b[off+1:off+1+length]))
+			off += 1 + length
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package checker
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+// fakeDNSAnswer is a scripted response of the fake DNS server.
+type fakeDNSAnswer struct {
+	rcode      int
+	truncated  bool
+	answer     []DNSRecord
+	authority  []DNSRecord
+	additional []DNSRecord
+}
+
+// encodeDNSResponse builds the response to the question in query.
+// Records without a name are owned by the queried name.
+func encodeDNSResponse(t *testing.T, query []byte, a fakeDNSAnswer) []byte {
+	t.Helper()
+
+	q, err := ParseDNSMessage(query)
+	if err != nil || len(q.Question) != 1 {
+		t.Fatalf("fake DNS server: bad query: %v", err)
+	}
+	owned := func(records []DNSRecord) []DNSRecord {
+		out := make([]DNSRecord, len(records))
+		for i, rr := range records {
+			if rr.Name == "" {
+				rr.Name = q.Question[0].Name
+			}
+			out[i] = rr
+		}
+		return out
+	}
+
+	resp := &DNSMessage{
+		ID:            q.ID,
+		Rcode:         a.rcode,
+		Authoritative: true,
+		Truncated:     a.truncated,
+		Question:      q.Question,
+		Answer:        owned(a.answer),
+		Authority:     owned(a.authority),
+		Additional:    owned(a.additional),
+	}
+	msg, err := packDNSMessage(resp)
+	if err != nil {
+		t.Fatalf("fake DNS server: %v", err)
+	}
+	return msg
+}
+
+// packDNSMessage encodes m as the fake DNS server's response (QR bit set, no
+// name compression). Record data is encoded for the types listed in
+// DNSRecord, which only holds one value per record: MX records get
+// preference 10 and SOA records placeholder contact and timers.
+func packDNSMessage(m *DNSMessage) ([]byte, error) {
+	flags := uint16(0x8000 | m.Rcode&0x000f) // QR
+	if m.Authoritative {
+		flags |= 0x0400
+	}
+	if m.Truncated {
+		flags |= 0x0200
+	}
+	msg := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(msg[0:], m.ID)
+	binary.BigEndian.PutUint16(msg[2:], flags)
+	binary.BigEndian.PutUint16(msg[4:], uint16(len(m.Question)))
+	binary.BigEndian.PutUint16(msg[6:], uint16(len(m.Answer)))
+	binary.BigEndian.PutUint16(msg[8:], uint16(len(m.Authority)))
+	binary.BigEndian.PutUint16(msg[10:], uint16(len(m.Additional)))
+
+	var err error
+	for _, q := range m.Question {
+		if msg, err = appendDNSName(msg, q.Name); err != nil {
+			return nil, err
+		}
+		msg = binary.BigEndian.AppendUint16(msg, q.Type)
+		msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+	}
+	for _, section := range [][]DNSRecord{m.Answer, m.Authority, m.Additional} {
+		for _, rr := range section {
+			if msg, err = appendDNSRecord(msg, rr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return msg, nil
+}
+
+// appendDNSRecord appends a resource record in uncompressed wire format.
+func appendDNSRecord(msg []byte, rr DNSRecord) ([]byte, error) {
+	var rdata []byte
+	var err error
+	switch rr.Type {
+	case DNSTypeA:
+		if rdata = net.ParseIP(rr.Data).To4(); rdata == nil {
+			return nil, fmt.Errorf("invalid A record data: %q", rr.Data)
+		}
+	case DNSTypeAAAA:
+		ip := net.ParseIP(rr.Data)
+		if ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("invalid AAAA record data: %q", rr.Data)
+		}
+		rdata = ip.To16()
+	case DNSTypeNS:
+		rdata, err = appendDNSName(nil, rr.Data)
+	case DNSTypeMX:
+		rdata, err = appendDNSName([]byte{0, 10}, rr.Data)
+	case DNSTypeSOA:
+		// MNAME, RNAME, then serial/refresh/retry/expire/minimum
+		rdata, err = appendDNSName(nil, rr.Data)
+		if err == nil {
+			rdata, err = appendDNSName(rdata, "hostmaster."+strings.TrimSuffix(rr.Data, "."))
+		}
+		rdata = append(rdata, make([]byte, 20)...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err = appendDNSName(msg, rr.Name)
+	if err != nil {
+		return nil, err
+	}
+	msg = binary.BigEndian.AppendUint16(msg, rr.Type)
+	msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+	msg = binary.BigEndian.AppendUint32(msg, rr.TTL)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(rdata)))
+	return append(msg, rdata...), nil
+}
+
+// startDNSServer runs a UDP+TCP DNS server on the same local port. handler
+// receives the queried name and whether the query came over TCP.
+func startDNSServer(t *testing.T, handler func(name string, tcp bool) fakeDNSAnswer) string {
+	t.Helper()
+	isolateUpstreams(t)
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+	ln, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		t.Skipf("cannot listen on tcp port of udp socket: %v", err)
+	}
+	t.Cleanup(func() {
+		pc.Close()
+		ln.Close()
+	})
+
+	respond := func(query []byte, tcp bool) []byte {
+		name, _, err := readDNSName(query, 12)
+		if err != nil {
+			return nil
+		}
+		return encodeDNSResponse(t, query, handler(name, tcp))
+	}
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := respond(buf[:n], false); resp != nil {
+				pc.WriteTo(resp, addr)
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err != nil {
+					return
+				}
+				resp := respond(query, true)
+				framed := binary.BigEndian.AppendUint16(nil, uint16(len(resp)))
+				conn.Write(append(framed, resp...))
+			}(conn)
+		}
+	}()
+
+	return pc.LocalAddr().String()
+}
+
+func TestDNSClientExchange(t *testing.T) {
+	server := startDNSServer(t, func(name string, tcp bool) fakeDNSAnswer {
+		return fakeDNSAnswer{
+			answer: []DNSRecord{
+				{Type: DNSTypeA, TTL: 60, Data: "192.0.2.1"},
+				{Type: DNSTypeAAAA, TTL: 60, Data: "2001:db8::1"},
+			},
+		}
+	})
+
+	c := &DNSClient{Timeout: time.Second}
+	msg, err := c.Exchange(context.Background(), server, "Example.TEST", DNSTypeA)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if msg.Rcode != DNSRcodeSuccess || !msg.Authoritative {
+		t.Errorf("Exchange() rcode = %s, aa = %v", msg.RcodeName(), msg.Authoritative)
+	}
+	if len(msg.Answer) != 2 || msg.Answer[0].Data != "192.0.2.1" || msg.Answer[1].Data != "2001:db8::1" {
+		t.Errorf("Exchange() answer = %+v", msg.Answer)
+	}
+	if msg.Answer[0].Name != "example.test" {
+		t.Errorf("Exchange() owner = %q, want lowercase example.test", msg.Answer[0].Name)
+	}
+}
+
+// TestDNSClientTCPFallback verifies truncated UDP answers are retried over TCP
+func TestDNSClientTCPFallback(t *testing.T) {
+	var tcpQueries int32
+	server := startDNSServer(t, func(name string, tcp bool) fakeDNSAnswer {
+		if !tcp {
+			return fakeDNSAnswer{truncated: true}
+		}
+		atomic.AddInt32(&tcpQueries, 1)
+		return fakeDNSAnswer{authority: []DNSRecord{{Type: DNSTypeNS, Data: "ns1.example.net"}}}
+	})
+
+	c := &DNSClient{Timeout: time.Second}
+	msg, err := c.Exchange(context.Background(), server, "example.test", DNSTypeNS)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if atomic.LoadInt32(&tcpQueries) != 1 {
+		t.Errorf("TCP queries = %d, want 1", tcpQueries)
+	}
+	if msg.Truncated || len(msg.Authority) != 1 {
+		t.Errorf("Exchange() = %+v, want full TCP answer", msg)
+	}
+}
+
+func TestDNSClientDelegation(t *testing.T) {
+	tests := []struct {
+		name          string
+		answer        fakeDNSAnswer
+		wantDelegated bool
+		wantRcode     int
+		wantSignal    string
+		wantErr       bool
+	}{
+		{
+			name: "referral means delegated",
+			answer: fakeDNSAnswer{authority: []DNSRecord{
+				{Name: "example.test", Type: DNSTypeNS, Data: "ns1.example.net"},
+				{Name: "example.test", Type: DNSTypeNS, Data: "ns2.example.net"},
+			}},
+			wantDelegated: true,
+			wantSignal:    "NS delegation: ns1.example.net, ns2.example.net",
+		},
+		{
+			name:       "NXDOMAIN",
+			answer:     fakeDNSAnswer{rcode: DNSRcodeNameError},
+			wantRcode:  DNSRcodeNameError,
+			wantSignal: "NXDOMAIN from",
+		},
+		{
+			name:       "NOERROR without NS",
+			answer:     fakeDNSAnswer{authority: []DNSRecord{{Name: "test", Type: DNSTypeSOA, Data: "a.nic.test"}}},
+			wantSignal: "NOERROR without delegation",
+		},
+		{
+			name:    "SERVFAIL is an error",
+			answer:  fakeDNSAnswer{rcode: DNSRcodeServerFailure},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := startDNSServer(t, func(name string, tcp bool) fakeDNSAnswer { return tt.answer })
+			c := &DNSClient{Timeout: time.Second, Servers: map[string][]string{"test": {server}}}
+
+			got, err := c.Delegation(context.Background(), domain.Domain{Full: "example.test", Name: "example", TLD: "test"})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Delegation() expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Delegation() error = %v", err)
+			}
+			if got.Delegated() != tt.wantDelegated || got.Rcode != tt.wantRcode {
+				t.Errorf("Delegation() = %+v, want delegated=%v rcode=%d", got, tt.wantDelegated, tt.wantRcode)
+			}
+			if !strings.HasPrefix(got.Signal(), tt.wantSignal) {
+				t.Errorf("Signal() = %q, want prefix %q", got.Signal(), tt.wantSignal)
+			}
+		})
+	}
+}
+
+// TestDNSClientDelegationFallback verifies a failing server is skipped
+func TestDNSClientDelegationFallback(t *testing.T) {
+	bad := startDNSServer(t, func(string, bool) fakeDNSAnswer { return fakeDNSAnswer{rcode: DNSRcodeRefused} })
+	good := startDNSServer(t, func(string, bool) fakeDNSAnswer {
+		return fakeDNSAnswer{authority: []DNSRecord{{Name: "example.test", Type: DNSTypeNS, Data: "ns1.example.net"}}}
+	})
+
+	c := &DNSClient{Timeout: time.Second, Servers: map[string][]string{"test": {bad, good}}}
+	got, err := c.Delegation(context.Background(), domain.Domain{Full: "example.test", TLD: "test"})
+	if err != nil {
+		t.Fatalf("Delegation() error = %v", err)
+	}
+	if !got.Delegated() || got.Server != good {
+		t.Errorf("Delegation() = %+v, want delegation from %s", got, good)
+	}
+}
+
+// TestDNSClientReferralServers verifies referral targets are reached through glue
+func TestDNSClientReferralServers(t *testing.T) {
+	msg := &DNSMessage{Additional: []DNSRecord{
+		{Name: "ns.co.test", Type: DNSTypeA, Data: "192.0.2.53"},
+		{Name: "ns.co.test", Type: DNSTypeAAAA, Data: "2001:db8::53"},
+		{Name: "unrelated.test", Type: DNSTypeA, Data: "192.0.2.99"},
+	}}
+
+	got := (&DNSClient{}).referralServers(context.Background(), msg, []string{"ns.co.test"})
+	want := []string{"192.0.2.53:53", "[2001:db8::53]:53"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("referralServers() = %v, want %v", got, want)
+	}
+}
+
+func TestParseDNSMessageCompression(t *testing.T) {
+	// Response for example.test NS with the NS owner and target compressed
+	msg := []byte{
+		0x12, 0x34, 0x84, 0x00, 0, 1, 0, 0, 0, 1, 0, 0,
+		// question: example.test NS IN (offset 12)
+		7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 4, 't', 'e', 's', 't', 0, 0, 2, 0, 1,
+		// authority: owner = pointer to 12, NS, IN, TTL 300
+		0xc0, 12, 0, 2, 0, 1, 0, 0, 1, 0x2c, 0, 6,
+		// rdata: "ns1" + pointer to "example.test"
+		3, 'n', 's', '1', 0xc0, 12,
+	}
+
+	got, err := ParseDNSMessage(msg)
+	if err != nil {
+		t.Fatalf("ParseDNSMessage() error = %v", err)
+	}
+	if got.ID != 0x1234 || !got.Authoritative {
+		t.Errorf("ParseDNSMessage() header = %+v", got)
+	}
+	if len(got.Authority) != 1 || got.Authority[0].Name != "example.test" || got.Authority[0].Data != "ns1.example.test" {
+		t.Errorf("ParseDNSMessage() authority = %+v", got.Authority)
+	}
+}
+
+func TestParseDNSMessageMalformed(t *testing.T) {
+	inputs := map[string][]byte{
+		"short header":     {0, 1, 2},
+		"truncated name":   {0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 7, 'e', 'x'},
+		"compression loop": {0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0xc0, 12},
+	}
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseDNSMessage(input); err == nil {
+				t.Error("ParseDNSMessage() expected error")
+			}
+		})
+	}
+}
+
+func TestPackDNSMessageRoundTrip(t *testing.T) {
+	msg := &DNSMessage{
+		ID:            0xbeef,
+		Rcode:         DNSRcodeSuccess,
+		Authoritative: true,
+		Question:      []DNSQuestion{{Name: "example.test", Type: DNSTypeNS}},
+		Answer: []DNSRecord{
+			{Name: "example.test", Type: DNSTypeA, TTL: 60, Data: "192.0.2.1"},
+			{Name: "example.test", Type: DNSTypeAAAA, TTL: 60, Data: "2001:db8::1"},
+			{Name: "example.test", Type: DNSTypeMX, TTL: 60, Data: "mail.example.test"},
+		},
+		Authority:  []DNSRecord{{Name: "example.test", Type: DNSTypeNS, TTL: 3600, Data: "ns1.example.net"}},
+		Additional: []DNSRecord{{Name: "test", Type: DNSTypeSOA, TTL: 900, Data: "a.nic.test"}},
+	}
+
+	packed, err := packDNSMessage(msg)
+	if err != nil {
+		t.Fatalf("packDNSMessage() error = %v", err)
+	}
+	got, err := ParseDNSMessage(packed)
+	if err != nil {
+		t.Fatalf("ParseDNSMessage() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, msg) {
+		t.Errorf("round trip = %+v, want %+v", got, msg)
+	}
+
+	if _, err := packDNSMessage(&DNSMessage{Answer: []DNSRecord{{Name: "x.test", Type: DNSTypeA, Data: "2001:db8::1"}}}); err == nil {
+		t.Error("packDNSMessage() accepted an IPv6 address in an A record")
+	}
+}
@@ -0,0 +1,135 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"domaincheck/internal/domain"
+)
+
+// Sentinel errors classifying why a check failed. Errors returned by Check,
+// CheckConsensus and the sources wrap one of them, so callers can branch with
+// errors.Is instead of matching messages:
+//
+//	if errors.Is(err, checker.ErrRateLimited) {
+//	    // back off and retry later
+//	}
+//
+// ErrorCode maps them to the stable codes reported in Result.ErrorCode.
+var (
+	// ErrUnsupportedTLD means no source can check the domain's TLD
+	// (no RDAP server in the bootstrap registry, no WHOIS server known)
+	ErrUnsupportedTLD = errors.New("unsupported TLD")
+
+	// ErrTimeout means an upstream did not answer in time
+	ErrTimeout = errors.New("timeout")
+
+	// ErrRateLimited is returned when an upstream's rate limit (ours, or one it
+	// announced with Retry-After) would delay a query beyond the caller's deadline,
+	// or when the upstream refused the query as too frequent
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrUpstreamUnavailable means an upstream could not be reached or kept
+	// failing (connection refused, HTTP 5xx, open circuit breaker)
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+
+	// ErrParse means an upstream answered with something we could not interpret
+	ErrParse = errors.New("unparseable response")
+
+	// ErrInvalidDomain means the input is not a valid domain name.
+	// It is domain.ErrInvalidFormat, so errors from domain.Normalize match it.
+	ErrInvalidDomain = domain.ErrInvalidFormat
+
+	// ErrCancelled means the caller cancelled the check
+	ErrCancelled = errors.New("cancelled")
+)
+
+// Error codes reported in Result.ErrorCode (JSON "error_code").
+// They are part of the API and never change meaning.
+const (
+	CodeUnsupportedTLD      = "unsupported_tld"
+	CodeTimeout             = "timeout"
+	CodeRateLimited         = "rate_limited"
+	CodeUpstreamUnavailable = "upstream_unavailable"
+	CodeParse               = "parse_error"
+	CodeInvalidDomain       = "invalid_domain"
+	CodeCancelled           = "cancelled"
+
+	// CodeUnknown is reported for errors that fit none of the classes above
+	CodeUnknown = "unknown"
+)
+
+// ErrorCode returns the stable code for a check error, or "" for nil.
+//
+// Errors wrapping one of the sentinels map to its code. Unwrapped errors are
+// classified by cause: context cancellation → CodeCancelled, deadlines and
+// network timeouts → CodeTimeout, other network errors → CodeUpstreamUnavailable.
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrInvalidDomain), errors.Is(err, domain.ErrEmptyDomain):
+		return CodeInvalidDomain
+	case errors.Is(err, ErrUnsupportedTLD):
+		return CodeUnsupportedTLD
+	case errors.Is(err, ErrRateLimited):
+		return CodeRateLimited
+	case errors.Is(err, ErrCancelled), errors.Is(err, context.Canceled):
+		return CodeCancelled
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	case errors.Is(err, ErrParse):
+		return CodeParse
+	case errors.Is(err, ErrUpstreamUnavailable), errors.Is(err, ErrCircuitOpen):
+		return CodeUpstreamUnavailable
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return CodeTimeout
+		}
+		return CodeUpstreamUnavailable
+	case isTransient(err):
+		return CodeUpstreamUnavailable
+	default:
+		return CodeUnknown
+	}
+}
+
+// codeSentinels maps codes back to the sentinel errors, for classify.
+var codeSentinels = map[string]error{
+	CodeUnsupportedTLD:      ErrUnsupportedTLD,
+	CodeTimeout:             ErrTimeout,
+	CodeRateLimited:         ErrRateLimited,
+	CodeUpstreamUnavailable: ErrUpstreamUnavailable,
+	CodeParse:               ErrParse,
+	CodeInvalidDomain:       ErrInvalidDomain,
+	CodeCancelled:           ErrCancelled,
+}
+
+// classify makes err match its class's sentinel with errors.Is, keeping its
+// message. Errors that already wrap the sentinel, and unclassified errors,
+// are returned as is.
+func classify(err error) error {
+	sentinel, ok := codeSentinels[ErrorCode(err)]
+	if !ok || errors.Is(err, sentinel) {
+		return err
+	}
+	return withCode(sentinel, err)
+}
+
+// codedError attaches a sentinel to an error without changing its message.
+type codedError struct {
+	sentinel error
+	err      error
+}
+
+// withCode returns err, also matching sentinel with errors.Is.
+func withCode(sentinel, err error) error {
+	return &codedError{sentinel: sentinel, err: err}
+}
+
+func (e *codedError) Error() string   { return e.err.Error() }
+func (e *codedError) Unwrap() []error { return []error{e.sentinel, e.err} }
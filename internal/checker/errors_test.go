@@ -0,0 +1,73 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"domaincheck/internal/domain"
+)
+
+// timeoutError is a net.Error reporting a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"unsupported TLD", withCode(ErrUnsupportedTLD, errors.New("RDAP server not configured for TLD: zz")), CodeUnsupportedTLD},
+		{"rate limited", fmt.Errorf("%w: busy", ErrRateLimited), CodeRateLimited},
+		{"circuit open", fmt.Errorf("%w: whois", ErrCircuitOpen), CodeUpstreamUnavailable},
+		{"parse", withCode(ErrParse, errors.New("whois returned no output")), CodeParse},
+		{"invalid domain", fmt.Errorf("normalize: %w", domain.ErrInvalidFormat), CodeInvalidDomain},
+		{"empty domain", domain.ErrEmptyDomain, CodeInvalidDomain},
+		{"context cancelled", fmt.Errorf("whois cancelled: %w", context.Canceled), CodeCancelled},
+		{"context deadline", fmt.Errorf("whois timeout: %w", context.DeadlineExceeded), CodeTimeout},
+		{"network timeout", fmt.Errorf("dns timeout: %w", timeoutError{}), CodeTimeout},
+		{"connection refused", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, CodeUpstreamUnavailable},
+		{"unclassified", errors.New("something odd"), CodeUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorCode(tt.err); got != tt.want {
+				t.Errorf("ErrorCode(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	err := classify(fmt.Errorf("whois timeout: %w", context.DeadlineExceeded))
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("classify() = %v, want to match ErrTimeout and the cause", err)
+	}
+	if err.Error() != "whois timeout: context deadline exceeded" {
+		t.Errorf("classify() changed the message to %q", err.Error())
+	}
+
+	plain := errors.New("something odd")
+	if classify(plain) != plain {
+		t.Error("classify() wrapped an unclassified error")
+	}
+}
+
+func TestPipelineUnsupportedTLD(t *testing.T) {
+	p := NewPipeline(Stage{Source: fakeSource{name: "rdap", tlds: []string{"com"}}})
+
+	result, err := p.Check(context.Background(), domain.Domain{Full: "example.zz", Name: "example", TLD: "zz"})
+	if !errors.Is(err, ErrUnsupportedTLD) {
+		t.Errorf("Check() err = %v, want ErrUnsupportedTLD", err)
+	}
+	if result.ErrorCode != CodeUnsupportedTLD {
+		t.Errorf("Check() ErrorCode = %q, want %q", result.ErrorCode, CodeUnsupportedTLD)
+	}
+}
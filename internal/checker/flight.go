@@ -0,0 +1,206 @@
+package checker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+// flightGroup coalesces concurrent checks of the same domain, so callers that
+// ask while a check is in flight share its Result instead of querying the
+// upstreams again. It coalesces other shared work the same way (see share),
+// such as wildcardDetector's probe of a TLD.
+//
+// The shared check runs on a context detached from any single caller: one
+// caller giving up (timeout, client disconnect) does not fail the others.
+// The shared check is cancelled once every caller waiting for it has given
+// up, and its deadline is the latest of theirs (see flightContext).
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is one in-flight check and the callers waiting for it.
+type flightCall struct {
+	done    chan struct{}
+	value   interface{}
+	err     error
+	waiters int
+	ctx     *flightContext
+}
+
+// flightContext is the context a shared check runs on. It keeps the values of
+// the caller that started the check (e.g. WithFresh) but not its cancellation.
+// Its deadline is the latest deadline of the callers that joined, moving later
+// as callers with more time arrive; a caller without a deadline removes it.
+// Upstream timeouts and rate limit waits derived from it thus never outlast
+// every caller, nor cut short one with more time.
+//
+// It tracks its own cancellation rather than wrapping a context.WithCancel:
+// contexts derived from it then take their error from Err, so they report
+// context.DeadlineExceeded too once the deadline passes.
+type flightContext struct {
+	context.Context // detached from the first caller, only used for values
+
+	done chan struct{}
+
+	mu       sync.Mutex
+	err      error
+	deadline time.Time
+	bounded  bool // false once a caller without deadline joined
+	timer    *time.Timer
+}
+
+// newFlightContext returns the context of a check started by a caller with ctx.
+func newFlightContext(ctx context.Context) *flightContext {
+	c := &flightContext{
+		Context: context.WithoutCancel(ctx),
+		done:    make(chan struct{}),
+		bounded: true,
+	}
+	c.join(ctx)
+	return c
+}
+
+// join extends the deadline to cover a caller with ctx.
+func (c *flightContext) join(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.bounded || c.err != nil {
+		return
+	}
+	deadline, ok := ctx.Deadline()
+	switch {
+	case !ok:
+		c.bounded = false
+		c.deadline = time.Time{}
+		if c.timer != nil {
+			c.timer.Stop()
+		}
+	case !deadline.After(c.deadline):
+		// An earlier caller already allows more time
+	case c.timer == nil:
+		c.deadline = deadline
+		c.timer = time.AfterFunc(time.Until(deadline), func() { c.stop(context.DeadlineExceeded) })
+	default:
+		c.deadline = deadline
+		c.timer.Reset(time.Until(deadline))
+	}
+}
+
+// Deadline returns the latest deadline of the callers.
+func (c *flightContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadline, c.bounded
+}
+
+// Done is closed once the check is stopped or its deadline passes.
+func (c *flightContext) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err reports why Done was closed: context.DeadlineExceeded once the deadline
+// has passed, like a context made by context.WithDeadline, or the error the
+// check was stopped with.
+func (c *flightContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// stop ends the context with err (context.Canceled or
+// context.DeadlineExceeded) and releases its timer. Only the first call has
+// an effect.
+func (c *flightContext) stop(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	close(c.done)
+}
+
+// do runs check(ctx, d) once per key at a time and hands its outcome to every
+// caller that arrives while it runs. Callers whose ctx ends before the check
+// does get an error result for ctx.Err().
+func (g *flightGroup) do(ctx context.Context, key string, d domain.Domain, check func(context.Context, domain.Domain) (domain.Result, error)) (domain.Result, error) {
+	start := time.Now()
+	v, err := g.share(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return check(ctx, d)
+	})
+	result, ok := v.(domain.Result)
+	if !ok {
+		// The caller gave up
+		return failResult(domain.Result{Domain: d, CheckedAt: start}, err, "", start)
+	}
+	return result, err
+}
+
+// share is do for work other than a domain check: it runs fn once per key at
+// a time and hands its value and error to every caller that arrives while it
+// runs. Callers whose ctx ends before fn does get a nil value and ctx.Err().
+func (g *flightGroup) share(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	c, ok := g.calls[key]
+	if ok && c.ctx.Err() == nil {
+		c.waiters++
+		c.ctx.join(ctx)
+	} else {
+		// Nothing in flight, or a call past its deadline that is about to fail
+		c = &flightCall{done: make(chan struct{}), waiters: 1, ctx: newFlightContext(ctx)}
+		g.calls[key] = c
+		go g.run(key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, c.err
+	case <-ctx.Done():
+		g.leave(key, c, ctx.Err())
+		return nil, ctx.Err()
+	}
+}
+
+// run executes the shared call and wakes up its waiters.
+func (g *flightGroup) run(key string, c *flightCall, fn func(context.Context) (interface{}, error)) {
+	c.value, c.err = fn(c.ctx)
+	c.ctx.stop(context.Canceled)
+
+	g.mu.Lock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+
+	close(c.done)
+}
+
+// leave removes a waiter that gave up with err, cancelling the check with the
+// same error when none remain.
+func (g *flightGroup) leave(key string, c *flightCall, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c.waiters--
+	if c.waiters > 0 {
+		return
+	}
+	c.ctx.stop(err)
+	// New callers must not join a cancelled check
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
@@ -0,0 +1,238 @@
+package checker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+var flightDomain = domain.Domain{Full: "example.com", Name: "example", TLD: "com"}
+
+// blockingCheck returns a check that counts its calls and waits for release
+// (or its context) before answering taken.
+func blockingCheck(calls *int32, release <-chan struct{}) func(context.Context, domain.Domain) (domain.Result, error) {
+	return func(ctx context.Context, d domain.Domain) (domain.Result, error) {
+		atomic.AddInt32(calls, 1)
+		select {
+		case <-release:
+			return domain.Result{Domain: d, Status: domain.StatusTaken, Source: "rdap"}, nil
+		case <-ctx.Done():
+			return domain.Result{Domain: d, Status: domain.StatusError}, ctx.Err()
+		}
+	}
+}
+
+// waitForWaiters blocks until the in-flight call for key has n waiters.
+func waitForWaiters(t *testing.T, g *flightGroup, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		c := g.calls[key]
+		ok := c != nil && c.waiters == n
+		g.mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d waiters on %s", n, key)
+}
+
+func TestFlightGroupShares(t *testing.T) {
+	g := &flightGroup{}
+	var calls int32
+	release := make(chan struct{})
+	check := blockingCheck(&calls, release)
+
+	const n = 5
+	results := make([]domain.Result, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = g.do(context.Background(), "k", flightDomain, check)
+		}(i)
+	}
+	waitForWaiters(t, g, "k", n)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("check ran %d times, want 1", calls)
+	}
+	for i, r := range results {
+		if r.Status != domain.StatusTaken {
+			t.Errorf("caller %d got %v, want shared taken result", i, r.Status)
+		}
+	}
+
+	// Once finished, the next call runs a new check
+	g.do(context.Background(), "k", flightDomain, check)
+	if calls != 2 {
+		t.Errorf("check ran %d times after completion, want 2", calls)
+	}
+}
+
+func TestFlightGroupCallerCancel(t *testing.T) {
+	g := &flightGroup{}
+	var calls int32
+	release := make(chan struct{})
+	check := blockingCheck(&calls, release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := g.do(ctx, "k", flightDomain, check)
+		first <- err
+	}()
+	waitForWaiters(t, g, "k", 1)
+
+	second := make(chan domain.Result, 1)
+	go func() {
+		r, _ := g.do(context.Background(), "k", flightDomain, check)
+		second <- r
+	}()
+	waitForWaiters(t, g, "k", 2)
+
+	// The first caller giving up must not cancel the shared check
+	cancel()
+	if err := <-first; err == nil {
+		t.Error("cancelled caller got nil error")
+	}
+	close(release)
+	if r := <-second; r.Status != domain.StatusTaken {
+		t.Errorf("remaining caller got %v, want taken", r.Status)
+	}
+	if calls != 1 {
+		t.Errorf("check ran %d times, want 1", calls)
+	}
+}
+
+func TestFlightGroupAllCancel(t *testing.T) {
+	g := &flightGroup{}
+	cancelled := make(chan struct{})
+	check := func(ctx context.Context, d domain.Domain) (domain.Result, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return domain.Result{Domain: d, Status: domain.StatusError}, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan domain.Result, 1)
+	go func() {
+		r, _ := g.do(ctx, "k", flightDomain, check)
+		done <- r
+	}()
+	waitForWaiters(t, g, "k", 1)
+	cancel()
+
+	if r := <-done; r.Status != domain.StatusError || r.Domain != flightDomain {
+		t.Errorf("cancelled caller got %+v, want error result for the domain", r)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("shared check was not cancelled after every caller left")
+	}
+}
+
+// TestFlightContextDeadlineChildren verifies contexts derived from a shared
+// check's context report context.DeadlineExceeded once its deadline passes,
+// as upstream timeouts derived from it would, and context.Canceled when the
+// check is stopped instead
+func TestFlightContextDeadlineChildren(t *testing.T) {
+	caller, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c := newFlightContext(caller)
+
+	child, cancelChild := context.WithCancel(c)
+	defer cancelChild()
+	// A longer timeout than the shared deadline is cut short by it
+	timeout, cancelTimeout := context.WithTimeout(c, time.Minute)
+	defer cancelTimeout()
+
+	for _, ctx := range []context.Context{c, child, timeout} {
+		select {
+		case <-ctx.Done():
+		case <-time.After(2 * time.Second):
+			t.Fatal("context not done after the shared deadline")
+		}
+		if err := ctx.Err(); err != context.DeadlineExceeded {
+			t.Errorf("Err() = %v, want context.DeadlineExceeded", err)
+		}
+	}
+
+	c = newFlightContext(context.Background())
+	child, cancelChild = context.WithCancel(c)
+	defer cancelChild()
+	c.stop(context.Canceled)
+	<-child.Done()
+	if err := child.Err(); err != context.Canceled {
+		t.Errorf("Err() after stop = %v, want context.Canceled", err)
+	}
+}
+
+// TestFlightGroupDeadline verifies the shared check runs until the latest
+// deadline of its callers, and no longer
+func TestFlightGroupDeadline(t *testing.T) {
+	g := &flightGroup{}
+	checkCtx := make(chan context.Context, 1)
+	ended := make(chan error, 1)
+	check := func(ctx context.Context, d domain.Domain) (domain.Result, error) {
+		checkCtx <- ctx
+		<-ctx.Done()
+		ended <- ctx.Err()
+		return domain.Result{Domain: d, Status: domain.StatusError}, ctx.Err()
+	}
+
+	start := time.Now()
+	short, cancelShort := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelShort()
+	long, cancelLong := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancelLong()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); g.do(short, "k", flightDomain, check) }()
+	ctx := <-checkCtx
+	go func() { defer wg.Done(); g.do(long, "k", flightDomain, check) }()
+	waitForWaiters(t, g, "k", 2)
+
+	want, _ := long.Deadline()
+	if got, ok := ctx.Deadline(); !ok || !got.Equal(want) {
+		t.Errorf("shared check deadline = %v, %v, want the later caller's %v", got, ok, want)
+	}
+	if err := <-ended; err != context.DeadlineExceeded {
+		t.Errorf("shared check ended with %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("shared check ended after %v, before the later caller's deadline", elapsed)
+	}
+	wg.Wait()
+
+	// A caller without deadline lifts it
+	release := make(chan struct{})
+	var calls int32
+	blocking := blockingCheck(&calls, release)
+	bounded, cancelBounded := context.WithTimeout(context.Background(), time.Minute)
+	defer cancelBounded()
+	wg.Add(2)
+	go func() { defer wg.Done(); g.do(bounded, "u", flightDomain, blocking) }()
+	waitForWaiters(t, g, "u", 1)
+	go func() { defer wg.Done(); g.do(context.Background(), "u", flightDomain, blocking) }()
+	waitForWaiters(t, g, "u", 2)
+	g.mu.Lock()
+	_, ok := g.calls["u"].ctx.Deadline()
+	g.mu.Unlock()
+	if ok {
+		t.Error("shared check has a deadline after a caller without one joined")
+	}
+	close(release)
+	wg.Wait()
+}
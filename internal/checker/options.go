@@ -0,0 +1,142 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Option configures a Checker created with New.
+type Option func(*Checker)
+
+// WithDNSTimeout bounds the DNS pre-filter's lookups of a domain (default 3s).
+// A TLD's wildcard probe has its own timeout.
+func WithDNSTimeout(d time.Duration) Option {
+	return func(c *Checker) { c.dnsTimeout = d }
+}
+
+// WithRDAPTimeout bounds a complete RDAP lookup, retries included (default 10s).
+func WithRDAPTimeout(d time.Duration) Option {
+	return func(c *Checker) { c.rdapTimeout = d }
+}
+
+// WithWHOISTimeout bounds a complete WHOIS lookup, discovery and referrals
+// included (default 10s). It applies to a copy of the WHOISClient given with
+// WithWHOISClient, or to a new client.
+func WithWHOISTimeout(d time.Duration) Option {
+	return func(c *Checker) { c.whoisTimeout = d }
+}
+
+// WithHTTPClient sets the HTTP client for RDAP queries. The client is shared
+// by all queries, so its Transport pools connections to each RDAP server.
+// Timeouts are set with WithRDAPTimeout; a client Timeout also applies.
+//
+// By default a package-wide client using http.DefaultTransport is used.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Checker) { c.httpClient = client }
+}
+
+// WithTransport sets the HTTP transport for RDAP queries (proxies, TLS
+// settings, connection pool sizes, or an httptest server's transport).
+// It replaces any client given with WithHTTPClient.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Checker) { c.httpClient = &http.Client{Transport: rt} }
+}
+
+// WithUserAgent sets the User-Agent sent to RDAP servers (default "domaincheck/1.0").
+func WithUserAgent(ua string) Option {
+	return func(c *Checker) { c.userAgent = ua }
+}
+
+// WithResolver sets the resolver used by the DNS pre-filter and, unless
+// WithDNSClient is given, to discover the TLDs' authoritative nameservers.
+//
+// By default the system resolver is used.
+func WithResolver(r *net.Resolver) Option {
+	return func(c *Checker) { c.resolver = r }
+}
+
+// WithDNSServer sends every DNS lookup of WithResolver to one server instead
+// of the system's (e.g. "127.0.0.1:5353", or "10.0.0.53" for port 53).
+func WithDNSServer(addr string) Option {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "53")
+	}
+	return WithResolver(&net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		},
+	})
+}
+
+// WithDNSClient sets the client for the authoritative DNS stage
+// (default: the client set with SetDNSClient).
+func WithDNSClient(client *DNSClient) Option {
+	return func(c *Checker) { c.dnsClient = client }
+}
+
+// WithWHOISClient sets the client for the WHOIS stage
+// (default: the client set with SetWHOISClient).
+func WithWHOISClient(client *WHOISClient) Option {
+	return func(c *Checker) { c.whoisClient = client }
+}
+
+// WithWHOISServers pins WHOIS servers: TLD → "host" or "host:port". TLDs not
+// listed are discovered through whois.iana.org. Like WithWHOISTimeout, it
+// applies to a copy of the WHOISClient given with WithWHOISClient.
+func WithWHOISServers(servers map[string]string) Option {
+	return func(c *Checker) { c.whoisServers = servers }
+}
+
+// WithBootstrap sets the RDAP bootstrap registry mapping TLDs to RDAP servers
+// (default: the registry set with SetBootstrap).
+func WithBootstrap(b *Bootstrap) Option {
+	return func(c *Checker) { c.bootstrap = b }
+}
+
+// WithCache sets the result cache; nil disables caching
+// (default: the cache set with SetCache).
+func WithCache(cache *Cache) Option {
+	return func(c *Checker) {
+		c.cache = cache
+		c.cacheSet = true
+	}
+}
+
+// WithPipeline replaces the checker's pipeline. The settings of the other
+// options only apply to the sources of the default pipeline, so this is
+// meant for pipelines built from custom Sources.
+func WithPipeline(p *Pipeline) Option {
+	return func(c *Checker) { c.pipeline = p }
+}
+
+// WithZoneIndex answers from zone files indexed with BuildZoneIndex before
+// any network query: the default pipeline starts with NewZoneSource(index),
+// which reports delegated names as taken and passes the others on to DNS,
+// RDAP and WHOIS. TLDs without an index are checked as usual.
+func WithZoneIndex(index *ZoneIndex) Option {
+	return func(c *Checker) { c.zones = index }
+}
+
+// WithRecording saves the upstream traffic of every check (DNS answers, RDAP
+// responses, WHOIS text) and its outcome to dir/<domain>.json, replacing the
+// previous recording of the domain. Recorded checks bypass the cache.
+// An empty dir disables recording.
+//
+// See Recording for the format and WithReplay to serve checks from it.
+func WithRecording(dir string) Option {
+	return func(c *Checker) { c.recordDir = dir }
+}
+
+// WithReplay serves every check from the recordings in dir (see
+// WithRecording) without contacting any upstream: a domain is checked against
+// its recorded answers, so a misclassification can be reproduced after the
+// registry's data has changed. Queries the recording has no answer for fail
+// with ErrNotRecorded. Replay takes precedence over WithRecording; an empty
+// dir disables replay.
+func WithReplay(dir string) Option {
+	return func(c *Checker) { c.replayDir = dir }
+}
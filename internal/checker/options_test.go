@@ -0,0 +1,202 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+// localUpstreams are stand-ins for every upstream a Checker talks to.
+type localUpstreams struct {
+	dns       string // DNS server address; answers A for "taken.test", NXDOMAIN otherwise
+	rdap      *httptest.Server
+	bootstrap *Bootstrap
+	conns     int32 // TCP connections accepted by rdap
+}
+
+// startLocalUpstreams starts a DNS server and an RDAP server handling .test.
+func startLocalUpstreams(t *testing.T, rdap http.HandlerFunc) *localUpstreams {
+	t.Helper()
+	u := &localUpstreams{}
+
+	u.dns = startDNSServer(t, func(name string, tcp bool) fakeDNSAnswer {
+		if name == "taken.test" {
+			return fakeDNSAnswer{answer: []DNSRecord{{Type: DNSTypeA, TTL: 60, Data: "192.0.2.1"}}}
+		}
+		return fakeDNSAnswer{rcode: DNSRcodeNameError}
+	})
+
+	u.rdap = httptest.NewUnstartedServer(rdap)
+	u.rdap.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&u.conns, 1)
+		}
+	}
+	u.rdap.Start()
+	t.Cleanup(u.rdap.Close)
+
+	b, err := ParseBootstrap([]byte(fmt.Sprintf(`{"services": [[["test"], [%q]]]}`, u.rdap.URL+"/")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.bootstrap = b
+	return u
+}
+
+// options returns the options pointing a Checker at the stand-ins.
+func (u *localUpstreams) options() []Option {
+	return []Option{
+		WithDNSServer(u.dns),
+		WithDNSClient(&DNSClient{Servers: map[string][]string{"test": {u.dns}}}),
+		WithBootstrap(u.bootstrap),
+		WithHTTPClient(u.rdap.Client()),
+		WithCache(nil),
+	}
+}
+
+func TestCheckerLocalUpstreams(t *testing.T) {
+	var userAgent atomic.Value
+	u := startLocalUpstreams(t, func(w http.ResponseWriter, r *http.Request) {
+		userAgent.Store(r.UserAgent())
+		w.WriteHeader(http.StatusNotFound)
+	})
+	c := New(append(u.options(), WithUserAgent("checker-test/1.0"))...)
+
+	tests := []struct {
+		name          string
+		wantAvailable bool
+		wantSource    string
+	}{
+		{"taken.test", false, "dns"},
+		{"free.test", true, "rdap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := domain.Domain{Full: tt.name, Name: "x", TLD: "test"}
+			result, err := c.Check(context.Background(), d)
+			if err != nil {
+				t.Fatalf("Check() error = %v (attempts %+v)", err, result.Attempts)
+			}
+			if result.Available != tt.wantAvailable || result.Source != tt.wantSource {
+				t.Errorf("Check() = available %v from %q, want %v from %q",
+					result.Available, result.Source, tt.wantAvailable, tt.wantSource)
+			}
+		})
+	}
+
+	if got := userAgent.Load(); got != "checker-test/1.0" {
+		t.Errorf("User-Agent = %v, want checker-test/1.0", got)
+	}
+}
+
+// TestCheckerReusesRDAPConnections checks that RDAP queries share keep-alive connections
+func TestCheckerReusesRDAPConnections(t *testing.T) {
+	u := startLocalUpstreams(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	c := New(u.options()...)
+
+	for i := 0; i < 5; i++ {
+		d := domain.Domain{Full: fmt.Sprintf("free%d.test", i), Name: "x", TLD: "test"}
+		if _, err := c.Check(context.Background(), d); err != nil {
+			t.Fatalf("Check(%s) error = %v", d.Full, err)
+		}
+	}
+	if conns := atomic.LoadInt32(&u.conns); conns != 1 {
+		t.Errorf("RDAP server accepted %d connections, want 1", conns)
+	}
+}
+
+func TestCheckerTimeouts(t *testing.T) {
+	u := startLocalUpstreams(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	whois := startWHOISServer(t, func(q string) string {
+		return "No match for \"" + q + "\".\r\n"
+	})
+	c := New(append(u.options(),
+		WithRDAPTimeout(50*time.Millisecond),
+		WithWHOISServers(map[string]string{"test": whois}),
+	)...)
+
+	d := domain.Domain{Full: "slow.test", Name: "slow", TLD: "test"}
+	result, err := c.Check(context.Background(), d)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if result.Source != "whois" || !result.Available {
+		t.Fatalf("Check() = available %v from %q, want available from whois", result.Available, result.Source)
+	}
+
+	var rdap *domain.Attempt
+	for i := range result.Attempts {
+		if result.Attempts[i].Source == "rdap" {
+			rdap = &result.Attempts[i]
+		}
+	}
+	if rdap == nil || rdap.Outcome != domain.StatusError {
+		t.Fatalf("rdap attempt = %+v, want a timeout error", rdap)
+	}
+	if rdap.Duration > 500*time.Millisecond {
+		t.Errorf("rdap attempt took %v, want about 50ms", rdap.Duration)
+	}
+}
+
+func TestCheckerCache(t *testing.T) {
+	var calls int32
+	u := startLocalUpstreams(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	d := domain.Domain{Full: "free.test", Name: "free", TLD: "test"}
+
+	tests := []struct {
+		name      string
+		cache     *Cache
+		wantCalls int32
+	}{
+		{"no cache", nil, 2},
+		{"own cache", NewCache(CacheConfig{}), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			c := New(append(u.options(), WithCache(tt.cache))...)
+			for i := 0; i < 2; i++ {
+				if _, err := c.Check(context.Background(), d); err != nil {
+					t.Fatalf("Check() error = %v", err)
+				}
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("RDAP queries = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCheckerWithPipeline(t *testing.T) {
+	p := NewPipeline(Stage{Source: fakeSource{name: "fake", verdict: Verdict{Status: domain.StatusTaken}}, Stop: StopOnAnswer})
+	c := New(WithPipeline(p), WithCache(nil))
+
+	if c.Pipeline() != p {
+		t.Fatal("Pipeline() does not return the pipeline given with WithPipeline")
+	}
+	result, err := c.Check(context.Background(), domain.Domain{Full: "x.test", Name: "x", TLD: "test"})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if result.Source != "fake" || result.Status != domain.StatusTaken {
+		t.Errorf("Check() = %s from %q, want taken from fake", result.Status, result.Source)
+	}
+}
@@ -0,0 +1,244 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+// StopRule controls whether a Pipeline stops after a stage returned a verdict.
+type StopRule int
+
+const (
+	// StopOnAnswer stops as soon as the stage reports available or taken
+	StopOnAnswer StopRule = iota
+
+	// StopOnTaken stops only when the stage reports a registered name
+	// (taken, pending delete or redemption period).
+	// An available verdict is kept as a fallback answer and the pipeline continues.
+	StopOnTaken
+
+	// StopOnAvailable stops only when the stage reports a registrable name
+	// (available or premium).
+	// A taken verdict is kept as a fallback answer and the pipeline continues.
+	StopOnAvailable
+
+	// Continue never stops. The verdict is kept as a fallback answer and
+	// later stages may override it.
+	Continue
+)
+
+// stops reports whether the rule ends the pipeline for the given verdict.
+func (r StopRule) stops(v Verdict) bool {
+	switch r {
+	case StopOnAnswer:
+		return v.Decided()
+	case StopOnTaken:
+		return v.Status.Registered()
+	case StopOnAvailable:
+		return v.Status.Registrable()
+	default:
+		return false
+	}
+}
+
+// Stage is a Source together with the rule deciding when its answer is final.
+type Stage struct {
+	Source Source
+	Stop   StopRule
+}
+
+// Pipeline runs a sequence of Sources against a domain until one of them
+// produces a final answer.
+//
+// For each stage the pipeline:
+//   - Skips the source when it does not support the domain's TLD
+//   - Calls Source.Check and records any error (errors never stop the pipeline)
+//   - Stops when the stage's StopRule accepts the verdict
+//
+// When no stage stops the pipeline, the most recent decided verdict is used.
+// If no source decided at all, the result is StatusError carrying the last error.
+//
+// Every source called is recorded in Result.Attempts, and Result.Confidence is
+// derived from the deciding verdict (see confidenceFor).
+//
+// A Pipeline is immutable and safe for concurrent use.
+type Pipeline struct {
+	stages []Stage
+}
+
+// NewPipeline creates a pipeline that runs the given stages in order.
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: append([]Stage(nil), stages...)}
+}
+
+// DefaultPipeline returns the standard DNS → authoritative DNS → RDAP → WHOIS
+// pipeline used by Check.
+//
+//   - dns:      stops when records exist (taken), otherwise continues
+//   - dns-auth: stops when the TLD's nameservers delegate the name, otherwise continues
+//   - rdap:     stops on any answer
+//   - whois:    stops on any answer (last resort)
+func DefaultPipeline() *Pipeline {
+	return NewPipeline(
+		Stage{Source: NewDNSSource(), Stop: StopOnTaken},
+		Stage{Source: NewAuthDNSSource(), Stop: StopOnTaken},
+		Stage{Source: NewRDAPSource(), Stop: StopOnAnswer},
+		Stage{Source: NewWHOISSource(), Stop: StopOnAnswer},
+	)
+}
+
+// Stages returns a copy of the pipeline's stages.
+func (p *Pipeline) Stages() []Stage {
+	return append([]Stage(nil), p.stages...)
+}
+
+// InsertBefore returns a new pipeline with stage inserted before the first
+// stage whose source is named name. If no such stage exists, stage is appended.
+func (p *Pipeline) InsertBefore(name string, stage Stage) *Pipeline {
+	stages := make([]Stage, 0, len(p.stages)+1)
+	inserted := false
+	for _, s := range p.stages {
+		if !inserted && s.Source.Name() == name {
+			stages = append(stages, stage)
+			inserted = true
+		}
+		stages = append(stages, s)
+	}
+	if !inserted {
+		stages = append(stages, stage)
+	}
+	return &Pipeline{stages: stages}
+}
+
+// Without returns a new pipeline with every stage whose source is named name removed.
+func (p *Pipeline) Without(name string) *Pipeline {
+	stages := make([]Stage, 0, len(p.stages))
+	for _, s := range p.stages {
+		if s.Source.Name() != name {
+			stages = append(stages, s)
+		}
+	}
+	return &Pipeline{stages: stages}
+}
+
+// Check runs the pipeline for a domain. See Check for the result contract.
+func (p *Pipeline) Check(ctx context.Context, d domain.Domain) (domain.Result, error) {
+	start := time.Now()
+
+	result := domain.Result{
+		Domain:    d,
+		CheckedAt: start,
+	}
+
+	var (
+		lastErr      error
+		lastSource   string
+		fallback     Verdict
+		fallbackFrom string
+	)
+
+	for _, stage := range p.stages {
+		if !stage.Source.Supports(d.Zone()) {
+			continue
+		}
+
+		// Don't start another upstream query once the caller has given up
+		if err := ctx.Err(); err != nil {
+			lastErr = err
+			break
+		}
+
+		lastSource = stage.Source.Name()
+		started := time.Now()
+		verdict, err := stage.Source.Check(ctx, d)
+		result.Attempts = append(result.Attempts, newAttempt(lastSource, verdict, err, time.Since(started)))
+		if err != nil {
+			// Source failed - remember why and try the next one
+			lastErr = err
+			continue
+		}
+
+		if stage.Stop.stops(verdict) {
+			return finishResult(result, verdict, lastSource, start), nil
+		}
+
+		if verdict.Decided() {
+			fallback = verdict
+			fallbackFrom = lastSource
+		}
+	}
+
+	if fallback.Decided() {
+		return finishResult(result, fallback, fallbackFrom, start), nil
+	}
+
+	return failResult(result, lastErr, lastSource, start)
+}
+
+// failResult turns a result into an error result for lastErr. A nil lastErr
+// means no source could decide: none supported the domain's TLD (no attempts),
+// or every source that ran was inconclusive.
+//
+// The returned error wraps lastErr classified with the sentinel errors (see
+// ErrorCode), and Result.ErrorCode carries the matching code.
+func failResult(result domain.Result, lastErr error, source string, start time.Time) (domain.Result, error) {
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no source could determine availability for TLD: %s", result.Domain.Zone())
+		if len(result.Attempts) == 0 {
+			lastErr = withCode(ErrUnsupportedTLD, lastErr)
+		}
+	}
+	lastErr = classify(lastErr)
+
+	result.Status = domain.StatusError
+	result.Available = false
+	result.Error = fmt.Sprintf("all checks failed, last error: %v", lastErr)
+	result.ErrorCode = ErrorCode(lastErr)
+	result.Source = source
+	result.Confidence = domain.ConfidenceNone
+	result.Duration = time.Since(start)
+	return result, fmt.Errorf("domain check failed: %w", lastErr)
+}
+
+// finishResult fills in the verdict-derived fields of a result.
+func finishResult(result domain.Result, v Verdict, source string, start time.Time) domain.Result {
+	result.Status = v.Status
+	result.Available = v.Status.Registrable()
+	result.Source = source
+	result.Registration = v.Registration
+	result.Confidence = confidenceFor(v, result.Attempts)
+	result.Duration = time.Since(start)
+	return result
+}
+
+// newAttempt builds the evidence record for one source call.
+func newAttempt(source string, v Verdict, err error, took time.Duration) domain.Attempt {
+	attempt := domain.Attempt{
+		Source:   source,
+		Outcome:  v.Status,
+		Duration: took,
+		Signal:   v.Signal,
+	}
+	if err != nil {
+		attempt.Outcome = domain.StatusError
+		attempt.Error = err.Error()
+	}
+	return attempt
+}
+
+// confidenceFor derives the result confidence from the deciding verdict.
+//
+// The verdict's own confidence is used (medium when the source didn't set one),
+// lowered to low when another source disagreed on whether the name is
+// registrable at all (e.g. DNS records exist but WHOIS says available).
+func confidenceFor(v Verdict, attempts []domain.Attempt) domain.Confidence {
+	for _, a := range attempts {
+		if (a.Outcome.Registrable() && v.Status.Registered()) || (a.Outcome.Registered() && v.Status.Registrable()) {
+			return domain.ConfidenceLow
+		}
+	}
+	return effectiveConfidence(v)
+}
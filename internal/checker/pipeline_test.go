@@ -0,0 +1,331 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"domaincheck/internal/domain"
+)
+
+// fakeSource is a scripted Source for pipeline tests.
+type fakeSource struct {
+	name    string
+	tlds    []string // supported TLDs; nil means all
+	verdict Verdict
+	err     error
+	calls   *int
+}
+
+func (f fakeSource) Name() string { return f.name }
+
+func (f fakeSource) Supports(tld string) bool {
+	if f.tlds == nil {
+		return true
+	}
+	for _, t := range f.tlds {
+		if t == tld {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fakeSource) Check(ctx context.Context, d domain.Domain) (Verdict, error) {
+	if f.calls != nil {
+		*f.calls++
+	}
+	return f.verdict, f.err
+}
+
+var (
+	verdictAvailable = Verdict{Status: domain.StatusAvailable}
+	verdictTaken     = Verdict{Status: domain.StatusTaken}
+	verdictUnknown   = Verdict{Status: domain.StatusUnknown}
+)
+
+func TestPipelineCheck(t *testing.T) {
+	d := domain.Domain{Full: "example.com", Name: "example", TLD: "com"}
+
+	tests := []struct {
+		name       string
+		stages     []Stage
+		wantStatus domain.Status
+		wantSource string
+		wantErr    bool
+	}{
+		{
+			name: "first answer wins",
+			stages: []Stage{
+				{Source: fakeSource{name: "a", verdict: verdictTaken}},
+				{Source: fakeSource{name: "b", verdict: verdictAvailable}},
+			},
+			wantStatus: domain.StatusTaken,
+			wantSource: "a",
+		},
+		{
+			name: "undecided stage continues",
+			stages: []Stage{
+				{Source: fakeSource{name: "a", verdict: verdictUnknown}, Stop: StopOnTaken},
+				{Source: fakeSource{name: "b", verdict: verdictAvailable}},
+			},
+			wantStatus: domain.StatusAvailable,
+			wantSource: "b",
+		},
+		{
+			name: "error falls through to next source",
+			stages: []Stage{
+				{Source: fakeSource{name: "a", err: errors.New("boom")}},
+				{Source: fakeSource{name: "b", verdict: verdictTaken}},
+			},
+			wantStatus: domain.StatusTaken,
+			wantSource: "b",
+		},
+		{
+			name: "unsupported TLD is skipped",
+			stages: []Stage{
+				{Source: fakeSource{name: "a", tlds: []string{"org"}, verdict: verdictAvailable}},
+				{Source: fakeSource{name: "b", verdict: verdictTaken}},
+			},
+			wantStatus: domain.StatusTaken,
+			wantSource: "b",
+		},
+		{
+			name: "StopOnTaken keeps available as fallback",
+			stages: []Stage{
+				{Source: fakeSource{name: "a", verdict: verdictAvailable}, Stop: StopOnTaken},
+				{Source: fakeSource{name: "b", err: errors.New("boom")}},
+			},
+			wantStatus: domain.StatusAvailable,
+			wantSource: "a",
+		},
+		{
+			name: "Continue lets later stage override",
+			stages: []Stage{
+				{Source: fakeSource{name: "a", verdict: verdictAvailable}, Stop: Continue},
+				{Source: fakeSource{name: "b", verdict: verdictTaken}, Stop: Continue},
+			},
+			wantStatus: domain.StatusTaken,
+			wantSource: "b",
+		},
+		{
+			name: "all sources fail",
+			stages: []Stage{
+				{Source: fakeSource{name: "a", err: errors.New("first")}},
+				{Source: fakeSource{name: "b", err: errors.New("second")}},
+			},
+			wantStatus: domain.StatusError,
+			wantSource: "b",
+			wantErr:    true,
+		},
+		{
+			name:       "empty pipeline",
+			stages:     nil,
+			wantStatus: domain.StatusError,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewPipeline(tt.stages...).Check(context.Background(), d)
+
+			if tt.wantErr && err == nil {
+				t.Errorf("Check() expected error but got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Check() unexpected error: %v", err)
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("Check() Status = %v, want %v", result.Status, tt.wantStatus)
+			}
+			if result.Available != (tt.wantStatus == domain.StatusAvailable) {
+				t.Errorf("Check() Available = %v inconsistent with Status %v", result.Available, result.Status)
+			}
+			if result.Source != tt.wantSource {
+				t.Errorf("Check() Source = %q, want %q", result.Source, tt.wantSource)
+			}
+			if result.Domain != d {
+				t.Errorf("Check() Domain = %+v, want %+v", result.Domain, d)
+			}
+		})
+	}
+}
+
+// TestPipelineCheckErrorMessage verifies the error text matches the legacy format
+func TestPipelineCheckErrorMessage(t *testing.T) {
+	p := NewPipeline(Stage{Source: fakeSource{name: "a", err: errors.New("server down")}})
+
+	result, err := p.Check(context.Background(), domain.Domain{Full: "example.com", TLD: "com"})
+	if err == nil {
+		t.Fatal("Check() expected error but got nil")
+	}
+	if result.Error != "all checks failed, last error: server down" {
+		t.Errorf("Check() Error = %q", result.Error)
+	}
+	if !strings.Contains(err.Error(), "server down") {
+		t.Errorf("Check() err = %v, want wrapped source error", err)
+	}
+}
+
+func TestPipelineCheckErrorCode(t *testing.T) {
+	limited := fmt.Errorf("%w: RDAP server returned 429", ErrRateLimited)
+	p := NewPipeline(Stage{Source: fakeSource{name: "a", err: limited}})
+
+	result, err := p.Check(context.Background(), domain.Domain{Full: "example.com", TLD: "com"})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Check() err = %v, want ErrRateLimited", err)
+	}
+	if result.ErrorCode != "rate_limited" {
+		t.Errorf("Check() ErrorCode = %q, want rate_limited", result.ErrorCode)
+	}
+}
+
+// TestPipelineCheckRegistration verifies registration details reach the Result
+func TestPipelineCheckRegistration(t *testing.T) {
+	reg := &domain.Registration{Registrar: "Example Registrar"}
+	p := NewPipeline(Stage{Source: fakeSource{name: "rdap", verdict: Verdict{Status: domain.StatusTaken, Registration: reg}}})
+
+	result, err := p.Check(context.Background(), domain.Domain{Full: "example.com", TLD: "com"})
+	if err != nil {
+		t.Fatalf("Check() unexpected error: %v", err)
+	}
+	if result.Registration != reg {
+		t.Errorf("Check() Registration = %+v, want %+v", result.Registration, reg)
+	}
+}
+
+// TestPipelineCheckCanceledContext verifies no source is called after cancellation
+func TestPipelineCheckCanceledContext(t *testing.T) {
+	calls := 0
+	p := NewPipeline(Stage{Source: fakeSource{name: "a", verdict: verdictTaken, calls: &calls}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := p.Check(ctx, domain.Domain{Full: "example.com", TLD: "com"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Check() err = %v, want context.Canceled", err)
+	}
+	if calls != 0 {
+		t.Errorf("source called %d times after cancellation, want 0", calls)
+	}
+	if result.CheckedAt.IsZero() {
+		t.Error("Check() CheckedAt should be set even on cancellation")
+	}
+}
+
+func TestPipelineInsertBeforeAndWithout(t *testing.T) {
+	p := NewPipeline(
+		Stage{Source: fakeSource{name: "dns"}},
+		Stage{Source: fakeSource{name: "rdap"}},
+	)
+
+	names := func(p *Pipeline) string {
+		var n []string
+		for _, s := range p.Stages() {
+			n = append(n, s.Source.Name())
+		}
+		return strings.Join(n, ",")
+	}
+
+	if got := names(p.InsertBefore("rdap", Stage{Source: fakeSource{name: "zone"}})); got != "dns,zone,rdap" {
+		t.Errorf("InsertBefore(rdap) = %s, want dns,zone,rdap", got)
+	}
+	if got := names(p.InsertBefore("missing", Stage{Source: fakeSource{name: "zone"}})); got != "dns,rdap,zone" {
+		t.Errorf("InsertBefore(missing) = %s, want dns,rdap,zone", got)
+	}
+	if got := names(p.Without("dns")); got != "rdap" {
+		t.Errorf("Without(dns) = %s, want rdap", got)
+	}
+	if got := names(p); got != "dns,rdap" {
+		t.Errorf("original pipeline modified: %s", got)
+	}
+}
+
+func TestDefaultPipeline(t *testing.T) {
+	want := []string{"dns", "dns-auth", "rdap", "whois"}
+	stages := DefaultPipeline().Stages()
+	if len(stages) != len(want) {
+		t.Fatalf("DefaultPipeline() has %d stages, want %d", len(stages), len(want))
+	}
+	for i, s := range stages {
+		if s.Source.Name() != want[i] {
+			t.Errorf("stage %d = %s, want %s", i, s.Source.Name(), want[i])
+		}
+	}
+}
+
+// TestPipelineCheckAttempts verifies every called source is recorded in order
+func TestPipelineCheckAttempts(t *testing.T) {
+	p := NewPipeline(
+		Stage{Source: fakeSource{name: "dns", err: errors.New("timeout")}, Stop: StopOnTaken},
+		Stage{Source: fakeSource{name: "skipped", tlds: []string{"org"}, verdict: verdictTaken}},
+		Stage{Source: fakeSource{name: "rdap", verdict: Verdict{Status: domain.StatusAvailable, Signal: "HTTP 404"}}},
+		Stage{Source: fakeSource{name: "whois", verdict: verdictTaken}},
+	)
+
+	result, err := p.Check(context.Background(), domain.Domain{Full: "example.com", TLD: "com"})
+	if err != nil {
+		t.Fatalf("Check() unexpected error: %v", err)
+	}
+
+	want := []domain.Attempt{
+		{Source: "dns", Outcome: domain.StatusError, Error: "timeout"},
+		{Source: "rdap", Outcome: domain.StatusAvailable, Signal: "HTTP 404"},
+	}
+	if len(result.Attempts) != len(want) {
+		t.Fatalf("Check() Attempts = %+v, want %d entries", result.Attempts, len(want))
+	}
+	for i, a := range result.Attempts {
+		a.Duration = 0
+		if a != want[i] {
+			t.Errorf("Attempts[%d] = %+v, want %+v", i, a, want[i])
+		}
+	}
+}
+
+func TestPipelineCheckConfidence(t *testing.T) {
+	d := domain.Domain{Full: "example.com", TLD: "com"}
+
+	tests := []struct {
+		name   string
+		stages []Stage
+		want   domain.Confidence
+	}{
+		{
+			name:   "source confidence is used",
+			stages: []Stage{{Source: fakeSource{name: "rdap", verdict: Verdict{Status: domain.StatusAvailable, Confidence: domain.ConfidenceHigh}}}},
+			want:   domain.ConfidenceHigh,
+		},
+		{
+			name:   "unset confidence defaults to medium",
+			stages: []Stage{{Source: fakeSource{name: "custom", verdict: verdictTaken}}},
+			want:   domain.ConfidenceMedium,
+		},
+		{
+			name: "disagreement lowers confidence",
+			stages: []Stage{
+				{Source: fakeSource{name: "a", verdict: verdictAvailable}, Stop: Continue},
+				{Source: fakeSource{name: "b", verdict: Verdict{Status: domain.StatusTaken, Confidence: domain.ConfidenceHigh}}},
+			},
+			want: domain.ConfidenceLow,
+		},
+		{
+			name:   "errors have no confidence",
+			stages: []Stage{{Source: fakeSource{name: "a", err: errors.New("boom")}}},
+			want:   domain.ConfidenceNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _ := NewPipeline(tt.stages...).Check(context.Background(), d)
+			if result.Confidence != tt.want {
+				t.Errorf("Check() Confidence = %v, want %v", result.Confidence, tt.want)
+			}
+		})
+	}
+}
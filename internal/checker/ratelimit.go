@@ -0,0 +1,276 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Default rate limits per upstream host, used when a RateLimitConfig field is zero.
+var (
+	defaultRDAPRateLimit  = RateLimit{Rate: 5, Burst: 10}
+	defaultWHOISRateLimit = RateLimit{Rate: 1, Burst: 3}
+	defaultDNSRateLimit   = RateLimit{Rate: 50, Burst: 50}
+)
+
+const (
+	// defaultRateLimitMaxWait caps how long a query may queue when the caller
+	// has no deadline
+	defaultRateLimitMaxWait = 30 * time.Second
+
+	// defaultRetryAfter is the pause applied after an HTTP 429 without Retry-After
+	defaultRetryAfter = time.Second
+)
+
+// RateLimit is a token bucket: Rate queries per second on average, with
+// bursts of up to Burst queries.
+//
+// A zero Rate selects the protocol default; a negative Rate disables limiting.
+// A zero Burst defaults to the rate rounded up (at least 1).
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitConfig configures a RateLimiter.
+//
+// Limits apply per upstream host (RDAP server, WHOIS server, DNS server), so
+// a slow registry does not hold back queries to the others.
+type RateLimitConfig struct {
+	// RDAP is the limit for each RDAP server (default 5/s, burst 10)
+	RDAP RateLimit
+
+	// WHOIS is the limit for each WHOIS server (default 1/s, burst 3).
+	// WHOIS servers throttle hardest and ban abusive clients.
+	WHOIS RateLimit
+
+	// DNS is the limit for each authoritative DNS server (default 50/s, burst 50)
+	DNS RateLimit
+
+	// Hosts overrides the limit for specific hosts (e.g. "whois.verisign-grs.com"),
+	// whatever the protocol
+	Hosts map[string]RateLimit
+
+	// MaxWait caps how long a query may queue when the caller's context has
+	// no deadline (default 30s)
+	MaxWait time.Duration
+}
+
+// RateLimiter queues queries per upstream host so we stay under each
+// upstream's rate limit instead of getting throttled or banned.
+//
+// Queries wait their turn rather than failing; a query fails with
+// ErrRateLimited only when its turn would come after the caller's deadline.
+// Pauses requested by upstreams (HTTP 429/503 with Retry-After) are applied
+// to every query for that host.
+//
+// A RateLimiter is safe for concurrent use.
+type RateLimiter struct {
+	cfg RateLimitConfig
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket is the limiter state of one upstream host.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64 // negative when queries are queued
+	last   time.Time
+
+	// pausedUntil is set from Retry-After; no query is sent before it
+	pausedUntil time.Time
+}
+
+// NewRateLimiter creates a limiter. See RateLimitConfig for defaults.
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	cfg.RDAP = rateLimitOr(cfg.RDAP, defaultRDAPRateLimit)
+	cfg.WHOIS = rateLimitOr(cfg.WHOIS, defaultWHOISRateLimit)
+	cfg.DNS = rateLimitOr(cfg.DNS, defaultDNSRateLimit)
+	cfg.MaxWait = durationOr(cfg.MaxWait, defaultRateLimitMaxWait)
+	return &RateLimiter{
+		cfg:     cfg,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// rateLimitOr applies the default to a zero limit and derives a missing burst.
+func rateLimitOr(l, def RateLimit) RateLimit {
+	if l.Rate == 0 {
+		l.Rate = def.Rate
+		if l.Burst == 0 {
+			l.Burst = def.Burst
+		}
+	}
+	if l.Burst <= 0 {
+		l.Burst = int(math.Max(1, math.Ceil(l.Rate)))
+	}
+	return l
+}
+
+// limitFor returns the limit applying to host for the given protocol.
+func (l *RateLimiter) limitFor(protocol, host string) RateLimit {
+	var limit RateLimit
+	switch protocol {
+	case "rdap":
+		limit = l.cfg.RDAP
+	case "whois":
+		limit = l.cfg.WHOIS
+	default:
+		limit = l.cfg.DNS
+	}
+	if override, ok := l.cfg.Hosts[host]; ok {
+		return rateLimitOr(override, limit)
+	}
+	return limit
+}
+
+// bucket returns the bucket for protocol+host, creating it full. The caller must hold l.mu.
+func (l *RateLimiter) bucket(protocol, host string, now time.Time) *tokenBucket {
+	key := protocol + "/" + host
+	b, ok := l.buckets[key]
+	if !ok {
+		limit := l.limitFor(protocol, host)
+		b = &tokenBucket{rate: limit.Rate, burst: float64(limit.Burst), tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// Wait blocks until a query to host may be sent.
+//
+// Parameters:
+//   - protocol: "rdap", "whois" or "dns" (selects the default limit)
+//   - host: the upstream host name or address, without port
+//
+// Returns:
+//   - nil when the query may proceed
+//   - an error wrapping ErrRateLimited when the wait would outlast ctx's
+//     deadline (or MaxWait); nothing is consumed in that case
+//   - ctx.Err() when ctx ends while waiting; the token is returned, so
+//     abandoned queries don't use up the budget of later ones
+func (l *RateLimiter) Wait(ctx context.Context, protocol, host string) error {
+	if l == nil {
+		return nil
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	b := l.bucket(protocol, host, now)
+
+	var wait time.Duration
+	if b.rate > 0 {
+		// Refill, then take a token; a negative balance is our place in the queue
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens < 1 {
+			wait = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		}
+	}
+	if pause := b.pausedUntil.Sub(now); pause > wait {
+		wait = pause
+	}
+
+	limit := l.cfg.MaxWait
+	if deadline, ok := ctx.Deadline(); ok {
+		limit = deadline.Sub(now)
+	}
+	if wait > limit {
+		l.mu.Unlock()
+		return fmt.Errorf("%w: %s %s busy for another %v", ErrRateLimited, protocol, host, wait.Round(time.Millisecond))
+	}
+	if b.rate > 0 {
+		b.tokens--
+	}
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		if b.rate > 0 {
+			l.mu.Lock()
+			b.tokens = math.Min(b.burst, b.tokens+1)
+			l.mu.Unlock()
+		}
+		return ctx.Err()
+	}
+}
+
+// Pause stops queries to host until d has elapsed, as asked by the upstream
+// (e.g. HTTP Retry-After). A shorter pause never cuts an existing one short.
+func (l *RateLimiter) Pause(protocol, host string, d time.Duration) {
+	if l == nil || d <= 0 {
+		return
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(protocol, host, now)
+	if until := now.Add(d); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
+
+// parseRetryAfter reads an HTTP Retry-After header (delay in seconds or an HTTP date).
+// It returns 0 when the header is missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// hostOf strips the port from a "host:port" address.
+func hostOf(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// defaultRateLimiter is the limiter used by the RDAP, WHOIS and DNS clients.
+var defaultRateLimiter = struct {
+	sync.RWMutex
+	limiter *RateLimiter
+}{
+	limiter: NewRateLimiter(RateLimitConfig{}),
+}
+
+// SetRateLimiter replaces the limiter used for all upstream queries.
+// A nil limiter disables rate limiting.
+// This should be called at startup to tune rates.
+func SetRateLimiter(l *RateLimiter) {
+	defaultRateLimiter.Lock()
+	defaultRateLimiter.limiter = l
+	defaultRateLimiter.Unlock()
+}
+
+// currentRateLimiter returns the limiter for upstream queries, or nil when disabled.
+func currentRateLimiter() *RateLimiter {
+	defaultRateLimiter.RLock()
+	defer defaultRateLimiter.RUnlock()
+	return defaultRateLimiter.limiter
+}
@@ -0,0 +1,180 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+// isolateUpstreams gives the test its own upstream guards: no rate limiting,
+// fresh circuit breakers and fast retries. Local stand-in servers all live on
+// 127.0.0.1, so otherwise tests would queue behind and trip each other.
+func isolateUpstreams(t *testing.T) {
+	t.Helper()
+	prevLimiter, prevBreakers, prevRetry := currentRateLimiter(), currentBreakers(), currentRetryConfig()
+	SetRateLimiter(nil)
+	SetBreakers(NewBreakers(BreakerConfig{}))
+	SetRetryConfig(RetryConfig{BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})
+	t.Cleanup(func() {
+		SetRateLimiter(prevLimiter)
+		SetBreakers(prevBreakers)
+		SetRetryConfig(prevRetry)
+	})
+}
+
+// useRateLimiter installs a limiter for the test.
+func useRateLimiter(t *testing.T, l *RateLimiter) {
+	t.Helper()
+	prev := currentRateLimiter()
+	SetRateLimiter(l)
+	t.Cleanup(func() { SetRateLimiter(prev) })
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{RDAP: RateLimit{Rate: 20, Burst: 2}})
+	ctx := context.Background()
+
+	// The burst goes through immediately, the next query queues for 1/rate
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(ctx, "rdap", "rdap.example"); err != nil {
+			t.Fatalf("Wait() #%d error = %v", i, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("3 queries at 20/s burst 2 took %v, want >= 50ms", elapsed)
+	}
+
+	// Buckets are per host
+	start = time.Now()
+	if err := l.Wait(ctx, "rdap", "other.example"); err != nil || time.Since(start) > 20*time.Millisecond {
+		t.Errorf("Wait() on another host = %v after %v, want immediate", err, time.Since(start))
+	}
+}
+
+func TestRateLimiterDeadline(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{WHOIS: RateLimit{Rate: 1, Burst: 1}})
+
+	if err := l.Wait(context.Background(), "whois", "whois.example"); err != nil {
+		t.Fatalf("first Wait() error = %v", err)
+	}
+
+	// The next token is ~1s away, beyond this deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := l.Wait(ctx, "whois", "whois.example")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Wait() error = %v, want ErrRateLimited", err)
+	}
+	if time.Since(start) > 50*time.Millisecond {
+		t.Error("Wait() blocked although the deadline could not be met")
+	}
+}
+
+// TestRateLimiterCancelRefunds verifies callers that give up while queued
+// return their token instead of delaying everyone after them
+func TestRateLimiterCancelRefunds(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{WHOIS: RateLimit{Rate: 2, Burst: 1}})
+	if err := l.Wait(context.Background(), "whois", "whois.example"); err != nil {
+		t.Fatalf("first Wait() error = %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		if err := l.Wait(ctx, "whois", "whois.example"); !errors.Is(err, context.Canceled) {
+			t.Fatalf("cancelled Wait() #%d error = %v, want context.Canceled", i, err)
+		}
+	}
+
+	// Only the first query's token is missing: the next one is ~0.5s away, not 2.5s
+	start := time.Now()
+	if err := l.Wait(context.Background(), "whois", "whois.example"); err != nil {
+		t.Fatalf("Wait() after cancellations error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("Wait() after 4 cancelled waits took %v, want under 900ms", elapsed)
+	}
+}
+
+func TestRateLimiterPause(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{DNS: RateLimit{Rate: -1}, MaxWait: 50 * time.Millisecond})
+
+	l.Pause("dns", "192.0.2.1", time.Hour)
+	l.Pause("dns", "192.0.2.1", time.Millisecond) // does not shorten the pause
+	if err := l.Wait(context.Background(), "dns", "192.0.2.1"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Wait() during pause = %v, want ErrRateLimited (MaxWait exceeded)", err)
+	}
+	if err := l.Wait(context.Background(), "dns", "192.0.2.2"); err != nil {
+		t.Errorf("Wait() on unpaused host = %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRDAPRetryAfter(t *testing.T) {
+	var requests int32
+	useRDAPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	useRateLimiter(t, NewRateLimiter(RateLimitConfig{}))
+
+	d := domain.Domain{Full: "example.test", Name: "example", TLD: "test"}
+	status, _, err := RDAPLookup(context.Background(), d)
+	if err != nil || status != domain.StatusAvailable {
+		t.Errorf("RDAPLookup() = %v, %v; want available after retry", status, err)
+	}
+	if requests != 2 {
+		t.Errorf("server saw %d requests, want 2", requests)
+	}
+}
+
+func TestRDAPRateLimitedDeadline(t *testing.T) {
+	var requests int32
+	useRDAPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	useRateLimiter(t, NewRateLimiter(RateLimitConfig{}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	d := domain.Domain{Full: "example.test", Name: "example", TLD: "test"}
+	_, _, err := RDAPLookup(ctx, d)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("RDAPLookup() error = %v, want ErrRateLimited", err)
+	}
+	if requests != 1 {
+		t.Errorf("server saw %d requests, want 1 (retry would exceed deadline)", requests)
+	}
+}
@@ -4,28 +4,107 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	neturl "net/url"
+	"strings"
 	"time"
 
 	"domaincheck/internal/domain"
 )
 
-// rdapServers maps TLDs to their RDAP server base URLs.
-// These are verified working RDAP servers for each TLD.
-// Note: Many TLDs use the RDAP Bootstrap service, but we hardcode known servers for performance.
-var rdapServers = map[string]string{
-	"com": "https://rdap.verisign.com/com/v1/domain/",
-	"net": "https://rdap.verisign.com/net/v1/domain/",
-	"org": "https://rdap.publicinterestregistry.org/rdap/domain/",
-	// Note: .io, .ai, .dev, .app servers may require bootstrap service
-	// Removed for now until verified - can be added when correct URLs are confirmed
+// Default RDAP client settings.
+const (
+	// defaultRDAPTimeout bounds a complete lookup, including retries
+	defaultRDAPTimeout = 10 * time.Second
+
+	// defaultUserAgent identifies us to RDAP servers (some require a User-Agent)
+	defaultUserAgent = "domaincheck/1.0"
+)
+
+// defaultRDAPHTTPClient is shared by all RDAP queries so that connections to
+// each server are kept alive and reused.
+var defaultRDAPHTTPClient = &http.Client{}
+
+// rdapClient holds the settings of RDAP queries.
+// Zero fields select the package defaults.
+type rdapClient struct {
+	http      *http.Client
+	userAgent string
+	timeout   time.Duration
+	bootstrap *Bootstrap // nil: the registry set with SetBootstrap
+}
+
+// httpClient returns the HTTP client to query with.
+func (c rdapClient) httpClient() *http.Client {
+	if c.http != nil {
+		return c.http
+	}
+	return defaultRDAPHTTPClient
+}
+
+// registry returns the bootstrap registry mapping TLDs to RDAP servers.
+func (c rdapClient) registry() *Bootstrap {
+	if c.bootstrap != nil {
+		return c.bootstrap
+	}
+	return currentBootstrap()
+}
+
+// server returns the RDAP base URL for a zone: the bootstrap registry's, else
+// the one in the TLD registry's metadata (see domain.LookupTLD).
+func (c rdapClient) server(zone string) (string, bool) {
+	if base, ok := c.registry().Lookup(zone); ok {
+		return base, true
+	}
+	info, ok := domain.LookupTLD(zone[strings.LastIndexByte(zone, '.')+1:])
+	if !ok || info.RDAP == "" {
+		return "", false
+	}
+	return info.RDAP, true
 }
 
-// rdapResponse represents the minimal RDAP JSON response we care about.
-// Full RDAP responses contain much more data, but we only need the status.
+// rdapResponse represents the parts of an RDAP domain object (RFC 9083) we use.
 type rdapResponse struct {
 	// Status contains registration status values like "active", "registered", etc.
 	Status []string `json:"status"`
+
+	// Events contains lifecycle dates ("registration", "expiration", "last changed")
+	Events []rdapEvent `json:"events"`
+
+	// Entities contains the registrar and contacts
+	Entities []rdapEntity `json:"entities"`
+
+	// Nameservers contains the delegated nameservers
+	Nameservers []rdapNameserver `json:"nameservers"`
+
+	// SecureDNS contains DNSSEC delegation information
+	SecureDNS *struct {
+		DelegationSigned bool `json:"delegationSigned"`
+	} `json:"secureDNS"`
+}
+
+// rdapEvent is a single entry of the RDAP "events" array.
+type rdapEvent struct {
+	Action string `json:"eventAction"`
+	Date   string `json:"eventDate"`
+}
+
+// rdapEntity is an RDAP entity (registrar, registrant, abuse contact, ...).
+type rdapEntity struct {
+	Handle    string        `json:"handle"`
+	Roles     []string      `json:"roles"`
+	VCard     []interface{} `json:"vcardArray"`
+	PublicIDs []struct {
+		Type       string `json:"type"`
+		Identifier string `json:"identifier"`
+	} `json:"publicIds"`
+	Entities []rdapEntity `json:"entities"`
+}
+
+// rdapNameserver is an entry of the RDAP "nameservers" array.
+type rdapNameserver struct {
+	LDHName string `json:"ldhName"`
 }
 
 // RDAPCheck queries an RDAP server to check if a domain is registered.
@@ -34,7 +113,8 @@ type rdapResponse struct {
 // It provides structured JSON responses and is the preferred method for checking domains.
 //
 // The function:
-//   - Determines the correct RDAP server based on the domain's TLD
+//   - Resolves the RDAP server for the domain's TLD from the bootstrap registry
+//     (see SetBootstrap and LoadBootstrap)
 //   - Makes an HTTPS request to the RDAP server
 //   - Parses the JSON response
 //   - Returns availability based on HTTP status code and response content
@@ -42,54 +122,69 @@ type rdapResponse struct {
 // HTTP Status Code Interpretation:
 //   - 404 Not Found → Domain is available
 //   - 200 OK → Domain exists, check status array
+//   - 429 Too Many Requests → retried after Retry-After (see rdapClient.fetch),
+//     then ErrRateLimited
 //   - Other codes → Error occurred
 //
 // Returns:
 //   - available: true if domain is available for registration
 //   - err: error if RDAP query failed or TLD is not supported
 func RDAPCheck(ctx context.Context, d domain.Domain) (available bool, err error) {
-	// Find RDAP server for this TLD
-	serverBase, ok := rdapServers[d.TLD]
-	if !ok {
-		return false, fmt.Errorf("RDAP server not configured for TLD: %s", d.TLD)
-	}
+	status, _, err := RDAPLookup(ctx, d)
+	return status.Registrable(), err
+}
 
-	// Construct full RDAP URL
-	url := serverBase + d.Full
+// RDAPLookup is RDAPCheck with full detail. It returns the lifecycle status
+// derived from the RDAP status values (see rdapLifecycleStatus) and, for names
+// known to the registry, the registration details (registrar, dates,
+// nameservers, DNSSEC, contacts). The registration is nil when the domain is available.
+func RDAPLookup(ctx context.Context, d domain.Domain) (status domain.Status, reg *domain.Registration, err error) {
+	status, reg, _, err = rdapLookup(ctx, d)
+	return status, reg, err
+}
 
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+// rdapLookup is RDAPLookup that also returns the signal behind the answer
+// (HTTP status code and RDAP status values), used for the Result evidence trail.
+// The signal is set whenever the server responded, even if err != nil.
+func rdapLookup(ctx context.Context, d domain.Domain) (domain.Status, *domain.Registration, string, error) {
+	return rdapClient{}.lookup(ctx, d)
+}
 
-	// Create request with context
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return false, fmt.Errorf("failed to create RDAP request: %w", err)
+// lookup is rdapLookup with the client's settings.
+func (c rdapClient) lookup(ctx context.Context, d domain.Domain) (domain.Status, *domain.Registration, string, error) {
+	// Find RDAP server for the public suffix (the bootstrap falls back to the TLD)
+	serverBase, ok := c.server(d.Zone())
+	if !ok {
+		return domain.StatusUnknown, nil, "", withCode(ErrUnsupportedTLD, fmt.Errorf("RDAP server not configured for TLD: %s", d.Zone()))
 	}
 
-	// Set User-Agent header (some RDAP servers require this)
-	req.Header.Set("User-Agent", "domaincheck/1.0")
-	req.Header.Set("Accept", "application/rdap+json")
+	// Construct full RDAP URL (RFC 9082: <base>domain/<name>)
+	url := serverBase + "domain/" + d.Full
+
+	// The timeout covers reading the body, so it can't be a per-request
+	// http.Client timeout
+	ctx, cancel := context.WithTimeout(ctx, durationOr(c.timeout, defaultRDAPTimeout))
+	defer cancel()
 
-	// Execute request
-	resp, err := client.Do(req)
+	resp, err := c.fetch(ctx, url)
 	if err != nil {
-		return false, fmt.Errorf("RDAP request failed: %w", err)
+		return domain.StatusUnknown, nil, "", err
 	}
 	defer resp.Body.Close()
 
+	signal := fmt.Sprintf("HTTP %d", resp.StatusCode)
+
 	// Interpret HTTP status code
 	switch resp.StatusCode {
 	case http.StatusNotFound:
 		// 404 = domain not found in registry = available
-		return true, nil
+		return domain.StatusAvailable, nil, signal, nil
 
 	case http.StatusOK:
 		// 200 = domain found, parse response to check status
 		var rdapResp rdapResponse
 		if err := json.NewDecoder(resp.Body).Decode(&rdapResp); err != nil {
-			return false, fmt.Errorf("failed to parse RDAP response: %w", err)
+			return domain.StatusUnknown, nil, signal, withCode(ErrParse, fmt.Errorf("failed to parse RDAP response: %w", err))
 		}
 
 		// Check status array for registration indicators
@@ -97,22 +192,266 @@ func RDAPCheck(ctx context.Context, d domain.Domain) (available bool, err error)
 		// If status array is empty or contains only inactive statuses, might be available
 		if len(rdapResp.Status) == 0 {
 			// No status means likely available (rare but possible)
-			return true, nil
+			return domain.StatusAvailable, nil, signal + " with no status", nil
 		}
 
-		// Check for active/registered status
-		for _, status := range rdapResp.Status {
-			// These statuses indicate the domain is registered
-			if status == "active" || status == "registered" {
-				return false, nil
-			}
+		// Any other status (including "active"/"registered") means the domain
+		// exists; when there's no clear lifecycle status, assume taken to be safe
+		signal += " status=" + strings.Join(rdapResp.Status, ",")
+		return rdapLifecycleStatus(rdapResp.Status), rdapResp.registration(), signal, nil
+
+	case http.StatusTooManyRequests:
+		// Still throttled after waiting out every Retry-After we were given
+		return domain.StatusUnknown, nil, signal, fmt.Errorf("%w: RDAP server returned %d", ErrRateLimited, resp.StatusCode)
+
+	default:
+		// Other status codes indicate errors; 5xx means the server is failing
+		err := fmt.Errorf("RDAP server returned unexpected status: %d", resp.StatusCode)
+		if resp.StatusCode >= 500 {
+			err = withCode(ErrUpstreamUnavailable, err)
+		}
+		return domain.StatusUnknown, nil, signal, err
+	}
+}
+
+// maxRDAPRateLimitRetries bounds how often a query is resent after the server
+// asked us to slow down
+const maxRDAPRateLimitRetries = 3
+
+// fetch sends an RDAP query (see fetchOnce for retries of failures).
+//
+// When the server answers 429 (or 503 with Retry-After), every query to that
+// server is paused for the announced delay and the query is queued and resent,
+// up to maxRDAPRateLimitRetries times. The last throttled response is returned
+// if the server keeps refusing. If the pause would outlast ctx's deadline, an
+// error wrapping ErrRateLimited is returned instead.
+func (c rdapClient) fetch(ctx context.Context, rawURL string) (*http.Response, error) {
+	host := rawURL
+	if u, err := neturl.Parse(rawURL); err == nil {
+		host = u.Hostname()
+	}
+	limiter := currentRateLimiter()
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.fetchOnce(ctx, host, rawURL)
+		if err != nil {
+			return nil, err
+		}
+
+		pause := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests && pause == 0:
+			pause = defaultRetryAfter
+		case resp.StatusCode == http.StatusServiceUnavailable && pause > 0:
+			// Planned maintenance or overload with a known end: wait it out
+		default:
+			return resp, nil
+		}
+		if limiter == nil || attempt == maxRDAPRateLimitRetries {
+			return resp, nil
+		}
+
+		// Drain so the connection can be reused for the retry
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		limiter.Pause("rdap", host, pause)
+	}
+}
+
+// fetchOnce sends an RDAP query through callUpstream, so network errors
+// and 5xx answers are retried with backoff and count against the server's
+// circuit breaker. It returns the last response received, if any, even when
+// every try failed.
+func (c rdapClient) fetchOnce(ctx context.Context, host, rawURL string) (*http.Response, error) {
+	client := c.httpClient()
+	if t := tapeFrom(ctx); t != nil {
+		client = t.httpClient(client)
+	}
+	userAgent := c.userAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+
+	var resp *http.Response
+	err := callUpstream(ctx, "rdap", host, func(ctx context.Context) error {
+		// Create request with context
+		req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create RDAP request: %w", err)
+		}
+
+		// Set User-Agent header (some RDAP servers require this)
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Set("Accept", "application/rdap+json")
+
+		// Execute request
+		r, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("RDAP request failed: %w", err)
+		}
+		if resp != nil {
+			resp.Body.Close()
 		}
+		resp = r
 
-		// If no clear "registered" status, assume taken to be safe
-		return false, nil
+		// A 503 with Retry-After is handled by fetch's pause instead
+		if r.StatusCode >= 500 && (r.StatusCode != http.StatusServiceUnavailable || r.Header.Get("Retry-After") == "") {
+			return transient("RDAP server returned %d", r.StatusCode)
+		}
+		return nil
+	})
+	if resp != nil {
+		// Let the caller interpret the last answer (e.g. a persistent 502)
+		return resp, nil
+	}
+	return nil, err
+}
 
+// rdapLifecycleStatus maps RDAP status values (RFC 8056 EPP mapping) of an
+// existing domain object to a lifecycle status.
+//
+// Mapping (first match wins):
+//   - "redemption period", "pending restore" → StatusRedemptionPeriod
+//   - "pending delete"                       → StatusPendingDelete
+//   - "reserved"                             → StatusReserved
+//   - "blocked"                              → StatusBlocked
+//   - anything else                          → StatusTaken
+func rdapLifecycleStatus(statuses []string) domain.Status {
+	has := func(values ...string) bool {
+		for _, s := range statuses {
+			for _, v := range values {
+				if strings.EqualFold(strings.TrimSpace(s), v) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	switch {
+	case has("redemption period", "pending restore"):
+		return domain.StatusRedemptionPeriod
+	case has("pending delete"):
+		return domain.StatusPendingDelete
+	case has("reserved"):
+		return domain.StatusReserved
+	case has("blocked"):
+		return domain.StatusBlocked
 	default:
-		// Other status codes indicate errors
-		return false, fmt.Errorf("RDAP server returned unexpected status: %d", resp.StatusCode)
+		return domain.StatusTaken
+	}
+}
+
+// registration converts the RDAP domain object into a domain.Registration.
+func (r rdapResponse) registration() *domain.Registration {
+	reg := &domain.Registration{
+		Statuses: r.Status,
+	}
+
+	for _, event := range r.Events {
+		t, err := time.Parse(time.RFC3339, event.Date)
+		if err != nil {
+			continue
+		}
+		switch strings.ToLower(event.Action) {
+		case "registration":
+			reg.Created = t
+		case "expiration":
+			reg.Expires = t
+		case "last changed":
+			reg.Updated = t
+		}
+	}
+
+	for _, ns := range r.Nameservers {
+		if ns.LDHName != "" {
+			reg.Nameservers = append(reg.Nameservers, strings.ToLower(strings.TrimSuffix(ns.LDHName, ".")))
+		}
+	}
+
+	if r.SecureDNS != nil {
+		reg.DNSSEC = r.SecureDNS.DelegationSigned
+	}
+
+	// Entities nest (registrars hold their abuse contact, contacts may hold
+	// further entities): flatten them at every depth
+	var walk func(entities []rdapEntity)
+	walk = func(entities []rdapEntity) {
+		for _, e := range entities {
+			entity := domain.Entity{
+				Handle: e.Handle,
+				Roles:  e.Roles,
+				Name:   vcardValue(e.VCard, "fn"),
+				Email:  vcardValue(e.VCard, "email"),
+			}
+			reg.Entities = append(reg.Entities, entity)
+
+			if hasRole(e.Roles, "registrar") && reg.Registrar == "" {
+				reg.Registrar = entity.Name
+				for _, id := range e.PublicIDs {
+					if id.Type == "IANA Registrar ID" {
+						reg.RegistrarIANAID = id.Identifier
+					}
+				}
+				if reg.RegistrarIANAID == "" && e.Handle != "" && isDigits(e.Handle) {
+					// Many registries use the IANA ID as the registrar handle
+					reg.RegistrarIANAID = e.Handle
+				}
+			}
+
+			walk(e.Entities)
+		}
+	}
+	walk(r.Entities)
+
+	return reg
+}
+
+// vcardValue returns the first text value of a jCard (RFC 7095) property.
+//
+// A jCard looks like: ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Example Inc."]]]
+func vcardValue(vcard []interface{}, property string) string {
+	if len(vcard) < 2 {
+		return ""
+	}
+	props, ok := vcard[1].([]interface{})
+	if !ok {
+		return ""
+	}
+	for _, p := range props {
+		fields, ok := p.([]interface{})
+		if !ok || len(fields) < 4 {
+			continue
+		}
+		if name, _ := fields[0].(string); name != property {
+			continue
+		}
+		if value, ok := fields[3].(string); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// hasRole reports whether roles contains role.
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
 	}
+	return true
 }
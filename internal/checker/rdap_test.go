@@ -2,6 +2,10 @@ package checker
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -51,11 +55,11 @@ func TestRDAPCheck(t *testing.T) {
 			wantErr:       false,
 		},
 		{
-			name: "unsupported TLD (.xyz)",
+			name: "unsupported TLD (.zz)",
 			domain: domain.Domain{
-				Full: "example.xyz",
+				Full: "example.zz",
 				Name: "example",
-				TLD:  "xyz",
+				TLD:  "zz",
 			},
 			wantAvailable: false,
 			wantErr:       true,
@@ -131,7 +135,7 @@ func TestRDAPCheckCanceledContext(t *testing.T) {
 // TestRDAPCheckUnsupportedTLDs tests various unsupported TLDs
 func TestRDAPCheckUnsupportedTLDs(t *testing.T) {
 	unsupportedTLDs := []string{
-		"xyz", "co", "uk", "de", "fr", "jp",
+		"co", "uk", "de", "jp", "zz", "notarealtld",
 	}
 
 	for _, tld := range unsupportedTLDs {
@@ -152,14 +156,14 @@ func TestRDAPCheckUnsupportedTLDs(t *testing.T) {
 	}
 }
 
-// TestRDAPCheckSupportedTLDs verifies all TLDs in our map are callable
+// TestRDAPCheckSupportedTLDs verifies well-known bootstrap TLDs are callable
 func TestRDAPCheckSupportedTLDs(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test in short mode")
 	}
 
-	// Test that each TLD in our map can be queried
-	// Only testing verified working RDAP servers: com, net, org
+	// Test that each TLD in the bootstrap registry can be queried
+	// Only testing long-standing RDAP servers: com, net, org
 	supportedTLDs := []string{"com", "net", "org"}
 
 	for _, tld := range supportedTLDs {
@@ -200,3 +204,282 @@ func TestRDAPCheckSupportedTLDs(t *testing.T) {
 		})
 	}
 }
+
+// useRDAPServer points the bootstrap registry for .test at a local handler.
+func useRDAPServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	isolateUpstreams(t)
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	b, err := ParseBootstrap([]byte(fmt.Sprintf(
+		`{"services": [[["test"], [%q]]]}`, srv.URL+"/")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := currentBootstrap()
+	SetBootstrap(b)
+	t.Cleanup(func() { SetBootstrap(prev) })
+}
+
+// TestRDAPServerFromTLDRegistry verifies TLDs missing from the bootstrap are
+// routed to the RDAP server of the TLD registry.
+func TestRDAPServerFromTLDRegistry(t *testing.T) {
+	var gotPath string
+	useRDAPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusNotFound)
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = "registry:" + r.URL.Path
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	registry, err := domain.ParseTLDRegistry([]byte(fmt.Sprintf(
+		`{"tlds": [{"tld": "test"}, {"tld": "example", "rdap": %q}]}`, srv.URL+"/")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain.SetTLDRegistry(registry)
+	defer domain.SetTLDRegistry(nil)
+
+	source := NewRDAPSource()
+	if !source.Supports("example") || source.Supports("other") {
+		t.Error("Supports() does not follow the TLD registry")
+	}
+	d := domain.Domain{Full: "name.example", Name: "name", TLD: "example"}
+	if available, err := RDAPCheck(context.Background(), d); err != nil || !available {
+		t.Fatalf("RDAPCheck() = %v, %v", available, err)
+	}
+	if gotPath != "registry:/domain/name.example" {
+		t.Errorf("request = %q, want the TLD registry's server", gotPath)
+	}
+
+	// The bootstrap wins over the registry
+	d = domain.Domain{Full: "name.test", Name: "name", TLD: "test"}
+	if _, err := RDAPCheck(context.Background(), d); err != nil || gotPath != "/domain/name.test" {
+		t.Errorf("RDAPCheck(name.test) = %v, request %q, want the bootstrap server", err, gotPath)
+	}
+}
+
+// TestRDAPCheckLocalServer exercises RDAPCheck against a local RDAP stand-in
+func TestRDAPCheckLocalServer(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		wantAvailable bool
+		wantErr       bool
+	}{
+		{"404 means available", http.StatusNotFound, "", true, false},
+		{"200 active means taken", http.StatusOK, `{"status": ["active"]}`, false, false},
+		{"200 without status means available", http.StatusOK, `{}`, true, false},
+		{"200 with invalid JSON", http.StatusOK, `{`, false, true},
+		{"500 is an error", http.StatusInternalServerError, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath string
+			useRDAPServer(t, func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			d := domain.Domain{Full: "example.test", Name: "example", TLD: "test"}
+			available, err := RDAPCheck(context.Background(), d)
+
+			if gotPath != "/domain/example.test" {
+				t.Errorf("RDAP request path = %q, want /domain/example.test", gotPath)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Error("RDAPCheck() expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RDAPCheck() unexpected error: %v", err)
+			}
+			if available != tt.wantAvailable {
+				t.Errorf("RDAPCheck() available = %v, want %v", available, tt.wantAvailable)
+			}
+		})
+	}
+}
+
+// testRDAPDomainObject is a trimmed RFC 9083 domain object as served by a thick registry
+const testRDAPDomainObject = `{
+  "objectClassName": "domain",
+  "ldhName": "EXAMPLE.TEST",
+  "status": ["client delete prohibited", "client transfer prohibited"],
+  "events": [
+    {"eventAction": "registration", "eventDate": "1997-09-15T04:00:00Z"},
+    {"eventAction": "expiration", "eventDate": "2028-09-14T04:00:00Z"},
+    {"eventAction": "last changed", "eventDate": "2019-09-09T15:39:04Z"},
+    {"eventAction": "last update of RDAP database", "eventDate": "not-a-date"}
+  ],
+  "entities": [
+    {
+      "objectClassName": "entity",
+      "handle": "292",
+      "roles": ["registrar"],
+      "publicIds": [{"type": "IANA Registrar ID", "identifier": "292"}],
+      "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Example Registrar, Inc."]]],
+      "entities": [
+        {
+          "roles": ["abuse"],
+          "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", ""], ["email", {}, "text", "abuse@registrar.test"]]]
+        }
+      ]
+    }
+  ],
+  "nameservers": [
+    {"objectClassName": "nameserver", "ldhName": "NS1.EXAMPLE.TEST"},
+    {"objectClassName": "nameserver", "ldhName": "NS2.EXAMPLE.TEST."}
+  ],
+  "secureDNS": {"delegationSigned": true}
+}`
+
+// TestRDAPLookupRegistration verifies registration details are parsed from RDAP
+func TestRDAPLookupRegistration(t *testing.T) {
+	useRDAPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rdap+json")
+		w.Write([]byte(testRDAPDomainObject))
+	})
+
+	d := domain.Domain{Full: "example.test", Name: "example", TLD: "test"}
+	status, reg, err := RDAPLookup(context.Background(), d)
+	if err != nil {
+		t.Fatalf("RDAPLookup() unexpected error: %v", err)
+	}
+	if status != domain.StatusTaken {
+		t.Fatalf("RDAPLookup() status = %v, want taken", status)
+	}
+	if reg == nil {
+		t.Fatal("RDAPLookup() registration = nil for taken domain")
+	}
+
+	if reg.Registrar != "Example Registrar, Inc." {
+		t.Errorf("Registrar = %q", reg.Registrar)
+	}
+	if reg.RegistrarIANAID != "292" {
+		t.Errorf("RegistrarIANAID = %q, want 292", reg.RegistrarIANAID)
+	}
+	if want := time.Date(1997, 9, 15, 4, 0, 0, 0, time.UTC); !reg.Created.Equal(want) {
+		t.Errorf("Created = %v, want %v", reg.Created, want)
+	}
+	if want := time.Date(2028, 9, 14, 4, 0, 0, 0, time.UTC); !reg.Expires.Equal(want) {
+		t.Errorf("Expires = %v, want %v", reg.Expires, want)
+	}
+	if reg.Updated.IsZero() {
+		t.Error("Updated not parsed from \"last changed\" event")
+	}
+	if got := strings.Join(reg.Nameservers, ","); got != "ns1.example.test,ns2.example.test" {
+		t.Errorf("Nameservers = %s", got)
+	}
+	if !reg.DNSSEC {
+		t.Error("DNSSEC = false, want true")
+	}
+	if len(reg.Statuses) != 2 {
+		t.Errorf("Statuses = %v", reg.Statuses)
+	}
+	if len(reg.Entities) != 2 || reg.Entities[1].Email != "abuse@registrar.test" {
+		t.Errorf("Entities = %+v, want registrar and nested abuse contact", reg.Entities)
+	}
+}
+
+// TestRDAPLookupAvailableHasNoRegistration verifies 404 responses carry no registration
+func TestRDAPLookupAvailableHasNoRegistration(t *testing.T) {
+	useRDAPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	status, reg, err := RDAPLookup(context.Background(), domain.Domain{Full: "free.test", TLD: "test"})
+	if err != nil || status != domain.StatusAvailable || reg != nil {
+		t.Errorf("RDAPLookup() = %v, %+v, %v; want available, nil, nil", status, reg, err)
+	}
+}
+
+func TestRDAPLifecycleStatus(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     domain.Status
+	}{
+		{[]string{"active"}, domain.StatusTaken},
+		{[]string{"client transfer prohibited", "server hold"}, domain.StatusTaken},
+		{[]string{"inactive", "pending delete"}, domain.StatusPendingDelete},
+		{[]string{"redemption period", "pending delete"}, domain.StatusRedemptionPeriod},
+		{[]string{"Pending Restore"}, domain.StatusRedemptionPeriod},
+		{[]string{"reserved"}, domain.StatusReserved},
+		{[]string{"blocked"}, domain.StatusBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.statuses, ","), func(t *testing.T) {
+			if got := rdapLifecycleStatus(tt.statuses); got != tt.want {
+				t.Errorf("rdapLifecycleStatus(%v) = %v, want %v", tt.statuses, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRDAPLookupRedemption verifies lifecycle states come through RDAPLookup and RDAPCheck
+func TestRDAPLookupRedemption(t *testing.T) {
+	useRDAPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": ["redemption period", "pending delete"]}`))
+	})
+
+	d := domain.Domain{Full: "dropping.test", TLD: "test"}
+	status, reg, err := RDAPLookup(context.Background(), d)
+	if err != nil {
+		t.Fatalf("RDAPLookup() unexpected error: %v", err)
+	}
+	if status != domain.StatusRedemptionPeriod {
+		t.Errorf("RDAPLookup() status = %v, want redemption_period", status)
+	}
+	if reg == nil {
+		t.Error("RDAPLookup() registration = nil for name in redemption")
+	}
+
+	available, err := RDAPCheck(context.Background(), d)
+	if err != nil || available {
+		t.Errorf("RDAPCheck() = %v, %v; want false, nil", available, err)
+	}
+}
+
+// TestRDAPSourceSignal verifies the RDAP source reports the HTTP evidence
+func TestRDAPSourceSignal(t *testing.T) {
+	tests := []struct {
+		status     int
+		body       string
+		wantSignal string
+		wantErr    bool
+	}{
+		{http.StatusNotFound, "", "HTTP 404", false},
+		{http.StatusOK, `{"status": ["active", "client hold"]}`, "HTTP 200 status=active,client hold", false},
+		{http.StatusServiceUnavailable, "", "HTTP 503", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantSignal, func(t *testing.T) {
+			useRDAPServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			v, err := NewRDAPSource().Check(context.Background(), domain.Domain{Full: "example.test", Name: "example", TLD: "test"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if v.Signal != tt.wantSignal {
+				t.Errorf("Check() Signal = %q, want %q", v.Signal, tt.wantSignal)
+			}
+			if !tt.wantErr && v.Confidence != domain.ConfidenceHigh {
+				t.Errorf("Check() Confidence = %v, want high", v.Confidence)
+			}
+		})
+	}
+}
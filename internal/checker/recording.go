@@ -0,0 +1,529 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+// ErrNotRecorded is returned in replay mode for an upstream query that the
+// recording has no answer for (e.g. a source added since it was made).
+var ErrNotRecorded = errors.New("no recorded answer")
+
+// maxRecordedBody caps the RDAP body bytes kept per response.
+const maxRecordedBody = 1 << 20
+
+// Recording is the upstream traffic of one check: the raw DNS answers, RDAP
+// responses and WHOIS text the sources based their verdicts on, plus the
+// outcome. It is saved as <domain>.json in the recording directory (see
+// WithRecording) and served back by WithReplay.
+//
+// Recordings are plain JSON so that they can be attached to bug reports,
+// edited by hand and checked in as regression fixtures.
+type Recording struct {
+	// Domain is the checked name
+	Domain string `json:"domain"`
+
+	// RecordedAt is when the check ran
+	RecordedAt time.Time `json:"recorded_at"`
+
+	// Status, Source and ErrorCode are the outcome of the recorded check
+	Status    string `json:"status"`
+	Source    string `json:"source,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+
+	// Lookups are the DNS pre-filter's resolver lookups, wildcard probes included
+	Lookups []RecordedLookup `json:"lookups,omitempty"`
+
+	// DNSServers are the authoritative servers used per TLD
+	DNSServers map[string][]string `json:"dns_servers,omitempty"`
+
+	// Exchanges are the authoritative DNS queries and their answers
+	Exchanges []RecordedExchange `json:"exchanges,omitempty"`
+
+	// RDAP are the RDAP responses, one per HTTP request
+	RDAP []RecordedHTTP `json:"rdap,omitempty"`
+
+	// WHOISServers are the WHOIS servers used per zone (TLD or public suffix)
+	WHOISServers map[string]string `json:"whois_servers,omitempty"`
+
+	// WHOIS are the WHOIS queries and the text received
+	WHOIS []RecordedWHOIS `json:"whois,omitempty"`
+}
+
+// RecordedLookup is one resolver lookup of the DNS pre-filter.
+type RecordedLookup struct {
+	// Type is "ip", "mx" or "ns"
+	Type string `json:"type"`
+	Name string `json:"name"`
+
+	// Values are the addresses or host names found
+	Values []string `json:"values,omitempty"`
+
+	// Error is the lookup failure; NotFound and Timeout classify it
+	Error    string `json:"error,omitempty"`
+	NotFound bool   `json:"not_found,omitempty"`
+	Timeout  bool   `json:"timeout,omitempty"`
+}
+
+// RecordedExchange is one authoritative DNS query and its answer.
+type RecordedExchange struct {
+	Server   string      `json:"server"`
+	Name     string      `json:"name"`
+	Type     uint16      `json:"type"`
+	Response *DNSMessage `json:"response,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Code     string      `json:"error_code,omitempty"`
+}
+
+// RecordedHTTP is one RDAP request and its response.
+type RecordedHTTP struct {
+	URL    string      `json:"url"`
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	Code   string      `json:"error_code,omitempty"`
+}
+
+// RecordedWHOIS is one WHOIS query and the text received.
+type RecordedWHOIS struct {
+	Server string `json:"server"`
+	Query  string `json:"query"`
+	Text   string `json:"text,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Code   string `json:"error_code,omitempty"`
+}
+
+// LoadRecording reads a recording saved by a recording Checker.
+func LoadRecording(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("invalid recording %s: %w", path, err)
+	}
+	return &rec, nil
+}
+
+// Save writes the recording to path, replacing any previous one atomically.
+func (r *Recording) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// recordingPath returns where the recording of a domain is kept in dir.
+func recordingPath(dir, name string) string {
+	return filepath.Join(dir, strings.ToLower(strings.TrimSuffix(name, "."))+".json")
+}
+
+// setResult stores the outcome of the recorded check.
+func (r *Recording) setResult(result domain.Result) {
+	r.Status = result.Status.String()
+	r.Source = result.Source
+	r.ErrorCode = result.ErrorCode
+}
+
+// tape records the upstream traffic of one check into a Recording, or
+// replays it from one. It travels in the check's context, so the clients
+// deep in the pipeline find it without extra parameters. A tape is safe for
+// concurrent use (consensus checks query sources in parallel).
+type tape struct {
+	mu     sync.Mutex
+	rec    *Recording
+	replay bool
+
+	// next is the replay cursor per query key: answers to the same query
+	// (retries, referral hops) are replayed in order, the last one repeating
+	next map[string]int
+}
+
+type tapeKey struct{}
+
+// withTape returns ctx carrying t.
+func withTape(ctx context.Context, t *tape) context.Context {
+	return context.WithValue(ctx, tapeKey{}, t)
+}
+
+// tapeFrom returns the tape of the check ctx belongs to, or nil.
+func tapeFrom(ctx context.Context) *tape {
+	t, _ := ctx.Value(tapeKey{}).(*tape)
+	return t
+}
+
+// replaying reports whether the tape serves answers instead of recording them.
+func (t *tape) replaying() bool {
+	return t != nil && t.replay
+}
+
+// pick returns the index of the next answer among n recorded for key, or -1.
+// The caller must hold t.mu.
+func (t *tape) pick(key string, n int) int {
+	if n == 0 {
+		return -1
+	}
+	if t.next == nil {
+		t.next = make(map[string]int)
+	}
+	i := t.next[key]
+	if i < n-1 {
+		t.next[key] = i + 1
+	}
+	return i
+}
+
+// notRecorded is the replay error for a query missing from the recording.
+func notRecorded(format string, args ...interface{}) error {
+	return fmt.Errorf("%w for %s", ErrNotRecorded, fmt.Sprintf(format, args...))
+}
+
+// errorFields returns the message and code recorded for err.
+func errorFields(err error) (string, string) {
+	if err == nil {
+		return "", ""
+	}
+	return err.Error(), ErrorCode(err)
+}
+
+// replayError rebuilds a recorded error, keeping its class for ErrorCode.
+func replayError(message, code string) error {
+	if message == "" {
+		return nil
+	}
+	err := errors.New(message)
+	if sentinel, ok := codeSentinels[code]; ok {
+		return withCode(sentinel, err)
+	}
+	return err
+}
+
+// tapeResolver records or replays the DNS pre-filter's lookups.
+type tapeResolver struct {
+	next dnsResolver
+	tape *tape
+}
+
+func (r tapeResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	values, err := r.lookup("ip", host, func() ([]string, error) {
+		ips, err := r.next.LookupIP(ctx, network, host)
+		values := make([]string, len(ips))
+		for i, ip := range ips {
+			values[i] = ip.String()
+		}
+		return values, err
+	})
+	ips := make([]net.IP, 0, len(values))
+	for _, v := range values {
+		if ip := net.ParseIP(v); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, err
+}
+
+func (r tapeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	values, err := r.lookup("mx", name, func() ([]string, error) {
+		records, err := r.next.LookupMX(ctx, name)
+		values := make([]string, len(records))
+		for i, mx := range records {
+			values[i] = mx.Host
+		}
+		return values, err
+	})
+	records := make([]*net.MX, len(values))
+	for i, v := range values {
+		records[i] = &net.MX{Host: v}
+	}
+	return records, err
+}
+
+func (r tapeResolver) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	values, err := r.lookup("ns", name, func() ([]string, error) {
+		records, err := r.next.LookupNS(ctx, name)
+		values := make([]string, len(records))
+		for i, ns := range records {
+			values[i] = ns.Host
+		}
+		return values, err
+	})
+	records := make([]*net.NS, len(values))
+	for i, v := range values {
+		records[i] = &net.NS{Host: v}
+	}
+	return records, err
+}
+
+// lookup records the result of query, or replays the recorded one.
+//
+// Wildcard probes look up a random label, so in replay a name that was not
+// recorded is answered by the recorded probe of the same type and parent zone.
+func (r tapeResolver) lookup(typ, name string, query func() ([]string, error)) ([]string, error) {
+	t := r.tape
+	if !t.replay {
+		values, err := query()
+		entry := RecordedLookup{Type: typ, Name: name, Values: values}
+		if err != nil {
+			entry.Error = err.Error()
+			var dnsErr *net.DNSError
+			if errors.As(err, &dnsErr) {
+				entry.NotFound = dnsErr.IsNotFound
+				entry.Timeout = dnsErr.IsTimeout
+			}
+		}
+		t.mu.Lock()
+		t.rec.Lookups = append(t.rec.Lookups, entry)
+		t.mu.Unlock()
+		return values, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var matches []RecordedLookup
+	for _, l := range t.rec.Lookups {
+		if l.Type == typ && l.Name == name {
+			matches = append(matches, l)
+		}
+	}
+	if len(matches) == 0 && name != t.rec.Domain {
+		for _, l := range t.rec.Lookups {
+			if l.Type == typ && l.Name != t.rec.Domain && parentZone(l.Name) == parentZone(name) {
+				matches = append(matches, l)
+			}
+		}
+	}
+	i := t.pick("lookup "+typ+" "+name, len(matches))
+	if i < 0 {
+		return nil, notRecorded("%s lookup of %s", typ, name)
+	}
+	l := matches[i]
+	if l.Error != "" {
+		return nil, &net.DNSError{Err: l.Error, Name: name, IsNotFound: l.NotFound, IsTimeout: l.Timeout}
+	}
+	return l.Values, nil
+}
+
+// parentZone strips the first label of a name.
+func parentZone(name string) string {
+	_, parent, _ := strings.Cut(strings.TrimSuffix(name, "."), ".")
+	return parent
+}
+
+// dnsServers records or replays the authoritative servers of a TLD.
+func (t *tape) dnsServers(tld string, discover func() ([]string, error)) ([]string, error) {
+	if t.replay {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		servers, ok := t.rec.DNSServers[tld]
+		if !ok {
+			return nil, notRecorded("nameservers of TLD %s", tld)
+		}
+		return servers, nil
+	}
+
+	servers, err := discover()
+	if err == nil {
+		t.mu.Lock()
+		if t.rec.DNSServers == nil {
+			t.rec.DNSServers = make(map[string][]string)
+		}
+		t.rec.DNSServers[tld] = servers
+		t.mu.Unlock()
+	}
+	return servers, err
+}
+
+// exchange records or replays an authoritative DNS query. Replayed answers
+// are matched by name and type only, so referral hops replay in order.
+func (t *tape) exchange(server, name string, qtype uint16, query func() (*DNSMessage, error)) (*DNSMessage, error) {
+	if !t.replay {
+		msg, err := query()
+		entry := RecordedExchange{Server: server, Name: name, Type: qtype, Response: msg}
+		entry.Error, entry.Code = errorFields(err)
+		t.mu.Lock()
+		t.rec.Exchanges = append(t.rec.Exchanges, entry)
+		t.mu.Unlock()
+		return msg, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var matches []RecordedExchange
+	for _, e := range t.rec.Exchanges {
+		if e.Name == name && e.Type == qtype {
+			matches = append(matches, e)
+		}
+	}
+	i := t.pick(fmt.Sprintf("exchange %s %d", name, qtype), len(matches))
+	if i < 0 {
+		return nil, notRecorded("DNS query %s type %d", name, qtype)
+	}
+	e := matches[i]
+	if err := replayError(e.Error, e.Code); err != nil {
+		return nil, err
+	}
+	if e.Response == nil {
+		return nil, notRecorded("DNS query %s type %d", name, qtype)
+	}
+	return e.Response, nil
+}
+
+// whoisServer records or replays the WHOIS server of a zone.
+func (t *tape) whoisServer(zone string, discover func() (string, error)) (string, error) {
+	if t.replay {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		server, ok := t.rec.WHOISServers[zone]
+		if !ok {
+			return "", notRecorded("WHOIS server of zone %s", zone)
+		}
+		return server, nil
+	}
+
+	server, err := discover()
+	if err == nil {
+		t.mu.Lock()
+		if t.rec.WHOISServers == nil {
+			t.rec.WHOISServers = make(map[string]string)
+		}
+		t.rec.WHOISServers[zone] = server
+		t.mu.Unlock()
+	}
+	return server, err
+}
+
+// whois records or replays a WHOIS query.
+func (t *tape) whois(server, query string, send func() (string, error)) (string, error) {
+	if !t.replay {
+		text, err := send()
+		entry := RecordedWHOIS{Server: server, Query: query, Text: text}
+		entry.Error, entry.Code = errorFields(err)
+		t.mu.Lock()
+		t.rec.WHOIS = append(t.rec.WHOIS, entry)
+		t.mu.Unlock()
+		return text, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var matches []RecordedWHOIS
+	for _, w := range t.rec.WHOIS {
+		if w.Server == server && strings.EqualFold(w.Query, query) {
+			matches = append(matches, w)
+		}
+	}
+	i := t.pick("whois "+server+" "+query, len(matches))
+	if i < 0 {
+		return "", notRecorded("WHOIS query %q to %s", query, server)
+	}
+	w := matches[i]
+	return w.Text, replayError(w.Error, w.Code)
+}
+
+// httpClient returns client with its transport wrapped to record or replay
+// RDAP traffic. The transport itself is shared, so connections are still reused.
+func (t *tape) httpClient(client *http.Client) *http.Client {
+	wrapped := *client
+	wrapped.Transport = tapeTransport{next: client.Transport, tape: t}
+	return &wrapped
+}
+
+// tapeTransport records or replays HTTP exchanges.
+type tapeTransport struct {
+	next http.RoundTripper
+	tape *tape
+}
+
+func (tr tapeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t := tr.tape
+	url := req.URL.String()
+
+	if t.replay {
+		t.mu.Lock()
+		var matches []RecordedHTTP
+		for _, h := range t.rec.RDAP {
+			if h.URL == url {
+				matches = append(matches, h)
+			}
+		}
+		i := t.pick("rdap "+url, len(matches))
+		t.mu.Unlock()
+		if i < 0 {
+			return nil, notRecorded("RDAP request %s", url)
+		}
+		h := matches[i]
+		if err := replayError(h.Error, h.Code); err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", h.Status, http.StatusText(h.Status)),
+			StatusCode:    h.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        h.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(h.Body)),
+			ContentLength: int64(len(h.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	next := tr.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	entry := RecordedHTTP{URL: url}
+	if err == nil {
+		var body []byte
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxRecordedBody))
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		entry.Status = resp.StatusCode
+		entry.Header = resp.Header.Clone()
+		entry.Body = string(body)
+	}
+	entry.Error, entry.Code = errorFields(err)
+
+	t.mu.Lock()
+	t.rec.RDAP = append(t.rec.RDAP, entry)
+	t.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
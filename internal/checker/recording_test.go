@@ -0,0 +1,198 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"domaincheck/internal/domain"
+)
+
+// TestRecordReplay records checks against local upstreams, changes what the
+// upstreams answer, and verifies replay reproduces the recorded outcome
+// without contacting them
+func TestRecordReplay(t *testing.T) {
+	var registered, requests int32
+	u := startLocalUpstreams(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/broken.test"):
+			w.WriteHeader(http.StatusBadGateway)
+		case atomic.LoadInt32(&registered) == 1:
+			w.Write([]byte(`{"status": ["active"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	whois := startWHOISServer(t, func(q string) string {
+		return "Domain Name: " + strings.ToUpper(q) + "\r\nRegistrar: Example Registrar\r\n"
+	})
+	dir := t.TempDir()
+
+	tests := []struct {
+		name       string
+		wantStatus domain.Status
+		wantSource string
+	}{
+		{"taken.test", domain.StatusTaken, "dns"},
+		{"free.test", domain.StatusAvailable, "rdap"},
+		{"broken.test", domain.StatusTaken, "whois"},
+	}
+
+	recorder := New(append(u.options(),
+		WithWHOISServers(map[string]string{"test": whois}),
+		WithRecording(dir),
+	)...)
+	for _, tt := range tests {
+		result, err := recorder.Check(context.Background(), domain.Domain{Full: tt.name, Name: "x", TLD: "test"})
+		if err != nil {
+			t.Fatalf("recording %s: %v", tt.name, err)
+		}
+		if result.Status != tt.wantStatus || result.Source != tt.wantSource {
+			t.Fatalf("recording %s = %s from %q, want %s from %q", tt.name, result.Status, result.Source, tt.wantStatus, tt.wantSource)
+		}
+	}
+
+	// The registry changes its mind; replay must not notice
+	atomic.StoreInt32(&registered, 1)
+	recorded := atomic.LoadInt32(&requests)
+
+	replayer := New(WithReplay(dir), WithBootstrap(u.bootstrap), WithCache(nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := replayer.Check(context.Background(), domain.Domain{Full: tt.name, Name: "x", TLD: "test"})
+			if err != nil {
+				t.Fatalf("Check() error = %v (attempts %+v)", err, result.Attempts)
+			}
+			if result.Status != tt.wantStatus || result.Source != tt.wantSource {
+				t.Errorf("Check() = %s from %q, want %s from %q", result.Status, result.Source, tt.wantStatus, tt.wantSource)
+			}
+		})
+	}
+	if got := atomic.LoadInt32(&requests); got != recorded {
+		t.Errorf("replay sent %d RDAP requests", got-recorded)
+	}
+}
+
+func TestRecordingContents(t *testing.T) {
+	u := startLocalUpstreams(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	dir := t.TempDir()
+
+	c := New(append(u.options(), WithRecording(dir))...)
+	if _, err := c.Check(context.Background(), domain.Domain{Full: "free.test", Name: "free", TLD: "test"}); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	rec, err := LoadRecording(filepath.Join(dir, "free.test.json"))
+	if err != nil {
+		t.Fatalf("LoadRecording() error = %v", err)
+	}
+	if rec.Domain != "free.test" || rec.Status != "available" || rec.Source != "rdap" {
+		t.Errorf("outcome = %s %s from %s", rec.Domain, rec.Status, rec.Source)
+	}
+
+	var probes, lookups int
+	for _, l := range rec.Lookups {
+		if l.Name == "free.test" {
+			lookups++
+			if !l.NotFound {
+				t.Errorf("lookup %+v, want not found", l)
+			}
+		} else {
+			probes++
+		}
+	}
+	if lookups != 3 || probes == 0 {
+		t.Errorf("lookups = %d of the domain and %d wildcard probes, want 3 and some", lookups, probes)
+	}
+	if len(rec.Exchanges) != 1 || rec.Exchanges[0].Response == nil || rec.Exchanges[0].Response.Rcode != DNSRcodeNameError {
+		t.Errorf("exchanges = %+v, want one NXDOMAIN", rec.Exchanges)
+	}
+	if len(rec.DNSServers["test"]) != 1 {
+		t.Errorf("DNS servers = %v", rec.DNSServers)
+	}
+	if len(rec.RDAP) != 1 || rec.RDAP[0].Status != http.StatusNotFound || !strings.HasSuffix(rec.RDAP[0].URL, "/domain/free.test") {
+		t.Errorf("RDAP = %+v, want one 404", rec.RDAP)
+	}
+}
+
+func TestReplayMissing(t *testing.T) {
+	dir := t.TempDir()
+	c := New(WithReplay(dir), WithCache(nil))
+	d := domain.Domain{Full: "unrecorded.test", Name: "unrecorded", TLD: "test"}
+
+	_, err := c.Check(context.Background(), d)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Check() error = %v, want ErrNotExist", err)
+	}
+
+	// A recording without any traffic: every source runs out of answers
+	rec := &Recording{Domain: d.Full}
+	if err := rec.Save(filepath.Join(dir, "unrecorded.test.json")); err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.Check(context.Background(), d)
+	if err == nil {
+		t.Fatalf("Check() = %s, want error", result.Status)
+	}
+	for _, a := range result.Attempts {
+		if a.Outcome == domain.StatusError && !strings.Contains(a.Error, ErrNotRecorded.Error()) {
+			t.Errorf("attempt %s error = %q, want %q", a.Source, a.Error, ErrNotRecorded)
+		}
+	}
+}
+
+// TestReplayFixtures replays the recordings in testdata/recordings and checks
+// they still produce the recorded outcome. Recordings attached to bug reports
+// become regression tests by adding them there.
+func TestReplayFixtures(t *testing.T) {
+	dir := filepath.Join("testdata", "recordings")
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no recordings in %s: %v", dir, err)
+	}
+	isolateUpstreams(t)
+	c := New(WithReplay(dir), WithCache(nil))
+
+	for _, file := range files {
+		rec, err := LoadRecording(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run(rec.Domain, func(t *testing.T) {
+			d, err := domain.Normalize(rec.Domain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, _ := c.Check(context.Background(), d)
+			if result.Status.String() != rec.Status || result.Source != rec.Source || result.ErrorCode != rec.ErrorCode {
+				t.Errorf("replay = %s from %q (code %q), recorded %s from %q (code %q)",
+					result.Status, result.Source, result.ErrorCode, rec.Status, rec.Source, rec.ErrorCode)
+			}
+		})
+	}
+}
+
+func TestRecordingSaveReplaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "a.test.json")
+	for _, status := range []string{"taken", "available"} {
+		if err := (&Recording{Domain: "a.test", Status: status}).Save(path); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	rec, err := LoadRecording(path)
+	if err != nil || rec.Status != "available" {
+		t.Errorf("LoadRecording() = %+v, %v", rec, err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("directory holds %d files, want 1 (no temp files left)", len(entries))
+	}
+}
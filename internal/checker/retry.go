@@ -0,0 +1,194 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// Default retry settings used when a RetryConfig field is zero.
+const (
+	defaultRetryAttempts  = 3
+	defaultRetryBaseDelay = 100 * time.Millisecond
+	defaultRetryMaxDelay  = 2 * time.Second
+)
+
+// RetryConfig configures how queries failing with a transient error
+// (timeout, refused or reset connection, HTTP 5xx) are retried.
+type RetryConfig struct {
+	// Attempts is the total number of tries per query, including the first
+	// (default 3). 1 or a negative value disables retries.
+	Attempts int
+
+	// BaseDelay is the backoff before the first retry (default 100ms); it
+	// doubles on each retry
+	BaseDelay time.Duration
+
+	// MaxDelay caps the backoff between two tries (default 2s)
+	MaxDelay time.Duration
+}
+
+// withDefaults fills in zero fields.
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.Attempts == 0 {
+		c.Attempts = defaultRetryAttempts
+	}
+	c.BaseDelay = durationOr(c.BaseDelay, defaultRetryBaseDelay)
+	c.MaxDelay = durationOr(c.MaxDelay, defaultRetryMaxDelay)
+	return c
+}
+
+// backoff returns the pause before retry number n (1-based): exponential,
+// capped at MaxDelay, with jitter so that concurrent callers spread out.
+func (c RetryConfig) backoff(n int) time.Duration {
+	delay := c.BaseDelay
+	for i := 1; i < n && delay < c.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	// Jitter: uniformly in [delay/2, delay]
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// transientError marks an upstream answer that is worth retrying (e.g. HTTP 503).
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// transient wraps an error so isTransient reports true for it.
+func transient(format string, args ...interface{}) error {
+	return &transientError{err: fmt.Errorf(format, args...)}
+}
+
+// isTransient reports whether a failed query may succeed if sent again.
+// Our own refusals (rate limit, open circuit), cancellation and permanent DNS
+// failures (the upstream's name does not resolve) are final.
+func isTransient(err error) bool {
+	var te *transientError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, ErrRateLimited), errors.Is(err, ErrCircuitOpen), errors.Is(err, context.Canceled):
+		return false
+	case errors.As(err, &te):
+		return true
+	case errors.As(err, &dnsErr):
+		// Resolver timeouts and SERVFAIL, but not "no such host"
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	case errors.As(err, &netErr):
+		// Timeouts, refused and reset connections
+		return true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		// Connection closed mid-response
+		return true
+	default:
+		return false
+	}
+}
+
+// isUnresolvable reports whether err is a "no such host" for the upstream
+// itself: the query never reached the upstream, so it says nothing about its
+// health.
+func isUnresolvable(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// callUpstream sends one query to an upstream host, guarded by its circuit
+// breaker and rate limiter, and retries transient failures with backoff.
+//
+// Parameters:
+//   - protocol: "rdap", "whois" or "dns"
+//   - host: the upstream host name or address, without port
+//   - query: sends the query; it is called once per try
+//
+// Returns the error of the last try. A retry is skipped when its backoff
+// would end after ctx's deadline.
+func callUpstream(ctx context.Context, protocol, host string, query func(context.Context) error) error {
+	cfg := currentRetryConfig()
+	breakers := currentBreakers()
+	limiter := currentRateLimiter()
+	if tapeFrom(ctx).replaying() {
+		// Replayed answers cost the upstream nothing and say nothing about its health
+		breakers, limiter = nil, nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err := breakers.Allow(protocol, host); err != nil {
+			return err
+		}
+		if err := limiter.Wait(ctx, protocol, host); err != nil {
+			breakers.release(protocol, host)
+			return fmt.Errorf("%s query to %s not sent: %w", protocol, host, err)
+		}
+
+		err := query(ctx)
+		switch {
+		case ctx.Err() != nil:
+			// The caller gave up: says nothing about the upstream
+			breakers.release(protocol, host)
+			return err
+		case isUnresolvable(err):
+			// A misconfigured or retired host name: retrying won't help, and
+			// opening its breaker would only hide the real error
+			breakers.release(protocol, host)
+			return err
+		case isTransient(err):
+			breakers.Record(protocol, host, err)
+		default:
+			// Answers, even negative ones, show the upstream is healthy
+			breakers.Record(protocol, host, nil)
+			return err
+		}
+
+		if attempt >= cfg.Attempts {
+			return err
+		}
+		delay := cfg.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// defaultRetryConfig is the retry policy used by the RDAP, WHOIS and DNS clients.
+var defaultRetryConfig = struct {
+	sync.RWMutex
+	cfg RetryConfig
+}{
+	cfg: RetryConfig{}.withDefaults(),
+}
+
+// SetRetryConfig replaces the retry policy used for all upstream queries.
+// This should be called at startup; zero fields select the defaults.
+func SetRetryConfig(cfg RetryConfig) {
+	defaultRetryConfig.Lock()
+	defaultRetryConfig.cfg = cfg.withDefaults()
+	defaultRetryConfig.Unlock()
+}
+
+// currentRetryConfig returns the retry policy for upstream queries.
+func currentRetryConfig() RetryConfig {
+	defaultRetryConfig.RLock()
+	defer defaultRetryConfig.RUnlock()
+	return defaultRetryConfig.cfg
+}
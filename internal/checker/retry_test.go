@@ -0,0 +1,159 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"network error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"dns timeout", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "i/o timeout", Name: "whois.example", IsTimeout: true}}, true},
+		{"dns servfail", &net.DNSError{Err: "server misbehaving", Name: "whois.example", IsTemporary: true}, true},
+		{"dns no such host", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "whois.example", IsNotFound: true}}, false},
+		{"unexpected EOF", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"5xx", transient("RDAP server returned %d", 502), true},
+		{"rate limited", fmt.Errorf("%w: busy", ErrRateLimited), false},
+		{"circuit open", fmt.Errorf("%w: whois", ErrCircuitOpen), false},
+		{"cancelled", context.Canceled, false},
+		{"other", errors.New("invalid WHOIS query"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	cfg := RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}.withDefaults()
+	tests := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 150 * time.Millisecond, 300 * time.Millisecond}, // capped
+		{10, 150 * time.Millisecond, 300 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := cfg.backoff(tt.retry); got < tt.min || got > tt.max {
+				t.Errorf("backoff(%d) = %v, want in [%v, %v]", tt.retry, got, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestCallUpstreamRetries(t *testing.T) {
+	isolateUpstreams(t)
+
+	calls := 0
+	err := callUpstream(context.Background(), "whois", "whois.example", func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return transient("timeout")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("callUpstream() = %v after %d calls, want success on the 3rd", err, calls)
+	}
+
+	calls = 0
+	final := errors.New("invalid WHOIS query")
+	err = callUpstream(context.Background(), "whois", "whois.example", func(ctx context.Context) error {
+		calls++
+		return final
+	})
+	if err != final || calls != 1 {
+		t.Errorf("callUpstream() = %v after %d calls, want non-transient error without retry", err, calls)
+	}
+}
+
+func TestCallUpstreamBreakerOpens(t *testing.T) {
+	isolateUpstreams(t)
+	SetRetryConfig(RetryConfig{Attempts: 1})
+	SetBreakers(NewBreakers(BreakerConfig{Threshold: 3, Cooldown: time.Hour}))
+
+	calls := 0
+	dead := func(ctx context.Context) error {
+		calls++
+		return transient("connection refused")
+	}
+	for i := 0; i < 3; i++ {
+		callUpstream(context.Background(), "whois", "dead.example", dead)
+	}
+	err := callUpstream(context.Background(), "whois", "dead.example", dead)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("callUpstream() after 3 failures = %v, want ErrCircuitOpen", err)
+	}
+	if calls != 3 {
+		t.Errorf("upstream called %d times, want 3 (open breaker fails fast)", calls)
+	}
+
+	statuses := BreakerStatuses()
+	if len(statuses) != 1 || statuses[0].Upstream != "whois/dead.example" || statuses[0].State != BreakerOpen {
+		t.Errorf("BreakerStatuses() = %+v", statuses)
+	}
+}
+
+// TestCallUpstreamUnresolvable verifies a host that does not resolve is
+// neither retried nor held against its breaker
+func TestCallUpstreamUnresolvable(t *testing.T) {
+	isolateUpstreams(t)
+	SetBreakers(NewBreakers(BreakerConfig{Threshold: 1, Cooldown: time.Hour}))
+
+	calls := 0
+	missing := func(ctx context.Context) error {
+		calls++
+		return &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "gone.example", IsNotFound: true}}
+	}
+	for i := 0; i < 3; i++ {
+		if err := callUpstream(context.Background(), "whois", "gone.example", missing); !isUnresolvable(err) {
+			t.Fatalf("callUpstream() = %v, want the DNS error", err)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("upstream called %d times for 3 queries, want 3 (no retries, no open breaker)", calls)
+	}
+	if statuses := BreakerStatuses(); len(statuses) != 0 {
+		t.Errorf("BreakerStatuses() = %+v, want none", statuses)
+	}
+}
+
+func TestRDAPRetriesServerError(t *testing.T) {
+	var requests int32
+	useRDAPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	d := domain.Domain{Full: "example.test", Name: "example", TLD: "test"}
+	status, _, err := RDAPLookup(context.Background(), d)
+	if err != nil || status != domain.StatusAvailable {
+		t.Errorf("RDAPLookup() = %v, %v; want available after retry", status, err)
+	}
+	if requests != 2 {
+		t.Errorf("server saw %d requests, want 2", requests)
+	}
+}
@@ -0,0 +1,213 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+// Verdict is the answer a single Source gives for a domain.
+//
+// A Source that cannot decide (for example the DNS pre-filter finding no
+// records) returns a Verdict with Status == domain.StatusUnknown so the
+// Pipeline moves on to the next stage.
+type Verdict struct {
+	// Status is the lifecycle status (StatusAvailable, StatusTaken, StatusRedemptionPeriod, ...)
+	// or StatusUnknown when undecided
+	Status domain.Status
+
+	// Registration holds registration details when the source provides them
+	Registration *domain.Registration
+
+	// Signal is the raw evidence behind the verdict ("HTTP 404", "MX records",
+	// the matched WHOIS indicator, ...). It is recorded in domain.Result.Attempts
+	// and may be set together with an error (e.g. "HTTP 503").
+	Signal string
+
+	// Confidence is how much the source trusts this verdict.
+	// Zero (domain.ConfidenceNone) means domain.ConfidenceMedium.
+	Confidence domain.Confidence
+}
+
+// Decided reports whether the verdict is a definitive answer.
+func (v Verdict) Decided() bool {
+	return v.Status != domain.StatusUnknown
+}
+
+// effectiveConfidence returns the verdict's confidence, medium when unset.
+func effectiveConfidence(v Verdict) domain.Confidence {
+	if v.Confidence == domain.ConfidenceNone {
+		return domain.ConfidenceMedium
+	}
+	return v.Confidence
+}
+
+// Source is a single availability data source (DNS, RDAP, WHOIS, ...).
+//
+// Implementations must be safe for concurrent use. Check should return an
+// error only when the source could not be queried at all; "no data" answers
+// are expressed as an undecided Verdict instead.
+type Source interface {
+	// Name is the short identifier reported in domain.Result.Source (e.g. "rdap")
+	Name() string
+
+	// Supports reports whether the source can answer for names registered in
+	// zone, the public suffix of the domain (e.g. "com", "co.uk"; see
+	// domain.Domain.Zone). Unsupported sources are skipped by the Pipeline
+	// without being called.
+	Supports(zone string) bool
+
+	// Check queries the source for the domain
+	Check(ctx context.Context, d domain.Domain) (Verdict, error)
+}
+
+// defaultDNSProbeTimeout bounds the DNS pre-filter's lookups.
+const defaultDNSProbeTimeout = 3 * time.Second
+
+// dnsSource adapts DNSFilter to the Source interface.
+// Zero fields select the system resolver, the shared wildcard detector and
+// defaultDNSProbeTimeout.
+type dnsSource struct {
+	resolver  *net.Resolver
+	wildcards *wildcardDetector
+	timeout   time.Duration
+}
+
+// NewDNSSource returns the DNS pre-filter as a Source.
+// It reports taken when the domain has A/AAAA/MX/NS records and is undecided otherwise.
+func NewDNSSource() Source {
+	return dnsSource{}
+}
+
+func (dnsSource) Name() string { return "dns" }
+
+func (dnsSource) Supports(tld string) bool { return true }
+
+func (s dnsSource) Check(ctx context.Context, d domain.Domain) (Verdict, error) {
+	found, signal, err := s.probe(ctx, d)
+	if found {
+		// Records only exist for delegated, i.e. registered, names
+		return Verdict{Status: domain.StatusTaken, Signal: signal, Confidence: domain.ConfidenceHigh}, nil
+	}
+	if err != nil {
+		return Verdict{Signal: signal}, fmt.Errorf("DNS lookup failed: %w", err)
+	}
+	return Verdict{Status: domain.StatusUnknown, Signal: signal}, nil
+}
+
+// probe is dnsProbe with the source's settings. The TLD's wildcard probe has
+// its own timeout; the source's timeout only bounds the domain's lookups.
+func (s dnsSource) probe(ctx context.Context, d domain.Domain) (bool, string, error) {
+	var resolver dnsResolver = &net.Resolver{}
+	if s.resolver != nil {
+		resolver = s.resolver
+	}
+	w := wildcards
+	if s.wildcards != nil {
+		w = s.wildcards
+	}
+	if t := tapeFrom(ctx); t != nil {
+		// Probe wildcards afresh so the recording holds every lookup
+		resolver = tapeResolver{next: resolver, tape: t}
+		w = newWildcardDetector(wildcardTTL)
+	}
+	wild := w.types(ctx, resolver, d.Zone())
+
+	ctx, cancel := context.WithTimeout(ctx, durationOr(s.timeout, defaultDNSProbeTimeout))
+	defer cancel()
+	return probeDNS(ctx, resolver, wild, d)
+}
+
+// authDNSSource adapts DNSDelegation to the Source interface.
+// A nil client selects the one set with SetDNSClient.
+type authDNSSource struct {
+	client *DNSClient
+}
+
+// NewAuthDNSSource returns the authoritative DNS delegation check as a Source.
+// It reports taken when the TLD's nameservers delegate the domain and is
+// undecided otherwise (NXDOMAIN does not rule out a registered name on hold).
+func NewAuthDNSSource() Source {
+	return authDNSSource{}
+}
+
+func (authDNSSource) Name() string { return "dns-auth" }
+
+func (authDNSSource) Supports(tld string) bool { return true }
+
+func (s authDNSSource) Check(ctx context.Context, d domain.Domain) (Verdict, error) {
+	client := s.client
+	if client == nil {
+		client = currentDNSClient()
+	}
+	delegation, err := client.Delegation(ctx, d)
+	if err != nil {
+		return Verdict{}, err
+	}
+	if delegation.Delegated() {
+		return Verdict{Status: domain.StatusTaken, Signal: delegation.Signal(), Confidence: domain.ConfidenceHigh}, nil
+	}
+	return Verdict{Status: domain.StatusUnknown, Signal: delegation.Signal()}, nil
+}
+
+// rdapSource adapts RDAPCheck to the Source interface.
+type rdapSource struct {
+	client rdapClient
+}
+
+// NewRDAPSource returns the RDAP client as a Source.
+// Only TLDs present in the RDAP bootstrap registry are supported.
+func NewRDAPSource() Source {
+	return rdapSource{}
+}
+
+func (rdapSource) Name() string { return "rdap" }
+
+func (s rdapSource) Supports(zone string) bool {
+	_, ok := s.client.server(zone)
+	return ok
+}
+
+func (s rdapSource) Check(ctx context.Context, d domain.Domain) (Verdict, error) {
+	status, reg, signal, err := s.client.lookup(ctx, d)
+	if err != nil {
+		return Verdict{Signal: signal}, err
+	}
+	return Verdict{Status: status, Registration: reg, Signal: signal, Confidence: domain.ConfidenceHigh}, nil
+}
+
+// whoisSource adapts WHOISCheck to the Source interface.
+// A nil client selects the one set with SetWHOISClient.
+type whoisSource struct {
+	client *WHOISClient
+}
+
+// NewWHOISSource returns the WHOIS client as a Source. It supports every TLD.
+func NewWHOISSource() Source {
+	return whoisSource{}
+}
+
+func (whoisSource) Name() string { return "whois" }
+
+func (whoisSource) Supports(tld string) bool { return true }
+
+func (s whoisSource) Check(ctx context.Context, d domain.Domain) (Verdict, error) {
+	client := s.client
+	if client == nil {
+		client = currentWHOISClient()
+	}
+	status, reg, signal, err := lookupWHOIS(ctx, client, d)
+	if err != nil {
+		return Verdict{Signal: signal}, err
+	}
+
+	// Free-text matching is a heuristic; a guess is worth even less
+	confidence := domain.ConfidenceMedium
+	if signal == whoisAssumedTaken {
+		confidence = domain.ConfidenceLow
+	}
+	return Verdict{Status: status, Registration: reg, Signal: signal, Confidence: confidence}, nil
+}
@@ -0,0 +1,135 @@
+package checker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+// defaultStreamConcurrency is the number of parallel checks of CheckStream.
+const defaultStreamConcurrency = 10
+
+// StreamOptions configures CheckStream.
+type StreamOptions struct {
+	// Concurrency bounds the number of domains checked at once (default 10)
+	Concurrency int
+
+	// Consensus checks every domain with CheckConsensus instead of Check
+	Consensus bool
+
+	// Acquire, when set, is called before each check to take a slot of a pool
+	// shared with other callers (e.g. a server-wide scheduler); release is
+	// called when the check is done. An error fails that domain's check.
+	Acquire func(ctx context.Context) (release func(), err error)
+}
+
+// StreamResult is the result of one domain checked by CheckStream.
+type StreamResult struct {
+	// Index is the position of the domain in the input channel, from 0
+	Index int
+
+	// Result is the check result, as returned by Check
+	Result domain.Result
+
+	// Err is the error returned by Check (Result then has StatusError)
+	Err error
+}
+
+// CheckStream checks the domains received from in, at most
+// opts.Concurrency at a time, and sends each result on the returned channel as
+// soon as it is ready. Results arrive in completion order; StreamResult.Index
+// ties each one to its input. Domains are read from in only as fast as they
+// are checked, so memory use does not grow with the batch.
+//
+// The returned channel is closed once in is closed and every domain has been
+// reported. When ctx is done, CheckStream stops reading in, checks in progress
+// are cancelled, results not yet received may be dropped, and the channel is
+// closed without leaking goroutines. Callers must either drain the channel or
+// cancel ctx.
+func (c *Checker) CheckStream(ctx context.Context, in <-chan domain.Domain, opts StreamOptions) <-chan StreamResult {
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = defaultStreamConcurrency
+	}
+	check := c.Check
+	if opts.Consensus {
+		check = c.CheckConsensus
+	}
+
+	type job struct {
+		index int
+		d     domain.Domain
+	}
+	jobs := make(chan job)
+	out := make(chan StreamResult, workers)
+
+	// Number the inputs and hand them to idle workers
+	go func() {
+		defer close(jobs)
+		for index := 0; ; index++ {
+			var (
+				d  domain.Domain
+				ok bool
+			)
+			select {
+			case d, ok = <-in:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- job{index: index, d: d}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				result, err := acquiredCheck(ctx, j.d, check, opts.Acquire)
+				select {
+				case out <- StreamResult{Index: j.index, Result: result, Err: err}:
+				case <-ctx.Done():
+					// Nobody may be receiving any more; drain jobs and quit
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// acquiredCheck runs check within a slot taken with acquire (if set).
+func acquiredCheck(ctx context.Context, d domain.Domain, check func(context.Context, domain.Domain) (domain.Result, error), acquire func(context.Context) (func(), error)) (domain.Result, error) {
+	if acquire != nil {
+		start := time.Now()
+		release, err := acquire(ctx)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				err = ctxErr
+			}
+			return failResult(domain.Result{Domain: d, CheckedAt: start}, err, "", start)
+		}
+		defer release()
+	}
+	return check(ctx, d)
+}
+
+// CheckStream checks a stream of domains with the default Checker.
+// See Checker.CheckStream.
+func CheckStream(ctx context.Context, in <-chan domain.Domain, opts StreamOptions) <-chan StreamResult {
+	return currentChecker().CheckStream(ctx, in, opts)
+}
@@ -0,0 +1,223 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+// gatedSource answers "taken" for names starting with "taken" and available
+// otherwise, after waiting for gate (if set). It tracks how many checks run at once.
+type gatedSource struct {
+	gate    chan struct{}
+	running *int32
+	peak    *int32
+}
+
+func (gatedSource) Name() string { return "gated" }
+
+func (gatedSource) Supports(tld string) bool { return true }
+
+func (s gatedSource) Check(ctx context.Context, d domain.Domain) (Verdict, error) {
+	n := atomic.AddInt32(s.running, 1)
+	defer atomic.AddInt32(s.running, -1)
+	for {
+		peak := atomic.LoadInt32(s.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(s.peak, peak, n) {
+			break
+		}
+	}
+
+	if s.gate != nil {
+		select {
+		case <-s.gate:
+		case <-ctx.Done():
+			return Verdict{}, ctx.Err()
+		}
+	}
+	if strings.HasPrefix(d.Name, "taken") {
+		return verdictTaken, nil
+	}
+	return verdictAvailable, nil
+}
+
+// newGatedChecker returns a checker running only a gatedSource.
+func newGatedChecker(gate chan struct{}) (*Checker, *int32) {
+	src := gatedSource{gate: gate, running: new(int32), peak: new(int32)}
+	c := New(WithPipeline(NewPipeline(Stage{Source: src, Stop: StopOnAnswer})), WithCache(nil))
+	return c, src.peak
+}
+
+// feed returns a closed channel holding the domains named.
+func feed(names ...string) <-chan domain.Domain {
+	in := make(chan domain.Domain, len(names))
+	for _, name := range names {
+		in <- domain.Domain{Full: name + ".test", Name: name, TLD: "test"}
+	}
+	close(in)
+	return in
+}
+
+func TestCheckStream(t *testing.T) {
+	names := make([]string, 50)
+	for i := range names {
+		names[i] = fmt.Sprintf("free%d", i)
+		if i%5 == 0 {
+			names[i] = fmt.Sprintf("taken%d", i)
+		}
+	}
+	c, peak := newGatedChecker(nil)
+
+	seen := make([]bool, len(names))
+	for sr := range c.CheckStream(context.Background(), feed(names...), StreamOptions{Concurrency: 4}) {
+		if sr.Index < 0 || sr.Index >= len(names) || seen[sr.Index] {
+			t.Fatalf("unexpected or repeated index %d", sr.Index)
+		}
+		seen[sr.Index] = true
+
+		if sr.Result.Domain.Name != names[sr.Index] {
+			t.Errorf("result %d is for %s, want %s", sr.Index, sr.Result.Domain.Name, names[sr.Index])
+		}
+		want := domain.StatusAvailable
+		if strings.HasPrefix(names[sr.Index], "taken") {
+			want = domain.StatusTaken
+		}
+		if sr.Err != nil || sr.Result.Status != want {
+			t.Errorf("%s = %s, %v, want %s", names[sr.Index], sr.Result.Status, sr.Err, want)
+		}
+	}
+	for i, ok := range seen {
+		if !ok {
+			t.Errorf("no result for index %d", i)
+		}
+	}
+	if got := atomic.LoadInt32(peak); got > 4 {
+		t.Errorf("peak concurrency = %d, want <= 4", got)
+	}
+}
+
+// TestCheckStreamBounded verifies inputs are only read as workers free up
+func TestCheckStreamBounded(t *testing.T) {
+	gate := make(chan struct{})
+	c, peak := newGatedChecker(gate)
+
+	in := make(chan domain.Domain)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := c.CheckStream(ctx, in, StreamOptions{Concurrency: 2})
+
+	// Two workers take two domains; the dispatcher holds a third.
+	// Distinct names: concurrent checks of one domain are coalesced
+	sent := 0
+	for sent < 4 {
+		select {
+		case in <- domain.Domain{Full: fmt.Sprintf("d%d.test", sent), Name: fmt.Sprintf("d%d", sent), TLD: "test"}:
+			sent++
+			continue
+		case <-time.After(50 * time.Millisecond):
+		}
+		break
+	}
+	if sent != 3 {
+		t.Errorf("CheckStream accepted %d domains while 2 checks were blocked, want 3", sent)
+	}
+
+	close(gate)
+	close(in)
+	n := 0
+	for range out {
+		n++
+	}
+	if n != sent {
+		t.Errorf("got %d results, want %d", n, sent)
+	}
+	if got := atomic.LoadInt32(peak); got != 2 {
+		t.Errorf("peak concurrency = %d, want 2", got)
+	}
+}
+
+// TestCheckStreamCancel verifies cancelling closes the stream without leaking
+// goroutines, even when nobody receives the results.
+func TestCheckStreamCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	gate := make(chan struct{}) // never opened: checks only end on cancel
+	c, _ := newGatedChecker(gate)
+	in := make(chan domain.Domain) // never closed
+	ctx, cancel := context.WithCancel(context.Background())
+	out := c.CheckStream(ctx, in, StreamOptions{Concurrency: 3})
+	for i := 0; i < 3; i++ {
+		in <- domain.Domain{Full: fmt.Sprintf("d%d.test", i), Name: fmt.Sprintf("d%d", i), TLD: "test"}
+	}
+
+	cancel()
+	deadline := time.After(2 * time.Second)
+	for open := true; open; {
+		select {
+		case _, open = <-out:
+		case <-deadline:
+			t.Fatal("stream not closed after cancel")
+		}
+	}
+
+	// Goroutines exit asynchronously after closing the channel
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines = %d after cancel, %d before", after, before)
+	}
+}
+
+func TestCheckStreamConsensus(t *testing.T) {
+	c := New(WithPipeline(NewPipeline(
+		Stage{Source: fakeSource{name: "a", verdict: verdictTaken}, Stop: StopOnAnswer},
+		Stage{Source: fakeSource{name: "b", verdict: verdictAvailable}, Stop: StopOnAnswer},
+	)), WithCache(nil))
+
+	for _, consensus := range []bool{false, true} {
+		var got domain.Status
+		for sr := range c.CheckStream(context.Background(), feed("x"), StreamOptions{Consensus: consensus}) {
+			got = sr.Result.Status
+		}
+		want := domain.StatusTaken
+		if consensus {
+			want = domain.StatusConflict
+		}
+		if got != want {
+			t.Errorf("Consensus %v: status = %s, want %s", consensus, got, want)
+		}
+	}
+}
+
+// TestCheckStreamAcquire verifies every check runs within an acquired slot
+// and a refused slot fails only that domain
+func TestCheckStreamAcquire(t *testing.T) {
+	c, _ := newGatedChecker(nil)
+	var acquired, released int32
+	acquire := func(ctx context.Context) (func(), error) {
+		if atomic.AddInt32(&acquired, 1) == 2 {
+			return nil, ErrRateLimited
+		}
+		return func() { atomic.AddInt32(&released, 1) }, nil
+	}
+
+	failed := 0
+	for sr := range c.CheckStream(context.Background(), feed("a", "b", "c"), StreamOptions{Concurrency: 1, Acquire: acquire}) {
+		if sr.Err != nil {
+			failed++
+			if sr.Result.Status != domain.StatusError || sr.Result.ErrorCode != CodeRateLimited {
+				t.Errorf("refused check = %s (code %q), want error rate_limited", sr.Result.Status, sr.Result.ErrorCode)
+			}
+		}
+	}
+	if failed != 1 || acquired != 3 || released != 2 {
+		t.Errorf("failed %d, acquired %d, released %d; want 1, 3, 2", failed, acquired, released)
+	}
+}
@@ -1,104 +1,598 @@
 package checker
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
-	"os/exec"
+	"io"
+	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"domaincheck/internal/domain"
 )
 
-// WHOISCheck queries the whois system to check if a domain is registered.
+const (
+	// ianaWHOISServer is the root WHOIS server used to discover per-TLD servers
+	ianaWHOISServer = "whois.iana.org"
+
+	// whoisPort is the RFC 3912 WHOIS port
+	whoisPort = "43"
+
+	// maxWHOISReferrals limits how many referrals are followed (registry → registrar)
+	maxWHOISReferrals = 2
+)
+
+// WHOISClient defaults, used when the corresponding field is zero.
+const (
+	defaultWHOISConnectTimeout = 5 * time.Second
+	defaultWHOISReadTimeout    = 10 * time.Second
+	defaultWHOISTimeout        = 10 * time.Second
+	defaultWHOISMaxResponse    = 256 << 10
+)
+
+// whoisAvailableIndicators are response fragments meaning "no such domain".
+var whoisAvailableIndicators = []string{
+	"No match for domain",
+	"No match for \"",
+	"NOT FOUND",
+	"No entries found",
+	"no matching record",
+	"Domain not found",
+	"No Data Found",
+	"Status: AVAILABLE",
+	"Not found:",
+}
+
+// whoisTakenIndicators are response fragments only present for registered domains.
+var whoisTakenIndicators = []string{
+	"Registry Domain ID:",
+	"Creation Date:",
+	"Registrar:",
+	"Domain Status:",
+	"Name Server:",
+	"Registrant Name:",
+}
+
+// whoisRateLimitIndicators are (lowercase) fragments of the refusals WHOIS
+// servers send instead of an answer when a client queries too fast.
+var whoisRateLimitIndicators = []string{
+	"query rate limit exceeded",
+	"rate limit exceeded",
+	"too many requests",
+	"exceeded the maximum allowable number",
+	"quota exceeded",
+}
+
+// whoisLifecycleIndicators map response fragments to lifecycle states.
+// They are matched case-insensitively before the availability indicators, in
+// order, so a name in redemption is not reported as plainly "taken" and a
+// reserved name is not reported as "available". Phrases are specific on purpose:
+// generic words like "reserved" appear in most WHOIS disclaimers.
+var whoisLifecycleIndicators = []struct {
+	fragment string
+	status   domain.Status
+}{
+	{"redemptionperiod", domain.StatusRedemptionPeriod},
+	{"redemption period", domain.StatusRedemptionPeriod},
+	{"pendingrestore", domain.StatusRedemptionPeriod},
+	{"pendingdelete", domain.StatusPendingDelete},
+	{"pending delete", domain.StatusPendingDelete},
+	{"status: reserved", domain.StatusReserved},
+	{"reserved domain name", domain.StatusReserved},
+	{"reserved by the registry", domain.StatusReserved},
+	{"is a reserved name", domain.StatusReserved},
+	{"status: blocked", domain.StatusBlocked},
+	{"domain is blocked", domain.StatusBlocked},
+	{"dpml block", domain.StatusBlocked},
+	{"status: premium", domain.StatusPremium},
+	{"is a premium domain", domain.StatusPremium},
+	{"premium domain name", domain.StatusPremium},
+}
+
+// whoisReferralKeys are the fields thin registries use to point at the registrar's server.
+var whoisReferralKeys = []string{
+	"registrar whois server:",
+	"whois server:",
+	"referralserver:",
+}
+
+// WHOISClient is a native RFC 3912 WHOIS client (TCP port 43).
+//
+// The zero value is ready to use: servers are discovered through whois.iana.org
+// and referrals from thin registries (like .com) are followed to the registrar.
+// A WHOISClient is safe for concurrent use.
+type WHOISClient struct {
+	// ConnectTimeout bounds establishing each TCP connection (default 5s)
+	ConnectTimeout time.Duration
+
+	// ReadTimeout bounds reading each server's response (default 10s)
+	ReadTimeout time.Duration
+
+	// Timeout bounds a complete lookup including discovery and referrals (default 10s)
+	Timeout time.Duration
+
+	// MaxResponseSize caps the bytes read per server; longer responses are truncated (default 256KB)
+	MaxResponseSize int64
+
+	// Servers overrides discovery: zone → "host" or "host:port". Keys are
+	// TLDs ("com") or public suffixes with their own server ("co.za")
+	Servers map[string]string
+
+	// IANAServer is the discovery server (default whois.iana.org)
+	IANAServer string
+
+	// DisableReferrals stops the client from following registrar referrals
+	DisableReferrals bool
+
+	mu         sync.Mutex
+	discovered map[string]string
+}
+
+// WHOISResponse is the answer to a WHOIS lookup.
+type WHOISResponse struct {
+	// Registry is the registry's answer, the only one that decides availability
+	Registry string
+
+	// Registrar is the answer of the registrar WHOIS server the registry
+	// referred to (thin registries like .com), or "" without a referral
+	Registrar string
+}
+
+// Text returns the registry's answer followed by the registrar's, if any.
+func (r WHOISResponse) Text() string {
+	if r.Registrar == "" {
+		return r.Registry
+	}
+	return r.Registry + "\n" + r.Registrar
+}
+
+// Lookup returns the WHOIS answers for a domain.
+//
+// When the registry's answer refers to a registrar WHOIS server, the
+// registrar is queried as well. A failing referral is not an error since the
+// registry answer is already authoritative for availability.
+func (c *WHOISClient) Lookup(ctx context.Context, d domain.Domain) (WHOISResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, durationOr(c.Timeout, defaultWHOISTimeout))
+	defer cancel()
+
+	server, err := c.ServerFor(ctx, d.Zone())
+	if err != nil {
+		return WHOISResponse{}, err
+	}
+
+	output, err := c.Query(ctx, server, d.Full)
+	if err != nil {
+		return WHOISResponse{}, err
+	}
+	resp := WHOISResponse{Registry: output}
+
+	if c.DisableReferrals {
+		return resp, nil
+	}
+
+	current := output
+	visited := map[string]bool{server: true}
+	for i := 0; i < maxWHOISReferrals; i++ {
+		referral := parseWHOISReferral(current)
+		if referral == "" || visited[referral] {
+			break
+		}
+		visited[referral] = true
+
+		current, err = c.Query(ctx, referral, d.Full)
+		if err != nil {
+			break
+		}
+		if resp.Registrar != "" {
+			resp.Registrar += "\n"
+		}
+		resp.Registrar += current
+	}
+
+	return resp, nil
+}
+
+// ServerFor returns the WHOIS server for a zone: a TLD ("com") or a public
+// suffix ("co.uk").
+//
+// The override in Servers for the longest matching suffix wins ("co.uk", then
+// "uk"), then the TLD's server in the TLD registry (see domain.LookupTLD);
+// otherwise the IANA root server is asked about the TLD and the "whois:" field
+// of its answer is cached for the lifetime of the client.
+func (c *WHOISClient) ServerFor(ctx context.Context, zone string) (string, error) {
+	zone = strings.ToLower(strings.Trim(zone, "."))
+	if zone == "" {
+		return "", fmt.Errorf("no TLD to look up WHOIS server for")
+	}
+
+	if t := tapeFrom(ctx); t != nil {
+		return t.whoisServer(zone, func() (string, error) { return c.serverFor(ctx, zone) })
+	}
+	return c.serverFor(ctx, zone)
+}
+
+// serverFor is ServerFor for a lowercase, non-empty zone.
+func (c *WHOISClient) serverFor(ctx context.Context, zone string) (string, error) {
+	for suffix := zone; ; {
+		if server, ok := c.Servers[suffix]; ok {
+			return server, nil
+		}
+		dot := strings.IndexByte(suffix, '.')
+		if dot < 0 {
+			break
+		}
+		suffix = suffix[dot+1:]
+	}
+	tld := zone[strings.LastIndexByte(zone, '.')+1:]
+	if info, ok := domain.LookupTLD(tld); ok && info.WHOIS != "" {
+		return info.WHOIS, nil
+	}
+
+	c.mu.Lock()
+	server, ok := c.discovered[tld]
+	c.mu.Unlock()
+	if ok {
+		return server, nil
+	}
+
+	iana := c.IANAServer
+	if iana == "" {
+		iana = ianaWHOISServer
+	}
+	output, err := c.Query(ctx, iana, tld)
+	if err != nil {
+		return "", fmt.Errorf("WHOIS server discovery failed: %w", err)
+	}
+
+	server = whoisField(output, "whois:")
+	if server == "" {
+		return "", withCode(ErrUnsupportedTLD, fmt.Errorf("no WHOIS server known for TLD: %s", tld))
+	}
+
+	c.mu.Lock()
+	if c.discovered == nil {
+		c.discovered = make(map[string]string)
+	}
+	c.discovered[tld] = server
+	c.mu.Unlock()
+
+	return server, nil
+}
+
+// Query sends a single RFC 3912 query to server ("host" or "host:port") and
+// returns the raw response text.
+//
+// Queries are rate limited per server; connection failures and timeouts are
+// retried with backoff and count against the server's circuit breaker
+// (see SetRateLimiter, SetRetryConfig, SetBreakers).
+func (c *WHOISClient) Query(ctx context.Context, server, query string) (string, error) {
+	// SECURITY: A line break would let callers smuggle extra queries
+	if strings.ContainsAny(query, "\r\n") {
+		return "", withCode(ErrInvalidDomain, fmt.Errorf("invalid WHOIS query"))
+	}
+
+	addr := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		addr = net.JoinHostPort(server, whoisPort)
+	}
+
+	send := func() (string, error) {
+		// Rate limits, retries and the circuit breaker are keyed by host
+		var output string
+		err := callUpstream(ctx, "whois", hostOf(addr), func(ctx context.Context) error {
+			var err error
+			output, err = c.queryOnce(ctx, server, addr, query)
+			return err
+		})
+		return output, err
+	}
+	if t := tapeFrom(ctx); t != nil {
+		return t.whois(server, query, send)
+	}
+	return send()
+}
+
+// queryOnce performs a single WHOIS exchange with addr.
+func (c *WHOISClient) queryOnce(ctx context.Context, server, addr, query string) (string, error) {
+	dialer := net.Dialer{Timeout: durationOr(c.ConnectTimeout, defaultWHOISConnectTimeout)}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return "", whoisError(ctx, fmt.Errorf("whois connect to %s failed: %w", server, err))
+	}
+	defer conn.Close()
+
+	// Bound the exchange by both the read timeout and the context deadline
+	deadline := time.Now().Add(durationOr(c.ReadTimeout, defaultWHOISReadTimeout))
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return "", err
+	}
+
+	// Unblock reads immediately if the context is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := io.WriteString(conn, query+"\r\n"); err != nil {
+		return "", whoisError(ctx, fmt.Errorf("whois query to %s failed: %w", server, err))
+	}
+
+	limit := c.MaxResponseSize
+	if limit <= 0 {
+		limit = defaultWHOISMaxResponse
+	}
+	output, err := io.ReadAll(io.LimitReader(conn, limit))
+	if err != nil {
+		return "", whoisError(ctx, fmt.Errorf("whois read from %s failed: %w", server, err))
+	}
+
+	return string(output), nil
+}
+
+// whoisError reports context expiry as a timeout instead of a network error.
+func whoisError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("whois timeout: %w", ctx.Err())
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("whois cancelled: %w", ctx.Err())
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("whois timeout: %w", err)
+	}
+	return err
+}
+
+// parseWHOISReferral extracts the next server to query from a thin registry response.
+func parseWHOISReferral(output string) string {
+	for _, key := range whoisReferralKeys {
+		value := whoisField(output, key)
+		if value == "" {
+			continue
+		}
+		// ReferralServer uses URL syntax (whois://host:port); rwhois is not supported
+		if strings.HasPrefix(value, "rwhois://") {
+			continue
+		}
+		value = strings.TrimPrefix(value, "whois://")
+		value = strings.TrimSuffix(value, "/")
+		if value != "" && !strings.ContainsAny(value, " \t") {
+			return strings.ToLower(value)
+		}
+	}
+	return ""
+}
+
+// whoisField returns the value of the first "key: value" line matching key (case-insensitive).
+func whoisField(output, key string) string {
+	if values := whoisFields(output, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// whoisFields returns the non-empty values of every "key: value" line
+// matching key (case-insensitive), in order.
+func whoisFields(output, key string) []string {
+	var values []string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) > len(key) && strings.EqualFold(line[:len(key)], key) {
+			if value := strings.TrimSpace(line[len(key):]); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// durationOr returns d, or def when d is not positive.
+func durationOr(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
+// defaultWHOISClient is the client used by WHOISCheck.
+var defaultWHOISClient = struct {
+	sync.RWMutex
+	client *WHOISClient
+}{
+	client: &WHOISClient{},
+}
+
+// SetWHOISClient replaces the client used by WHOISCheck.
+// This should be called at startup to tune timeouts or pin servers.
+func SetWHOISClient(c *WHOISClient) {
+	if c == nil {
+		return
+	}
+	defaultWHOISClient.Lock()
+	defaultWHOISClient.client = c
+	defaultWHOISClient.Unlock()
+}
+
+// currentWHOISClient returns the client used by WHOISCheck.
+func currentWHOISClient() *WHOISClient {
+	defaultWHOISClient.RLock()
+	defer defaultWHOISClient.RUnlock()
+	return defaultWHOISClient.client
+}
+
+// WHOISCheck queries WHOIS to check if a domain is registered.
 //
 // This is a fallback mechanism used when RDAP is unavailable or doesn't support the TLD.
 // WHOIS is the legacy protocol that predates RDAP and has inconsistent output formats.
 //
 // The function:
-//   - Executes the system `whois` command
+//   - Finds the TLD's WHOIS server (via whois.iana.org) and queries it over TCP port 43
+//   - Follows registrar referrals for thin registries like .com
 //   - Parses unstructured text output to determine availability
 //   - Returns availability based on pattern matching
 //
 // Important Notes:
-//   - Requires `whois` command to be installed on the system
+//   - Uses a built-in client; no `whois` binary is required (see SetWHOISClient)
 //   - Output format varies by TLD and WHOIS server
 //   - Less reliable than RDAP due to inconsistent formats
-//   - Exit code 1 is sometimes returned for available domains (not an error)
 //
 // Returns:
 //   - available: true if domain is available for registration
-//   - err: error if whois command failed or could not be executed
+//   - err: error if the WHOIS server could not be found or queried
 func WHOISCheck(ctx context.Context, d domain.Domain) (available bool, err error) {
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
+	status, err := WHOISStatus(ctx, d)
+	return status.Registrable(), err
+}
 
-	// Execute whois command
-	cmd := exec.CommandContext(ctx, "whois", d.Full)
-	output, cmdErr := cmd.CombinedOutput()
-	outputStr := string(output)
+// WHOISStatus is WHOISCheck returning the lifecycle status
+// (e.g. StatusRedemptionPeriod) instead of a boolean.
+func WHOISStatus(ctx context.Context, d domain.Domain) (domain.Status, error) {
+	status, _, _, err := whoisLookup(ctx, d)
+	return status, err
+}
+
+// whoisLookup is WHOISStatus that also returns the registration details of
+// taken domains and the signal behind the status (the matched indicator),
+// used for the Result evidence trail.
+func whoisLookup(ctx context.Context, d domain.Domain) (domain.Status, *domain.Registration, string, error) {
+	return lookupWHOIS(ctx, currentWHOISClient(), d)
+}
 
-	// Check for availability indicators first (even if command errored)
-	// WHOIS servers often return exit code 1 for available domains
-	availableIndicators := []string{
-		"No match for domain",
-		"No match for \"",
-		"NOT FOUND",
-		"No entries found",
-		"no matching record",
-		"Domain not found",
-		"No Data Found",
-		"Status: AVAILABLE",
-		"Not found:",
+// lookupWHOIS is whoisLookup with an explicit client.
+//
+// Only the registry's answer is classified: a registrar's answer can carry
+// its own boilerplate ("No match" for an unknown contact, lapsed-domain
+// notices) that says nothing about the registry's view. The registrar's
+// answer is only read for details, since thin registries publish few.
+func lookupWHOIS(ctx context.Context, client *WHOISClient, d domain.Domain) (domain.Status, *domain.Registration, string, error) {
+	resp, err := client.Lookup(ctx, d)
+	if err != nil {
+		return domain.StatusUnknown, nil, "", err
+	}
+	status, signal, err := matchWHOISStatus(resp.Registry)
+	if err != nil || status == domain.StatusAvailable {
+		return status, nil, signal, err
 	}
+	return status, parseWHOISRegistration(resp), signal, nil
+}
+
+// whoisDateLayouts are the date formats of WHOIS "... Date:" fields: the
+// ICANN RDDS format first, then common ccTLD variants.
+var whoisDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02-Jan-2006",
+}
 
-	for _, indicator := range availableIndicators {
-		if strings.Contains(outputStr, indicator) {
-			return true, nil
+// parseWHOISRegistration extracts the registration details from the fields
+// of the ICANN RDDS format. Each field is taken from the registrar's answer
+// when present, else from the registry's. It returns nil when neither
+// answer has any of the fields.
+func parseWHOISRegistration(resp WHOISResponse) *domain.Registration {
+	field := func(keys ...string) string {
+		for _, output := range []string{resp.Registrar, resp.Registry} {
+			for _, key := range keys {
+				if value := whoisField(output, key); value != "" {
+					return value
+				}
+			}
 		}
+		return ""
+	}
+	fields := func(key string) []string {
+		if values := whoisFields(resp.Registrar, key); len(values) > 0 {
+			return values
+		}
+		return whoisFields(resp.Registry, key)
+	}
+	date := func(keys ...string) time.Time {
+		value := field(keys...)
+		for _, layout := range whoisDateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC()
+			}
+		}
+		return time.Time{}
 	}
 
-	// Check for taken indicators
-	takenIndicators := []string{
-		"Registry Domain ID:",
-		"Creation Date:",
-		"Registrar:",
-		"Domain Status:",
-		"Name Server:",
-		"Registrant Name:",
+	reg := &domain.Registration{
+		Registrar:       field("Registrar:", "Sponsoring Registrar:"),
+		RegistrarIANAID: field("Registrar IANA ID:"),
+		Created:         date("Creation Date:", "Created:"),
+		Expires:         date("Registry Expiry Date:", "Registrar Registration Expiration Date:", "Expiry Date:"),
+		Updated:         date("Updated Date:", "Last Updated:"),
+	}
+	for _, ns := range fields("Name Server:") {
+		reg.Nameservers = append(reg.Nameservers, strings.ToLower(strings.TrimSuffix(ns, ".")))
+	}
+	for _, status := range fields("Domain Status:") {
+		// "clientTransferProhibited https://icann.org/epp#clientTransferProhibited"
+		reg.Statuses = append(reg.Statuses, strings.Fields(status)[0])
 	}
+	dnssec := strings.ToLower(field("DNSSEC:"))
+	reg.DNSSEC = strings.HasPrefix(dnssec, "signed") || dnssec == "yes"
 
-	for _, indicator := range takenIndicators {
-		if strings.Contains(outputStr, indicator) {
-			return false, nil
+	if reg.Registrar == "" && reg.Created.IsZero() && reg.Expires.IsZero() && len(reg.Nameservers) == 0 && len(reg.Statuses) == 0 {
+		return nil
+	}
+	return reg
+}
+
+// parseWHOISStatus interprets WHOIS text using the indicator lists.
+func parseWHOISStatus(output string) (domain.Status, error) {
+	status, _, err := matchWHOISStatus(output)
+	return status, err
+}
+
+// whoisAssumedTaken is the signal reported when no indicator matched and the
+// domain was assumed taken.
+const whoisAssumedTaken = "no indicator matched, assumed taken"
+
+// matchWHOISStatus is parseWHOISStatus that also returns which indicator matched.
+func matchWHOISStatus(output string) (domain.Status, string, error) {
+	// A throttled query says nothing about the domain
+	lower := strings.ToLower(output)
+	for _, fragment := range whoisRateLimitIndicators {
+		if strings.Contains(lower, fragment) {
+			return domain.StatusUnknown, fmt.Sprintf("matched %q", fragment), fmt.Errorf("%w: whois server refused the query", ErrRateLimited)
 		}
 	}
 
-	// If command had a real error (not just exit code 1), handle it
-	if cmdErr != nil {
-		// Context deadline exceeded
-		if ctx.Err() == context.DeadlineExceeded {
-			return false, fmt.Errorf("whois timeout: %w", ctx.Err())
+	// Lifecycle states take precedence over plain available/taken
+	for _, indicator := range whoisLifecycleIndicators {
+		if strings.Contains(lower, indicator.fragment) {
+			return indicator.status, fmt.Sprintf("matched %q", indicator.fragment), nil
 		}
+	}
 
-		// Exit code 1 is often normal for available domains
-		if exitErr, ok := cmdErr.(*exec.ExitError); ok {
-			if exitErr.ExitCode() == 1 && len(output) > 0 {
-				// Had output but no clear indicators - assume taken to be safe
-				return false, nil
-			}
+	// Check for availability indicators
+	for _, indicator := range whoisAvailableIndicators {
+		if strings.Contains(output, indicator) {
+			return domain.StatusAvailable, fmt.Sprintf("matched %q", indicator), nil
 		}
+	}
 
-		// Other errors (command not found, etc.)
-		return false, fmt.Errorf("whois command failed: %w", cmdErr)
+	// Check for taken indicators
+	for _, indicator := range whoisTakenIndicators {
+		if strings.Contains(output, indicator) {
+			return domain.StatusTaken, fmt.Sprintf("matched %q", indicator), nil
+		}
 	}
 
 	// If we have output but couldn't determine status, assume taken to be safe
-	if len(output) > 0 {
-		return false, nil
+	if strings.TrimSpace(output) != "" {
+		return domain.StatusTaken, whoisAssumedTaken, nil
 	}
 
-	// No output and no error is unusual - return error
-	return false, fmt.Errorf("whois returned no output")
+	// No output is unusual - return error
+	return domain.StatusUnknown, "empty response", withCode(ErrParse, fmt.Errorf("whois returned no output"))
 }
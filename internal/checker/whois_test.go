@@ -1,7 +1,13 @@
 package checker
 
 import (
+	"bufio"
 	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -118,7 +124,7 @@ func TestWHOISCheckCanceledContext(t *testing.T) {
 	}
 }
 
-// TestWHOISCheckTimeout tests behavior when whois command times out
+// TestWHOISCheckTimeout tests behavior when the WHOIS query times out
 func TestWHOISCheckTimeout(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test in short mode")
@@ -187,3 +193,410 @@ func TestWHOISCheckVariousTLDs(t *testing.T) {
 		})
 	}
 }
+
+// startWHOISServer runs a local port-43 stand-in that answers each query with respond(query).
+func startWHOISServer(t *testing.T, respond func(query string) string) string {
+	t.Helper()
+	isolateUpstreams(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					return
+				}
+				io.WriteString(conn, respond(strings.TrimRight(line, "\r\n")))
+			}(conn)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// TestWHOISClientLocalServer exercises the native client against a local server
+func TestWHOISClientLocalServer(t *testing.T) {
+	registry := startWHOISServer(t, func(q string) string {
+		switch q {
+		case "taken.test":
+			return "Domain Name: TAKEN.TEST\r\nRegistry Domain ID: 123\r\n"
+		case "free.test":
+			return "No match for \"FREE.TEST\".\r\n"
+		case "odd.test":
+			return "Some unrecognized banner\r\n"
+		default:
+			return ""
+		}
+	})
+	client := &WHOISClient{Servers: map[string]string{"test": registry}}
+
+	tests := []struct {
+		name          string
+		domain        string
+		wantAvailable bool
+		wantErr       bool
+	}{
+		{"taken indicator", "taken.test", false, false},
+		{"available indicator", "free.test", true, false},
+		{"unknown output assumed taken", "odd.test", false, false},
+		{"empty output is an error", "empty.test", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := domain.Domain{Full: tt.domain, TLD: "test"}
+			resp, err := client.Lookup(context.Background(), d)
+			if err != nil {
+				t.Fatalf("Lookup() unexpected error: %v", err)
+			}
+
+			status, err := parseWHOISStatus(resp.Registry)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("parseWHOISStatus() expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseWHOISStatus() unexpected error: %v", err)
+			}
+			if available := status.Registrable(); available != tt.wantAvailable {
+				t.Errorf("available = %v, want %v", available, tt.wantAvailable)
+			}
+		})
+	}
+}
+
+// TestWHOISClientReferral verifies thin-registry referrals are followed
+func TestWHOISClientReferral(t *testing.T) {
+	registrar := startWHOISServer(t, func(q string) string {
+		return "Registrant Name: Example Owner\r\n"
+	})
+	registry := startWHOISServer(t, func(q string) string {
+		return "Domain Name: EXAMPLE.TEST\r\nRegistrar WHOIS Server: " + registrar + "\r\n"
+	})
+
+	d := domain.Domain{Full: "example.test", TLD: "test"}
+
+	client := &WHOISClient{Servers: map[string]string{"test": registry}}
+	resp, err := client.Lookup(context.Background(), d)
+	if err != nil {
+		t.Fatalf("Lookup() unexpected error: %v", err)
+	}
+	if !strings.Contains(resp.Registry, "Domain Name: EXAMPLE.TEST") || strings.Contains(resp.Registry, "Registrant Name") {
+		t.Errorf("Lookup() registry answer = %q, want the registry text only", resp.Registry)
+	}
+	if !strings.Contains(resp.Registrar, "Registrant Name: Example Owner") {
+		t.Errorf("Lookup() registrar answer = %q, want the registrar text", resp.Registrar)
+	}
+	if text := resp.Text(); !strings.HasPrefix(text, resp.Registry) || !strings.HasSuffix(text, resp.Registrar) {
+		t.Errorf("Text() = %q, want registry then registrar answer", text)
+	}
+
+	client = &WHOISClient{Servers: map[string]string{"test": registry}, DisableReferrals: true}
+	resp, err = client.Lookup(context.Background(), d)
+	if err != nil {
+		t.Fatalf("Lookup() unexpected error: %v", err)
+	}
+	if resp.Registrar != "" {
+		t.Error("Lookup() followed referral despite DisableReferrals")
+	}
+}
+
+// TestLookupWHOISRegistrarAnswer verifies only the registry's answer decides
+// the status while the registrar's fills in the registration details
+func TestLookupWHOISRegistrarAnswer(t *testing.T) {
+	registrar := startWHOISServer(t, func(q string) string {
+		return "Domain Name: EXAMPLE.TEST\r\n" +
+			"Registrar: Example Registrar, Inc.\r\n" +
+			"Registrar IANA ID: 9999\r\n" +
+			"Creation Date: 2001-02-03T04:05:06Z\r\n" +
+			"Registrar Registration Expiration Date: 2030-02-03T04:05:06.0Z\r\n" +
+			"Domain Status: clientTransferProhibited https://icann.org/epp#clientTransferProhibited\r\n" +
+			"Name Server: NS1.EXAMPLE.TEST\r\n" +
+			"Name Server: NS2.EXAMPLE.TEST\r\n" +
+			"DNSSEC: signedDelegation\r\n" +
+			"Names in redemption period can be restored; No match for \"other contacts\".\r\n" +
+			"Query rate limit exceeded for contact lookups.\r\n"
+	})
+	registry := startWHOISServer(t, func(q string) string {
+		return "Domain Name: EXAMPLE.TEST\r\nRegistry Domain ID: 1_DOMAIN\r\n" +
+			"Registrar WHOIS Server: " + registrar + "\r\nUpdated Date: 2024-05-06T07:08:09Z\r\n"
+	})
+	client := &WHOISClient{Servers: map[string]string{"test": registry}}
+
+	status, reg, signal, err := lookupWHOIS(context.Background(), client, domain.Domain{Full: "example.test", TLD: "test"})
+	if err != nil {
+		t.Fatalf("lookupWHOIS() error = %v", err)
+	}
+	if status != domain.StatusTaken || signal != `matched "Registry Domain ID:"` {
+		t.Errorf("lookupWHOIS() = %v (%s), want taken from the registry answer", status, signal)
+	}
+	if reg == nil {
+		t.Fatal("lookupWHOIS() registration = nil")
+	}
+	if reg.Registrar != "Example Registrar, Inc." || reg.RegistrarIANAID != "9999" {
+		t.Errorf("registrar = %q (%q), want the registrar answer's", reg.Registrar, reg.RegistrarIANAID)
+	}
+	if want := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC); !reg.Created.Equal(want) {
+		t.Errorf("Created = %v, want %v", reg.Created, want)
+	}
+	if want := time.Date(2030, 2, 3, 4, 5, 6, 0, time.UTC); !reg.Expires.Equal(want) {
+		t.Errorf("Expires = %v, want %v", reg.Expires, want)
+	}
+	if want := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC); !reg.Updated.Equal(want) {
+		t.Errorf("Updated = %v, want %v from the registry answer", reg.Updated, want)
+	}
+	if len(reg.Nameservers) != 2 || reg.Nameservers[0] != "ns1.example.test" || reg.Nameservers[1] != "ns2.example.test" {
+		t.Errorf("Nameservers = %v", reg.Nameservers)
+	}
+	if len(reg.Statuses) != 1 || reg.Statuses[0] != "clientTransferProhibited" || !reg.DNSSEC {
+		t.Errorf("Statuses = %v, DNSSEC = %v", reg.Statuses, reg.DNSSEC)
+	}
+
+	// Available names carry no registration
+	free := startWHOISServer(t, func(q string) string { return "No match for \"FREE.TEST\".\r\n" })
+	client = &WHOISClient{Servers: map[string]string{"test": free}}
+	status, reg, _, err = lookupWHOIS(context.Background(), client, domain.Domain{Full: "free.test", TLD: "test"})
+	if err != nil || status != domain.StatusAvailable || reg != nil {
+		t.Errorf("lookupWHOIS(free.test) = %v, %+v, %v, want available without registration", status, reg, err)
+	}
+}
+
+// TestWHOISClientDiscovery verifies servers are discovered via the IANA server and cached
+func TestWHOISClientDiscovery(t *testing.T) {
+	registry := startWHOISServer(t, func(q string) string {
+		return "No match for \"" + strings.ToUpper(q) + "\".\r\n"
+	})
+	var ianaQueries int32
+	iana := startWHOISServer(t, func(q string) string {
+		atomic.AddInt32(&ianaQueries, 1)
+		if q != "test" {
+			return "% This query returned 0 objects.\r\n"
+		}
+		return "domain:       TEST\r\nwhois:        " + registry + "\r\n"
+	})
+
+	client := &WHOISClient{IANAServer: iana}
+
+	for i := 0; i < 2; i++ {
+		server, err := client.ServerFor(context.Background(), "test")
+		if err != nil {
+			t.Fatalf("ServerFor() unexpected error: %v", err)
+		}
+		if server != registry {
+			t.Errorf("ServerFor() = %q, want %q", server, registry)
+		}
+	}
+	if n := atomic.LoadInt32(&ianaQueries); n != 1 {
+		t.Errorf("IANA server queried %d times, want 1 (discovery should be cached)", n)
+	}
+
+	if _, err := client.ServerFor(context.Background(), "nowhois"); err == nil {
+		t.Error("ServerFor() expected error for TLD without WHOIS server")
+	}
+
+	// Public suffixes are discovered through their TLD
+	if server, err := client.ServerFor(context.Background(), "co.test"); err != nil || server != registry {
+		t.Errorf("ServerFor(co.test) = %q, %v, want %q", server, err, registry)
+	}
+	if n := atomic.LoadInt32(&ianaQueries); n != 2 {
+		t.Errorf("IANA server queried %d times, want 2 (nowhois only)", n)
+	}
+}
+
+// TestWHOISClientRegistryServer verifies the TLD registry's WHOIS server is
+// used without asking IANA.
+func TestWHOISClientRegistryServer(t *testing.T) {
+	var ianaQueries int32
+	iana := startWHOISServer(t, func(q string) string {
+		atomic.AddInt32(&ianaQueries, 1)
+		return "whois:        iana.example\r\n"
+	})
+	registry, err := domain.ParseTLDRegistry([]byte(`{"tlds": [{"tld": "test", "whois": "whois.nic.test"}, {"tld": "other"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain.SetTLDRegistry(registry)
+	defer domain.SetTLDRegistry(nil)
+
+	client := &WHOISClient{IANAServer: iana, Servers: map[string]string{"co.test": "co.example:43"}}
+	tests := []struct {
+		zone string
+		want string
+	}{
+		{"test", "whois.nic.test"},
+		{"sub.test", "whois.nic.test"},
+		{"co.test", "co.example:43"},
+		{"other", "iana.example"},
+	}
+	for _, tt := range tests {
+		if got, err := client.ServerFor(context.Background(), tt.zone); err != nil || got != tt.want {
+			t.Errorf("ServerFor(%q) = %q, %v, want %q", tt.zone, got, err, tt.want)
+		}
+	}
+	if n := atomic.LoadInt32(&ianaQueries); n != 1 {
+		t.Errorf("IANA server queried %d times, want 1 (other only)", n)
+	}
+}
+
+// TestWHOISClientSuffixServers verifies overrides match the longest public suffix
+func TestWHOISClientSuffixServers(t *testing.T) {
+	client := &WHOISClient{Servers: map[string]string{"test": "tld.example:43", "co.test": "co.example:43"}}
+	tests := map[string]string{
+		"test":        "tld.example:43",
+		"co.test":     "co.example:43",
+		"sub.co.test": "co.example:43",
+		"org.test":    "tld.example:43",
+	}
+	for zone, want := range tests {
+		if got, err := client.ServerFor(context.Background(), zone); err != nil || got != want {
+			t.Errorf("ServerFor(%q) = %q, %v, want %q", zone, got, err, want)
+		}
+	}
+}
+
+// TestWHOISClientLimits verifies the response size limit and read timeout
+func TestWHOISClientLimits(t *testing.T) {
+	big := startWHOISServer(t, func(q string) string {
+		return strings.Repeat("x", 10000)
+	})
+	client := &WHOISClient{MaxResponseSize: 100}
+	output, err := client.Query(context.Background(), big, "example.test")
+	if err != nil {
+		t.Fatalf("Query() unexpected error: %v", err)
+	}
+	if len(output) != 100 {
+		t.Errorf("Query() returned %d bytes, want 100", len(output))
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		// Accept and never answer
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(2 * time.Second)
+		}
+	}()
+
+	client = &WHOISClient{ReadTimeout: 50 * time.Millisecond}
+	start := time.Now()
+	_, err = client.Query(context.Background(), ln.Addr().String(), "example.test")
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("Query() err = %v, want timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Query() took %v, read timeout not applied", elapsed)
+	}
+
+	if _, err := client.Query(context.Background(), big, "a.test\r\nb.test"); err == nil {
+		t.Error("Query() should reject queries containing line breaks")
+	}
+}
+
+func TestParseWHOISStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   domain.Status
+	}{
+		{
+			name:   "redemption period EPP status",
+			output: "Domain Name: OLD.COM\nDomain Status: redemptionPeriod https://icann.org/epp#redemptionPeriod\nDomain Status: pendingDelete https://icann.org/epp#pendingDelete\n",
+			want:   domain.StatusRedemptionPeriod,
+		},
+		{
+			name:   "pending delete EPP status",
+			output: "Domain Name: OLD.COM\nDomain Status: pendingDelete https://icann.org/epp#pendingDelete\n",
+			want:   domain.StatusPendingDelete,
+		},
+		{
+			name:   "reserved name",
+			output: "Domain not found.\nStatus: RESERVED\n",
+			want:   domain.StatusReserved,
+		},
+		{
+			name:   "blocked name",
+			output: "Status: BLOCKED\n",
+			want:   domain.StatusBlocked,
+		},
+		{
+			name:   "premium name",
+			output: "The domain PREMIUM.TEST is a premium domain\n",
+			want:   domain.StatusPremium,
+		},
+		{
+			name:   "reserved in disclaimer does not count",
+			output: "Domain Name: EXAMPLE.COM\nRegistrar: Example\nAll rights reserved.\n",
+			want:   domain.StatusTaken,
+		},
+		{
+			name:   "plain available",
+			output: "No match for \"FREE.COM\".\n",
+			want:   domain.StatusAvailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWHOISStatus(tt.output)
+			if err != nil {
+				t.Fatalf("parseWHOISStatus() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseWHOISStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMatchWHOISStatusSignal verifies the matched indicator is reported
+func TestMatchWHOISStatusSignal(t *testing.T) {
+	tests := []struct {
+		output     string
+		wantSignal string
+	}{
+		{"Domain not found.\n", `matched "Domain not found"`},
+		{"Domain Status: pendingDelete\n", `matched "pendingdelete"`},
+		{"Something unexpected\n", whoisAssumedTaken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantSignal, func(t *testing.T) {
+			_, signal, err := matchWHOISStatus(tt.output)
+			if err != nil {
+				t.Fatalf("matchWHOISStatus() unexpected error: %v", err)
+			}
+			if signal != tt.wantSignal {
+				t.Errorf("matchWHOISStatus() signal = %q, want %q", signal, tt.wantSignal)
+			}
+		})
+	}
+}
+
+// TestMatchWHOISStatusRateLimited verifies throttling notices are not read as "taken"
+func TestMatchWHOISStatusRateLimited(t *testing.T) {
+	output := "% Query rate limit exceeded. Please try again later.\n"
+	status, _, err := matchWHOISStatus(output)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("matchWHOISStatus() err = %v, want ErrRateLimited", err)
+	}
+	if status != domain.StatusUnknown {
+		t.Errorf("matchWHOISStatus() status = %v, want unknown", status)
+	}
+}
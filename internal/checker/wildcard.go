@@ -0,0 +1,167 @@
+package checker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// wildcardTTL is how long a TLD's wildcard probe result is trusted.
+	wildcardTTL = 24 * time.Hour
+
+	// wildcardProbeTimeout bounds a TLD's wildcard probe. It is separate from
+	// the DNS pre-filter's timeout, so a slow probe doesn't eat the budget of
+	// the domain's own lookups.
+	wildcardProbeTimeout = 5 * time.Second
+)
+
+// dnsResolver is the subset of *net.Resolver used by the DNS pre-filter.
+type dnsResolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupNS(ctx context.Context, name string) ([]*net.NS, error)
+}
+
+// wildcardTypes records which record types a TLD synthesizes for any name.
+type wildcardTypes struct {
+	IP bool
+	MX bool
+	NS bool
+}
+
+// any reports whether the TLD wildcards at least one record type.
+func (w wildcardTypes) any() bool {
+	return w.IP || w.MX || w.NS
+}
+
+// String lists the wildcarded types (e.g. "A/AAAA, MX").
+func (w wildcardTypes) String() string {
+	var types []string
+	if w.IP {
+		types = append(types, "A/AAAA")
+	}
+	if w.MX {
+		types = append(types, "MX")
+	}
+	if w.NS {
+		types = append(types, "NS")
+	}
+	return strings.Join(types, ", ")
+}
+
+// wildcardEntry is a cached probe result.
+type wildcardEntry struct {
+	types   wildcardTypes
+	expires time.Time
+}
+
+// wildcardDetector finds TLDs whose registry publishes wildcard records, so
+// that every name under them resolves and DNS records prove nothing.
+//
+// The first check of a TLD looks up a random, certainly unregistered label
+// (e.g. "dc-3f9a1c0e7b2d4a66.tld"); the record types that answer are cached
+// for wildcardTTL. Probes that fail with anything other than "no such host"
+// are not cached, so a flaky resolver doesn't mark a TLD as clean for a day.
+//
+// Checks of names under the same TLD that arrive while it is being probed
+// wait for that probe instead of starting their own. A probe is bounded by
+// its own timeout and the latest deadline of the checks waiting for it.
+type wildcardDetector struct {
+	ttl     time.Duration
+	timeout time.Duration
+	flights flightGroup
+
+	mu      sync.Mutex
+	entries map[string]wildcardEntry
+}
+
+// newWildcardDetector creates a detector caching probe results for ttl.
+func newWildcardDetector(ttl time.Duration) *wildcardDetector {
+	return &wildcardDetector{ttl: ttl, timeout: wildcardProbeTimeout, entries: make(map[string]wildcardEntry)}
+}
+
+// wildcards is the detector used by the DNS pre-filter.
+var wildcards = newWildcardDetector(wildcardTTL)
+
+// types returns the wildcarded record types for tld, probing it if needed.
+// If ctx ends before the probe does, no types are reported.
+func (w *wildcardDetector) types(ctx context.Context, r dnsResolver, tld string) wildcardTypes {
+	tld = strings.ToLower(tld)
+
+	if types, ok := w.cached(tld); ok {
+		return types
+	}
+	v, _ := w.flights.share(ctx, tld, func(ctx context.Context) (interface{}, error) {
+		// A probe that ended while this one was being set up may have cached it
+		if types, ok := w.cached(tld); ok {
+			return types, nil
+		}
+		ctx, cancel := context.WithTimeout(ctx, w.timeout)
+		defer cancel()
+		return w.probe(ctx, r, tld), nil
+	})
+	types, _ := v.(wildcardTypes)
+	return types
+}
+
+// cached returns the unexpired probe result for tld, if any.
+func (w *wildcardDetector) cached(tld string) (wildcardTypes, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	entry, ok := w.entries[tld]
+	if !ok || !time.Now().Before(entry.expires) {
+		return wildcardTypes{}, false
+	}
+	return entry.types, true
+}
+
+// probe looks up a random label under tld and caches the record types that
+// answer, unless the lookups were inconclusive.
+func (w *wildcardDetector) probe(ctx context.Context, r dnsResolver, tld string) wildcardTypes {
+	probe := randomLabel() + "." + tld
+	var (
+		types      wildcardTypes
+		conclusive = true
+	)
+
+	// answered reports whether a lookup returned data, and notes inconclusive failures
+	answered := func(n int, err error) bool {
+		if err == nil {
+			return n > 0
+		}
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			conclusive = false
+		}
+		return false
+	}
+
+	ips, err := r.LookupIP(ctx, "ip", probe)
+	types.IP = answered(len(ips), err)
+	mx, err := r.LookupMX(ctx, probe)
+	types.MX = answered(len(mx), err)
+	ns, err := r.LookupNS(ctx, probe)
+	types.NS = answered(len(ns), err)
+
+	// A positive answer is conclusive even if another lookup failed
+	if conclusive || types.any() {
+		w.mu.Lock()
+		w.entries[tld] = wildcardEntry{types: types, expires: time.Now().Add(w.ttl)}
+		w.mu.Unlock()
+	}
+
+	return types
+}
+
+// randomLabel returns a label that is practically guaranteed not to be registered.
+func randomLabel() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return "dc-" + hex.EncodeToString(b[:])
+}
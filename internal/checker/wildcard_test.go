@@ -0,0 +1,221 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+// fakeResolver answers from fixed record sets. Names under a TLD listed in
+// wildcardIP resolve to an address regardless of the label.
+type fakeResolver struct {
+	ips        map[string]bool
+	mx         map[string]bool
+	wildcardIP map[string]bool
+	fail       error // returned for every lookup when set
+	lookups    int32
+}
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (f *fakeResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	atomic.AddInt32(&f.lookups, 1)
+	if f.fail != nil {
+		return nil, f.fail
+	}
+	tld := host[strings.LastIndex(host, ".")+1:]
+	if f.ips[host] || f.wildcardIP[tld] {
+		return []net.IP{net.ParseIP("192.0.2.1")}, nil
+	}
+	return nil, notFound(host)
+}
+
+func (f *fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	atomic.AddInt32(&f.lookups, 1)
+	if f.fail != nil {
+		return nil, f.fail
+	}
+	if f.mx[name] {
+		return []*net.MX{{Host: "mx." + name, Pref: 10}}, nil
+	}
+	return nil, notFound(name)
+}
+
+func (f *fakeResolver) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	atomic.AddInt32(&f.lookups, 1)
+	if f.fail != nil {
+		return nil, f.fail
+	}
+	return nil, notFound(name)
+}
+
+func TestProbeDNSWildcard(t *testing.T) {
+	r := &fakeResolver{
+		ips:        map[string]bool{"taken.com": true},
+		mx:         map[string]bool{"mail.wild": true},
+		wildcardIP: map[string]bool{"wild": true},
+	}
+
+	tests := []struct {
+		name       string
+		domain     domain.Domain
+		wantFound  bool
+		wantSignal string
+	}{
+		{"A record on normal TLD", domain.Domain{Full: "taken.com", TLD: "com"}, true, "A/AAAA records"},
+		{"no records on normal TLD", domain.Domain{Full: "free.com", TLD: "com"}, false, "no A/AAAA/MX/NS records"},
+		{"wildcard A ignored", domain.Domain{Full: "free.wild", TLD: "wild"}, false, "no trusted records (wildcard TLD, ignored A/AAAA)"},
+		{"MX still trusted on wildcard TLD", domain.Domain{Full: "mail.wild", TLD: "wild"}, true, "MX records"},
+	}
+
+	w := newWildcardDetector(time.Hour)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			found, signal, err := probeDNS(ctx, r, w.types(ctx, r, tt.domain.Zone()), tt.domain)
+			if err != nil {
+				t.Fatalf("probeDNS() error = %v", err)
+			}
+			if found != tt.wantFound || signal != tt.wantSignal {
+				t.Errorf("probeDNS() = %v, %q; want %v, %q", found, signal, tt.wantFound, tt.wantSignal)
+			}
+		})
+	}
+}
+
+// TestWildcardDetectorCache verifies each TLD is probed once and failures are not cached
+func TestWildcardDetectorCache(t *testing.T) {
+	r := &fakeResolver{wildcardIP: map[string]bool{"wild": true}}
+	w := newWildcardDetector(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if got := w.types(context.Background(), r, "WILD"); !got.IP || got.MX || got.NS {
+			t.Fatalf("types(wild) = %+v, want IP only", got)
+		}
+	}
+	if n := atomic.LoadInt32(&r.lookups); n != 3 {
+		t.Errorf("resolver called %d times, want 3 (one probe of each type)", n)
+	}
+
+	failing := &fakeResolver{fail: errors.New("i/o timeout")}
+	w.types(context.Background(), failing, "flaky")
+	w.types(context.Background(), failing, "flaky")
+	if n := atomic.LoadInt32(&failing.lookups); n != 6 {
+		t.Errorf("failing resolver called %d times, want 6 (inconclusive probes are retried)", n)
+	}
+}
+
+func TestWildcardDetectorExpiry(t *testing.T) {
+	r := &fakeResolver{}
+	w := newWildcardDetector(time.Nanosecond)
+
+	w.types(context.Background(), r, "com")
+	time.Sleep(time.Millisecond)
+	w.types(context.Background(), r, "com")
+	if n := atomic.LoadInt32(&r.lookups); n != 6 {
+		t.Errorf("resolver called %d times, want 6 (expired entry re-probed)", n)
+	}
+}
+
+// TestProbeDNSLookupError verifies resolver failures are reported, not hidden as "no records"
+func TestProbeDNSLookupError(t *testing.T) {
+	r := &fakeResolver{fail: errors.New("server misbehaving")}
+	found, _, err := probeDNS(context.Background(), r, wildcardTypes{}, domain.Domain{Full: "example.com", TLD: "com"})
+	if found || err == nil {
+		t.Errorf("probeDNS() = %v, %v; want not found with error", found, err)
+	}
+}
+
+// slowResolver blocks every lookup until release is closed (or its context
+// ends), recording the deadline of the lookups' contexts.
+type slowResolver struct {
+	fakeResolver
+	release  chan struct{}
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+func (s *slowResolver) wait(ctx context.Context, name string) error {
+	s.mu.Lock()
+	s.deadline, _ = ctx.Deadline()
+	s.mu.Unlock()
+	select {
+	case <-s.release:
+		return notFound(name)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *slowResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	atomic.AddInt32(&s.lookups, 1)
+	return nil, s.wait(ctx, host)
+}
+
+func (s *slowResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	atomic.AddInt32(&s.lookups, 1)
+	return nil, s.wait(ctx, name)
+}
+
+func (s *slowResolver) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	atomic.AddInt32(&s.lookups, 1)
+	return nil, s.wait(ctx, name)
+}
+
+// TestWildcardDetectorCoalesce verifies concurrent checks under one TLD share
+// a single probe
+func TestWildcardDetectorCoalesce(t *testing.T) {
+	r := &slowResolver{release: make(chan struct{})}
+	w := newWildcardDetector(time.Hour)
+
+	const callers = 5
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.types(context.Background(), r, "com")
+		}()
+	}
+	waitForWaiters(t, &w.flights, "com", callers)
+	close(r.release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&r.lookups); n != 3 {
+		t.Errorf("resolver called %d times, want 3 (one probe of each type)", n)
+	}
+}
+
+// TestWildcardDetectorTimeout verifies the probe is bounded by its own
+// timeout rather than only by its callers
+func TestWildcardDetectorTimeout(t *testing.T) {
+	r := &slowResolver{release: make(chan struct{})}
+	w := newWildcardDetector(time.Hour)
+	w.timeout = 20 * time.Millisecond
+
+	start := time.Now()
+	if got := w.types(context.Background(), r, "com"); got.any() {
+		t.Errorf("types(com) = %+v after timeout, want none", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("probe took %v, want it cut off by its timeout", elapsed)
+	}
+	r.mu.Lock()
+	deadline := r.deadline
+	r.mu.Unlock()
+	if deadline.IsZero() || deadline.After(start.Add(time.Second)) {
+		t.Errorf("probe lookup deadline = %v, want about %v", deadline, start.Add(w.timeout))
+	}
+	if _, ok := w.cached("com"); ok {
+		t.Error("timed out probe was cached")
+	}
+}
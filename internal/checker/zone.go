@@ -0,0 +1,770 @@
+package checker
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+const (
+	// zoneIndexExt is the file extension of per-zone index files
+	zoneIndexExt = ".zidx"
+
+	// zoneIndexFormat is the version written to (and required in) index headers
+	zoneIndexFormat = 1
+
+	// zoneIndexBlock is the number of names per block of the in-memory sparse
+	// index; a lookup reads one block from disk
+	zoneIndexBlock = 64
+
+	// maxZoneLine bounds a single (joined) line of a zone file
+	maxZoneLine = 1 << 20
+)
+
+// ErrZoneNotIndexed is returned by ZoneIndex.Delegated for names in zones
+// that have no index.
+var ErrZoneNotIndexed = errors.New("zone not indexed")
+
+// ZoneInfo describes one indexed zone.
+type ZoneInfo struct {
+	// Zone is the zone's apex without trailing dot (e.g. "com")
+	Zone string `json:"zone"`
+
+	// Serial is the SOA serial of the ingested zone file (0 when it had no SOA)
+	Serial uint32 `json:"serial,omitempty"`
+
+	// Names is the number of delegated names in the index
+	Names int `json:"names"`
+
+	// Built is when the index was written
+	Built time.Time `json:"built"`
+}
+
+// zoneHeader is the first line of an index file.
+type zoneHeader struct {
+	Format int `json:"format"`
+	ZoneInfo
+}
+
+// BuildZoneIndex ingests zone files in RFC 1035 master file format (such as
+// the ones ICANN's CZDS distributes, plain or gzip-compressed) and writes an
+// index of their delegated names to dir, one "<zone>.zidx" file per zone.
+//
+// A name is delegated when it has NS records and is exactly one label below
+// the zone's apex (the owner of its SOA record, else $ORIGIN, else the name's
+// TLD); glue and the apex's own records are ignored. Files of the
+// same zone are merged, and an existing index of a zone is replaced.
+//
+// The index holds the sorted, deduplicated labels (e.g. "example" for
+// example.com), so it is a fraction of the zone file's size. Labels are
+// sorted externally: they are buffered in chunks of zoneSortChunk, spilled to
+// sorted run files in a temporary directory under dir and merged into the
+// index, so memory use does not grow with the zone (the .com zone holds over
+// 150 million names). dir needs free space for about twice the index size.
+func BuildZoneIndex(dir string, paths ...string) ([]ZoneInfo, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("zone index: no zone files")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("zone index: %w", err)
+	}
+	tmp, err := os.MkdirTemp(dir, ".build-*")
+	if err != nil {
+		return nil, fmt.Errorf("zone index: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	b := &zoneBuilder{tmp: tmp, zones: make(map[string]*zoneNames)}
+	for _, path := range paths {
+		if err := readZoneFile(path, b); err != nil {
+			return nil, fmt.Errorf("zone index: %s: %w", path, err)
+		}
+	}
+
+	infos := make([]ZoneInfo, 0, len(b.zones))
+	built := time.Now().UTC().Truncate(time.Second)
+	for apex, z := range b.zones {
+		info := ZoneInfo{Zone: apex, Serial: z.serial, Built: built}
+		if err := b.writeIndex(filepath.Join(dir, apex+zoneIndexExt), &info, z); err != nil {
+			return nil, fmt.Errorf("zone index: %s: %w", apex, err)
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Zone < infos[j].Zone })
+	return infos, nil
+}
+
+// External sort parameters of BuildZoneIndex.
+var (
+	// zoneSortChunk is the number of labels buffered in memory, across all
+	// zones, before they are spilled to run files (about 100 MB)
+	zoneSortChunk = 1 << 22
+
+	// zoneMergeFanIn bounds the run files merged at once, and so the open files
+	zoneMergeFanIn = 64
+)
+
+// zoneHeaderSize is the space reserved for the header line of an index being
+// written: the name count is only known once the labels are. The JSON header
+// is padded with spaces, which readers ignore.
+const zoneHeaderSize = 512
+
+// zoneBuilder collects the delegated labels of every zone being built.
+type zoneBuilder struct {
+	tmp      string // directory of the run files
+	zones    map[string]*zoneNames
+	buffered int // labels in memory, across zones
+}
+
+// zoneNames collects the delegated labels of one zone while building.
+type zoneNames struct {
+	serial uint32
+	labels []string // not yet spilled
+	runs   []string // sorted, deduplicated run files
+}
+
+// zone returns the names collected for apex, creating them on first use.
+func (b *zoneBuilder) zone(apex string) *zoneNames {
+	z, ok := b.zones[apex]
+	if !ok {
+		z = &zoneNames{}
+		b.zones[apex] = z
+	}
+	return z
+}
+
+// add records a delegated label, spilling every zone's labels once the
+// buffer is full.
+func (b *zoneBuilder) add(apex, label string) error {
+	z := b.zone(apex)
+	// Clone: the label would otherwise pin the whole line in memory
+	z.labels = append(z.labels, strings.Clone(label))
+	b.buffered++
+	if b.buffered < zoneSortChunk {
+		return nil
+	}
+	for apex, z := range b.zones {
+		if err := b.spill(apex, z); err != nil {
+			return err
+		}
+	}
+	b.buffered = 0
+	return nil
+}
+
+// spill sorts the buffered labels of a zone into a new run file.
+func (b *zoneBuilder) spill(apex string, z *zoneNames) error {
+	if len(z.labels) == 0 {
+		return nil
+	}
+	sort.Strings(z.labels)
+	path, err := b.writeRun(apex, func(emit func(string) error) error {
+		return mergeLabels([]*labelRun{{mem: z.labels}}, emit)
+	})
+	if err != nil {
+		return err
+	}
+	z.runs = append(z.runs, path)
+	clear(z.labels)
+	z.labels = z.labels[:0]
+	return nil
+}
+
+// writeRun writes the labels produced by fill to a new run file.
+func (b *zoneBuilder) writeRun(apex string, fill func(emit func(string) error) error) (string, error) {
+	f, err := os.CreateTemp(b.tmp, apex+"-*.run")
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriterSize(f, 1<<16)
+	err = fill(func(label string) error {
+		w.WriteString(label)
+		return w.WriteByte('\n')
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return f.Name(), err
+}
+
+// writeIndex merges a zone's runs and buffered labels into its index file,
+// written atomically (temp file + rename), and sets info.Names.
+func (b *zoneBuilder) writeIndex(path string, info *ZoneInfo, z *zoneNames) error {
+	// Merge in passes while there are too many runs to open at once
+	for len(z.runs) > zoneMergeFanIn {
+		var merged []string
+		for start := 0; start < len(z.runs); start += zoneMergeFanIn {
+			group := z.runs[start:min(start+zoneMergeFanIn, len(z.runs))]
+			run, err := b.writeRun(info.Zone, func(emit func(string) error) error {
+				return mergeRunFiles(group, nil, emit)
+			})
+			if err != nil {
+				return err
+			}
+			for _, p := range group {
+				os.Remove(p)
+			}
+			merged = append(merged, run)
+		}
+		z.runs = merged
+	}
+	sort.Strings(z.labels)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriterSize(tmp, 1<<16)
+	w.Write(bytes.Repeat([]byte{' '}, zoneHeaderSize))
+	names := 0
+	err = mergeRunFiles(z.runs, z.labels, func(label string) error {
+		names++
+		w.WriteString(label)
+		return w.WriteByte('\n')
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		info.Names = names
+		err = writeZoneHeader(tmp, *info)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// writeZoneHeader writes the padded header line at the start of an index.
+func writeZoneHeader(f *os.File, info ZoneInfo) error {
+	header, err := json.Marshal(zoneHeader{Format: zoneIndexFormat, ZoneInfo: info})
+	if err != nil {
+		return err
+	}
+	if len(header) >= zoneHeaderSize {
+		return fmt.Errorf("index header too long")
+	}
+	line := append(header, bytes.Repeat([]byte{' '}, zoneHeaderSize-1-len(header))...)
+	_, err = f.WriteAt(append(line, '\n'), 0)
+	return err
+}
+
+// labelRun is one sorted input of a merge: a run file or labels in memory.
+type labelRun struct {
+	scanner *bufio.Scanner // nil for labels in memory
+	mem     []string
+	head    string
+}
+
+// advance moves to the next label, reporting false at the end of the run.
+func (r *labelRun) advance() (bool, error) {
+	if r.scanner == nil {
+		if len(r.mem) == 0 {
+			return false, nil
+		}
+		r.head, r.mem = r.mem[0], r.mem[1:]
+		return true, nil
+	}
+	if r.scanner.Scan() {
+		r.head = r.scanner.Text()
+		return true, nil
+	}
+	return false, r.scanner.Err()
+}
+
+// labelHeap orders runs by their current label (container/heap).
+type labelHeap []*labelRun
+
+func (h labelHeap) Len() int            { return len(h) }
+func (h labelHeap) Less(i, j int) bool  { return h[i].head < h[j].head }
+func (h labelHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *labelHeap) Push(x interface{}) { *h = append(*h, x.(*labelRun)) }
+func (h *labelHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// mergeLabels emits the labels of sorted runs in order, without duplicates.
+func mergeLabels(runs []*labelRun, emit func(string) error) error {
+	h := make(labelHeap, 0, len(runs))
+	for _, r := range runs {
+		ok, err := r.advance()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h = append(h, r)
+		}
+	}
+	heap.Init(&h)
+
+	last, first := "", true
+	for h.Len() > 0 {
+		r := h[0]
+		if first || r.head != last {
+			if err := emit(r.head); err != nil {
+				return err
+			}
+			last, first = r.head, false
+		}
+		ok, err := r.advance()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return nil
+}
+
+// mergeRunFiles merges run files and sorted labels in memory.
+func mergeRunFiles(paths []string, mem []string, emit func(string) error) error {
+	runs := make([]*labelRun, 0, len(paths)+1)
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(bufio.NewReaderSize(f, 1<<16))
+		runs = append(runs, &labelRun{scanner: scanner})
+	}
+	runs = append(runs, &labelRun{mem: mem})
+	return mergeLabels(runs, emit)
+}
+
+// readZoneFile parses one master file and adds its delegations to b.
+func readZoneFile(path string, b *zoneBuilder) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewReaderSize(f, 1<<16)
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	p := zoneParser{build: b}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxZoneLine)
+	var (
+		pending []string
+		depth   int
+		lineNo  int
+	)
+	for scanner.Scan() {
+		lineNo++
+		line := stripZoneComment(scanner.Text())
+
+		// Parentheses continue a record over several lines (e.g. SOA)
+		if depth == 0 && (line == "" || strings.TrimSpace(line) == "") {
+			continue
+		}
+		if depth == 0 {
+			pending = append(pending[:0], line)
+		} else {
+			pending = append(pending, line)
+		}
+		depth += strings.Count(line, "(") - strings.Count(line, ")")
+		if depth > 0 {
+			continue
+		}
+		depth = 0
+
+		joined := strings.Join(pending, " ")
+		joined = strings.NewReplacer("(", " ", ")", " ").Replace(joined)
+		if err := p.record(joined); err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if depth > 0 {
+		return fmt.Errorf("unbalanced parentheses at end of file")
+	}
+	return nil
+}
+
+// stripZoneComment removes a ";" comment outside quoted strings.
+func stripZoneComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// zoneParser tracks the state of a master file between records.
+type zoneParser struct {
+	build  *zoneBuilder
+	origin string // $ORIGIN, lowercase without trailing dot
+	apex   string // SOA owner (or origin / first TLD)
+	owner  string // owner of the previous record
+}
+
+// record handles one logical line (directive or resource record).
+func (p *zoneParser) record(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	switch strings.ToUpper(fields[0]) {
+	case "$ORIGIN":
+		if len(fields) < 2 {
+			return fmt.Errorf("$ORIGIN without name")
+		}
+		p.origin = p.absolute(fields[1])
+		return nil
+	case "$TTL":
+		return nil
+	case "$INCLUDE":
+		return fmt.Errorf("$INCLUDE is not supported")
+	}
+
+	// A record starting with blank space reuses the previous owner
+	owner := p.owner
+	if line[0] != ' ' && line[0] != '\t' {
+		owner = p.absolute(fields[0])
+		fields = fields[1:]
+	}
+	if owner == "" {
+		return fmt.Errorf("record without owner")
+	}
+	p.owner = owner
+
+	// Skip the optional TTL and class, in either order, up to the type
+	rrtype := ""
+	for len(fields) > 0 {
+		f := strings.ToUpper(fields[0])
+		fields = fields[1:]
+		if isZoneTTL(f) || f == "IN" || f == "CH" || f == "HS" || f == "CS" {
+			continue
+		}
+		rrtype = f
+		break
+	}
+	if rrtype == "" {
+		return fmt.Errorf("record for %s without type", owner)
+	}
+
+	switch rrtype {
+	case "SOA":
+		p.apex = owner
+		z := p.build.zone(owner)
+		// SOA rdata: mname rname serial refresh retry expire minimum
+		if len(fields) >= 3 {
+			if serial, err := strconv.ParseUint(fields[2], 10, 32); err == nil {
+				z.serial = uint32(serial)
+			}
+		}
+	case "NS":
+		apex := p.apex
+		if apex == "" {
+			apex = p.origin
+		}
+		if apex == "" {
+			apex = owner[strings.LastIndexByte(owner, '.')+1:]
+		}
+		if label, ok := strings.CutSuffix(owner, "."+apex); ok && label != "" && !strings.Contains(label, ".") {
+			return p.build.add(apex, label)
+		}
+	}
+	return nil
+}
+
+// absolute returns name lowercased and fully qualified against $ORIGIN,
+// without trailing dot.
+func (p *zoneParser) absolute(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case name == "@":
+		return p.origin
+	case strings.HasSuffix(name, "."):
+		return strings.TrimSuffix(name, ".")
+	case p.origin == "":
+		return name
+	default:
+		return name + "." + p.origin
+	}
+}
+
+// isZoneTTL reports whether a field is a TTL ("3600", "1h30m", "2D").
+func isZoneTTL(f string) bool {
+	if f == "" || f[0] < '0' || f[0] > '9' {
+		return false
+	}
+	for _, c := range f {
+		if (c < '0' || c > '9') && !strings.ContainsRune("SMHDW", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// ZoneIndex answers whether names are delegated from the index files written
+// by BuildZoneIndex, without network access.
+//
+// Each zone's file stays open; only the first label of every block of
+// zoneIndexBlock names is kept in memory, so a lookup is a binary search in
+// memory plus one small read. A ZoneIndex is safe for concurrent use.
+type ZoneIndex struct {
+	mu     sync.RWMutex
+	zones  map[string]*zoneFile
+	closed bool
+}
+
+// zoneFile is one open index file.
+type zoneFile struct {
+	info    ZoneInfo
+	file    *os.File
+	first   []string // first label of each block
+	offsets []int64  // file offset of each block, plus the end of the data
+}
+
+// OpenZoneIndex opens every index file in dir.
+func OpenZoneIndex(dir string) (*ZoneIndex, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+zoneIndexExt))
+	if err != nil {
+		return nil, fmt.Errorf("zone index: %w", err)
+	}
+
+	z := &ZoneIndex{zones: make(map[string]*zoneFile, len(paths))}
+	for _, path := range paths {
+		zf, err := openZoneFile(path)
+		if err != nil {
+			z.Close()
+			return nil, fmt.Errorf("zone index: %s: %w", path, err)
+		}
+		z.zones[zf.info.Zone] = zf
+	}
+	return z, nil
+}
+
+// openZoneFile reads an index file's header and builds its sparse index.
+func openZoneFile(path string) (*zoneFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReaderSize(f, 1<<16)
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	var header zoneHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if header.Format != zoneIndexFormat || header.Zone == "" {
+		f.Close()
+		return nil, fmt.Errorf("unsupported index format %d", header.Format)
+	}
+
+	zf := &zoneFile{info: header.ZoneInfo, file: f}
+	offset := int64(len(line))
+	names := 0
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if line[len(line)-1] != '\n' {
+				f.Close()
+				return nil, fmt.Errorf("truncated index")
+			}
+			if names%zoneIndexBlock == 0 {
+				zf.first = append(zf.first, string(line[:len(line)-1]))
+				zf.offsets = append(zf.offsets, offset)
+			}
+			offset += int64(len(line))
+			names++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	zf.offsets = append(zf.offsets, offset)
+	zf.info.Names = names
+	return zf, nil
+}
+
+// contains reports whether label is in the index.
+func (zf *zoneFile) contains(label string) (bool, error) {
+	// The last block whose first label is <= label
+	i := sort.Search(len(zf.first), func(i int) bool { return zf.first[i] > label }) - 1
+	if i < 0 {
+		return false, nil
+	}
+
+	block := make([]byte, zf.offsets[i+1]-zf.offsets[i])
+	if _, err := zf.file.ReadAt(block, zf.offsets[i]); err != nil {
+		return false, err
+	}
+	want := []byte(label)
+	for len(block) > 0 {
+		line, rest, _ := bytes.Cut(block, []byte{'\n'})
+		if bytes.Equal(line, want) {
+			return true, nil
+		}
+		block = rest
+	}
+	return false, nil
+}
+
+// Zones returns the indexed zones, sorted by name.
+func (z *ZoneIndex) Zones() []ZoneInfo {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	infos := make([]ZoneInfo, 0, len(z.zones))
+	for _, zf := range z.zones {
+		infos = append(infos, zf.info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Zone < infos[j].Zone })
+	return infos
+}
+
+// Supports reports whether zone (e.g. "com") is indexed.
+func (z *ZoneIndex) Supports(zone string) bool {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	_, ok := z.zones[strings.ToLower(zone)]
+	return ok
+}
+
+// Delegated reports whether the registrable name of a domain (e.g.
+// "example.com" for "www.example.com") is delegated in its zone. The zone is
+// the name's public suffix, the one Domain.Zone returns and Supports takes:
+// "example.co.uk" is looked up in the co.uk zone. The uk zone is never used
+// instead, as it holds the delegation of co.uk and not the names below it.
+// It returns ErrZoneNotIndexed when the zone has no index.
+func (z *ZoneIndex) Delegated(name string) (bool, error) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	zone := domain.PublicSuffix(name)
+	if len(name) <= len(zone) {
+		return false, fmt.Errorf("%w: %q is a public suffix", ErrInvalidDomain, name)
+	}
+	rest := name[:len(name)-len(zone)-1]
+	label := rest[strings.LastIndexByte(rest, '.')+1:]
+
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	if z.closed {
+		return false, fmt.Errorf("zone index closed")
+	}
+	zf, ok := z.zones[zone]
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrZoneNotIndexed, zone)
+	}
+	return zf.contains(label)
+}
+
+// Close closes the index files. The index must not be used afterwards.
+func (z *ZoneIndex) Close() error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.closed = true
+	var firstErr error
+	for _, zf := range z.zones {
+		if err := zf.file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// zoneSource adapts a ZoneIndex to the Source interface.
+type zoneSource struct {
+	index *ZoneIndex
+}
+
+// NewZoneSource returns a Source answering from zone files indexed with
+// BuildZoneIndex. It supports the TLDs with an index, reports taken when the
+// name is delegated and is undecided otherwise: names without nameservers
+// (on hold, redemption period, ...) are registered but absent from the zone.
+func NewZoneSource(index *ZoneIndex) Source {
+	return zoneSource{index: index}
+}
+
+func (zoneSource) Name() string { return "zone" }
+
+func (s zoneSource) Supports(zone string) bool {
+	return s.index.Supports(zone)
+}
+
+func (s zoneSource) Check(ctx context.Context, d domain.Domain) (Verdict, error) {
+	delegated, err := s.index.Delegated(d.Full)
+	if err != nil {
+		return Verdict{}, err
+	}
+	info := s.signalInfo(d.Zone())
+	if delegated {
+		return Verdict{Status: domain.StatusTaken, Signal: "delegated in zone " + info, Confidence: domain.ConfidenceHigh}, nil
+	}
+	return Verdict{Status: domain.StatusUnknown, Signal: "not in zone " + info}, nil
+}
+
+// signalInfo describes the zone snapshot a verdict is based on.
+func (s zoneSource) signalInfo(zone string) string {
+	s.index.mu.RLock()
+	defer s.index.mu.RUnlock()
+	zf, ok := s.index.zones[strings.ToLower(zone)]
+	if !ok {
+		return zone
+	}
+	if zf.info.Serial != 0 {
+		return fmt.Sprintf("%s (serial %d)", zf.info.Zone, zf.info.Serial)
+	}
+	return fmt.Sprintf("%s (built %s)", zf.info.Zone, zf.info.Built.Format(time.RFC3339))
+}
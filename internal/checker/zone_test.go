@@ -0,0 +1,341 @@
+package checker
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"domaincheck/internal/domain"
+)
+
+// writeZone writes a zone file to the test's temp dir, gzipped when the name ends in .gz.
+func writeZone(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if strings.HasSuffix(name, ".gz") {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		_, err = gz.Write([]byte(content))
+	} else {
+		_, err = f.Write([]byte(content))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// czdsZone is a zone in the fully qualified one-record-per-line CZDS format.
+const czdsZone = `com.	900	in	soa	a.gtld-servers.net. nstld.verisign-grs.com. 1760600000 1800 900 604800 86400
+com.	172800	in	ns	a.gtld-servers.net.
+example.com.	172800	in	ns	a.iana-servers.net.
+example.com.	172800	in	ns	b.iana-servers.net.
+Mixed-Case.COM.	172800	in	ns	ns1.mixed-case.com.
+ns1.mixed-case.com.	172800	in	a	192.0.2.53
+example.com.	86400	in	ds	370 13 2 BE74359954660069D5C63D200C39F5603827D7DD02B56F120EE9F3A86764247C
+deep.sub.com.	172800	in	ns	ns1.example.net.
+`
+
+// masterZone uses $ORIGIN, relative and blank owners, comments and a multi-line SOA.
+const masterZone = `$ORIGIN io.
+$TTL 3600
+@	IN	SOA	ns1.nic.io. hostmaster.nic.io. (
+		2026101601 ; serial
+		3600 900 1209600 300 )
+	IN	NS	ns1.nic.io.   ; the apex's own NS set
+startup		NS	ns1.dnshost.net.
+		NS	ns2.dnshost.net.
+glueonly	A	192.0.2.1 ; no NS: not delegated
+tool 300 IN NS ns1.tool.io.
+txt		TXT	"semicolon; inside"
+`
+
+func TestBuildZoneIndex(t *testing.T) {
+	dir := t.TempDir()
+	infos, err := BuildZoneIndex(dir,
+		writeZone(t, "com.zone", czdsZone),
+		writeZone(t, "io.zone.gz", masterZone),
+	)
+	if err != nil {
+		t.Fatalf("BuildZoneIndex() error = %v", err)
+	}
+	if len(infos) != 2 || infos[0].Zone != "com" || infos[1].Zone != "io" {
+		t.Fatalf("BuildZoneIndex() = %+v, want com and io", infos)
+	}
+	if infos[0].Names != 2 || infos[0].Serial != 1760600000 {
+		t.Errorf("com = %+v, want 2 names, serial 1760600000", infos[0])
+	}
+	if infos[1].Names != 2 || infos[1].Serial != 2026101601 {
+		t.Errorf("io = %+v, want 2 names, serial 2026101601", infos[1])
+	}
+
+	index, err := OpenZoneIndex(dir)
+	if err != nil {
+		t.Fatalf("OpenZoneIndex() error = %v", err)
+	}
+	defer index.Close()
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"example.com", true},
+		{"www.example.com", true},
+		{"mixed-case.com", true},
+		{"EXAMPLE.COM.", true},
+		{"sub.com", false},
+		{"gtld-servers.com", false},
+		{"startup.io", true},
+		{"tool.io", true},
+		{"glueonly.io", false},
+		{"txt.io", false},
+		{"aaa.com", false},
+		{"zzz.io", false},
+	}
+	for _, tt := range tests {
+		got, err := index.Delegated(tt.name)
+		if err != nil {
+			t.Errorf("Delegated(%q) error = %v", tt.name, err)
+		} else if got != tt.want {
+			t.Errorf("Delegated(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := index.Delegated("example.org"); !errors.Is(err, ErrZoneNotIndexed) {
+		t.Errorf("Delegated(example.org) error = %v, want ErrZoneNotIndexed", err)
+	}
+	if !index.Supports("COM") || index.Supports("org") {
+		t.Error("Supports() does not match the indexed zones")
+	}
+}
+
+// TestZoneIndexBlocks checks lookups across many blocks of the sparse index
+// and merging of several files of one zone.
+func TestZoneIndexBlocks(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < 1000; i++ {
+		w := &a
+		if i%3 == 0 {
+			w = &b
+		}
+		fmt.Fprintf(w, "name%04d.test. 3600 IN NS ns.example.net.\n", i*2)
+	}
+	dir := t.TempDir()
+	infos, err := BuildZoneIndex(dir, writeZone(t, "a.zone", a.String()), writeZone(t, "b.zone", b.String()+a.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Names != 1000 {
+		t.Fatalf("BuildZoneIndex() = %+v, want one zone with 1000 names", infos)
+	}
+
+	index, err := OpenZoneIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	for i := 0; i < 2000; i++ {
+		got, err := index.Delegated(fmt.Sprintf("name%04d.test", i))
+		if err != nil || got != (i%2 == 0) {
+			t.Fatalf("Delegated(name%04d.test) = %v, %v", i, got, err)
+		}
+	}
+}
+
+// TestZoneIndexSuffixZones verifies names under a multi-label public suffix
+// are looked up in the zone of that suffix, the zone Supports is asked about.
+func TestZoneIndexSuffixZones(t *testing.T) {
+	ukZone := writeZone(t, "uk.zone", "uk. 3600 IN SOA a. b. 7 1 1 1 1\nco.uk. 3600 IN NS ns.example.net.\nexample.uk. 3600 IN NS ns.example.net.\n")
+	coZone := writeZone(t, "co.uk.zone", "co.uk. 3600 IN SOA a. b. 9 1 1 1 1\nshop.co.uk. 3600 IN NS ns.example.net.\n")
+
+	t.Run("suffix indexed", func(t *testing.T) {
+		dir := t.TempDir()
+		if _, err := BuildZoneIndex(dir, ukZone, coZone); err != nil {
+			t.Fatal(err)
+		}
+		index, err := OpenZoneIndex(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer index.Close()
+
+		tests := []struct {
+			name string
+			want bool
+		}{
+			{"shop.co.uk", true},
+			{"www.shop.co.uk", true},
+			{"free.co.uk", false},
+			{"example.uk", true},
+			{"free.uk", false},
+		}
+		for _, tt := range tests {
+			d, err := domain.Normalize(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !index.Supports(d.Zone()) {
+				t.Errorf("Supports(%q) = false for %s", d.Zone(), tt.name)
+			}
+			if got, err := index.Delegated(tt.name); err != nil || got != tt.want {
+				t.Errorf("Delegated(%q) = %v, %v, want %v", tt.name, got, err, tt.want)
+			}
+		}
+		if _, err := index.Delegated("co.uk"); !errors.Is(err, ErrInvalidDomain) {
+			t.Errorf("Delegated(co.uk) error = %v, want ErrInvalidDomain", err)
+		}
+	})
+
+	t.Run("parent zone only", func(t *testing.T) {
+		// The uk zone delegates co.uk itself: it says nothing about shop.co.uk
+		dir := t.TempDir()
+		if _, err := BuildZoneIndex(dir, ukZone); err != nil {
+			t.Fatal(err)
+		}
+		index, err := OpenZoneIndex(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer index.Close()
+
+		if index.Supports("co.uk") {
+			t.Error("Supports(co.uk) = true with only uk indexed")
+		}
+		if _, err := index.Delegated("shop.co.uk"); !errors.Is(err, ErrZoneNotIndexed) {
+			t.Errorf("Delegated(shop.co.uk) error = %v, want ErrZoneNotIndexed", err)
+		}
+		if got, err := index.Delegated("example.uk"); err != nil || !got {
+			t.Errorf("Delegated(example.uk) = %v, %v, want true", got, err)
+		}
+	})
+}
+
+// TestBuildZoneIndexSpill forces the external sort to spill runs and merge
+// them in several passes.
+func TestBuildZoneIndexSpill(t *testing.T) {
+	defer func(chunk, fanIn int) { zoneSortChunk, zoneMergeFanIn = chunk, fanIn }(zoneSortChunk, zoneMergeFanIn)
+	zoneSortChunk, zoneMergeFanIn = 7, 3
+
+	var a, b strings.Builder
+	for i := 499; i >= 0; i-- {
+		fmt.Fprintf(&a, "name%03d.test. 3600 IN NS ns.example.net.\n", i)
+		fmt.Fprintf(&b, "name%03d.example. 3600 IN NS ns.example.net.\n", (i*7)%500)
+		if i%5 == 0 {
+			// Duplicates across runs
+			fmt.Fprintf(&b, "name%03d.test. 3600 IN NS ns.example.net.\n", i)
+		}
+	}
+	dir := t.TempDir()
+	infos, err := BuildZoneIndex(dir, writeZone(t, "a.zone", a.String()), writeZone(t, "b.zone", b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Names != 500 || infos[1].Names != 500 {
+		t.Fatalf("BuildZoneIndex() = %+v, want two zones with 500 names", infos)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("index dir holds %d entries, want the 2 index files", len(entries))
+	}
+
+	index, err := OpenZoneIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	for i := 0; i < 500; i++ {
+		for _, zone := range []string{"test", "example"} {
+			name := fmt.Sprintf("name%03d.%s", i, zone)
+			if got, err := index.Delegated(name); err != nil || !got {
+				t.Fatalf("Delegated(%s) = %v, %v", name, got, err)
+			}
+		}
+	}
+	if got, err := index.Delegated("name500.test"); err != nil || got {
+		t.Errorf("Delegated(name500.test) = %v, %v, want false", got, err)
+	}
+}
+
+func TestBuildZoneIndexErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		zone string
+	}{
+		{"include", "$INCLUDE other.zone\n"},
+		{"unbalanced", "test. IN SOA a. b. ( 1 2 3\n"},
+		{"no type", "example.test. 3600 IN\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BuildZoneIndex(t.TempDir(), writeZone(t, "bad.zone", tt.zone)); err == nil {
+				t.Error("BuildZoneIndex() error = nil")
+			}
+		})
+	}
+	if _, err := BuildZoneIndex(t.TempDir()); err == nil {
+		t.Error("BuildZoneIndex() without files error = nil")
+	}
+}
+
+func TestOpenZoneIndexInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad"+zoneIndexExt), []byte("not json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenZoneIndex(dir); err == nil {
+		t.Error("OpenZoneIndex() error = nil")
+	}
+}
+
+// TestCheckerZoneIndex verifies the zone source screens names before any upstream
+func TestCheckerZoneIndex(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := BuildZoneIndex(dir, writeZone(t, "test.zone", "zoned.test. 3600 IN NS ns1.example.net.\n")); err != nil {
+		t.Fatal(err)
+	}
+	index, err := OpenZoneIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+
+	u := startLocalUpstreams(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	c := New(append(u.options(), WithZoneIndex(index))...)
+
+	tests := []struct {
+		name         string
+		wantStatus   domain.Status
+		wantSource   string
+		wantAttempts int
+	}{
+		{"zoned.test", domain.StatusTaken, "zone", 1},
+		{"free.test", domain.StatusAvailable, "rdap", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := c.Check(context.Background(), domain.Domain{Full: tt.name, Name: strings.TrimSuffix(tt.name, ".test"), TLD: "test"})
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if result.Status != tt.wantStatus || result.Source != tt.wantSource || len(result.Attempts) != tt.wantAttempts {
+				t.Errorf("Check() = %s from %q after %d attempts, want %s from %q after %d",
+					result.Status, result.Source, len(result.Attempts), tt.wantStatus, tt.wantSource, tt.wantAttempts)
+			}
+			if result.Attempts[0].Source != "zone" {
+				t.Errorf("first attempt = %s, want zone", result.Attempts[0].Source)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+//go:build ignore
+
+// gen_tlds regenerates data/tlds.json from IANA's published data:
+//
+//   - https://data.iana.org/TLD/tlds-alpha-by-domain.txt: the delegated TLDs
+//     and the version of the root zone they were taken from
+//   - https://www.iana.org/domains/root/db: each TLD's type and operator
+//   - https://www.iana.org/domains/idn-tables: TLDs that registered IDN tables
+//   - whois.iana.org (port 43): each TLD's WHOIS server
+//   - https://data.iana.org/rdap/dns.json: each TLD's RDAP base URL
+//
+// Run it with "go generate ./internal/domain" or "make update-tlds".
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tldListURL   = "https://data.iana.org/TLD/tlds-alpha-by-domain.txt"
+	rootDBURL    = "https://www.iana.org/domains/root/db"
+	idnTablesURL = "https://www.iana.org/domains/idn-tables"
+	bootstrapURL = "https://data.iana.org/rdap/dns.json"
+	ianaWHOIS    = "whois.iana.org:43"
+)
+
+// tldInfo mirrors domain.TLDInfo (Unicode is derived when parsing).
+type tldInfo struct {
+	TLD      string `json:"tld"`
+	Type     string `json:"type"`
+	Operator string `json:"operator,omitempty"`
+	RDAP     string `json:"rdap,omitempty"`
+	WHOIS    string `json:"whois,omitempty"`
+	IDN      *bool  `json:"idn,omitempty"`
+}
+
+var client = &http.Client{Timeout: time.Minute}
+
+func main() {
+	out := flag.String("o", "data/tlds.json", "output file")
+	workers := flag.Int("whois-workers", 4, "concurrent whois.iana.org queries")
+	flag.Parse()
+
+	version, tlds, err := fetchTLDList()
+	if err != nil {
+		log.Fatal(err)
+	}
+	rootDB, err := fetchRootDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	idn, err := fetchIDNTLDs()
+	if err != nil {
+		log.Fatal(err)
+	}
+	rdap, err := fetchBootstrap()
+	if err != nil {
+		log.Fatal(err)
+	}
+	whois := fetchWHOISServers(tlds, *workers)
+
+	infos := make([]tldInfo, 0, len(tlds))
+	for _, tld := range tlds {
+		info, ok := rootDB[tld]
+		if !ok {
+			log.Fatalf("%s is delegated but missing from the root zone database", tld)
+		}
+		info.TLD = tld
+		info.RDAP = rdap[tld]
+		info.WHOIS = whois[tld]
+		if idn[tld] || strings.HasPrefix(tld, "xn--") {
+			yes := true
+			info.IDN = &yes
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].TLD < infos[j].TLD })
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "{\n  \"version\": %q,\n  \"tlds\": [\n", version)
+	for i, info := range infos {
+		line, err := marshal(info)
+		if err != nil {
+			log.Fatal(err)
+		}
+		buf.WriteString("    ")
+		buf.Write(line)
+		if i < len(infos)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("  ]\n}\n")
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d TLDs (version %s) to %s", len(infos), version, *out)
+}
+
+// marshal encodes v on one line without escaping "&" in operator names.
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
+
+func get(url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// fetchTLDList returns the root zone version and the delegated TLDs.
+func fetchTLDList() (string, []string, error) {
+	data, err := get(tldListURL)
+	if err != nil {
+		return "", nil, err
+	}
+	var version string
+	var tlds []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(strings.TrimPrefix(line, "#"))
+			// "# Version 2026101600, Last Updated Fri Oct 16 07:07:01 2026 UTC"
+			if len(fields) >= 2 && fields[0] == "Version" {
+				version = strings.TrimSuffix(fields[1], ",")
+			}
+			continue
+		}
+		if line != "" {
+			tlds = append(tlds, strings.ToLower(line))
+		}
+	}
+	if version == "" || len(tlds) == 0 {
+		return "", nil, fmt.Errorf("%s: no version or TLDs", tldListURL)
+	}
+	return version, tlds, scanner.Err()
+}
+
+// rootDBRow matches a row of the root zone database table: the TLD's page,
+// its type and its operator.
+var rootDBRow = regexp.MustCompile(`(?s)<a href="/domains/root/db/([^"/]+)\.html">.*?</td>\s*<td>([^<]*)</td>\s*<td>([^<]*)</td>`)
+
+// fetchRootDB returns the type and operator of every TLD in the root zone database.
+func fetchRootDB() (map[string]tldInfo, error) {
+	data, err := get(rootDBURL)
+	if err != nil {
+		return nil, err
+	}
+	infos := make(map[string]tldInfo)
+	for _, m := range rootDBRow.FindAllSubmatch(data, -1) {
+		info := tldInfo{Type: strings.TrimSpace(string(m[2]))}
+		if operator := html.UnescapeString(strings.TrimSpace(string(m[3]))); operator != "Not assigned" {
+			info.Operator = operator
+		}
+		infos[strings.ToLower(string(m[1]))] = info
+	}
+	if len(infos) < 1000 {
+		return nil, fmt.Errorf("%s: parsed only %d TLDs", rootDBURL, len(infos))
+	}
+	return infos, nil
+}
+
+// idnTable matches the link to an IDN table, named "<tld>_<language>_<version>.txt".
+var idnTable = regexp.MustCompile(`/domains/idn-tables/tables/([a-z0-9-]+)_`)
+
+// fetchIDNTLDs returns the TLDs with IDN tables in IANA's repository.
+func fetchIDNTLDs() (map[string]bool, error) {
+	data, err := get(idnTablesURL)
+	if err != nil {
+		return nil, err
+	}
+	tlds := make(map[string]bool)
+	for _, m := range idnTable.FindAllSubmatch(data, -1) {
+		tlds[string(m[1])] = true
+	}
+	if len(tlds) == 0 {
+		return nil, fmt.Errorf("%s: no IDN tables found", idnTablesURL)
+	}
+	return tlds, nil
+}
+
+// fetchBootstrap returns the RDAP base URL of every TLD in IANA's bootstrap,
+// preferring HTTPS.
+func fetchBootstrap() (map[string]string, error) {
+	data, err := get(bootstrapURL)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Services [][][]string `json:"services"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", bootstrapURL, err)
+	}
+	servers := make(map[string]string)
+	for _, service := range file.Services {
+		if len(service) != 2 || len(service[1]) == 0 {
+			continue
+		}
+		base := service[1][0]
+		for _, u := range service[1] {
+			if strings.HasPrefix(u, "https://") {
+				base = u
+				break
+			}
+		}
+		if !strings.HasSuffix(base, "/") {
+			base += "/"
+		}
+		for _, tld := range service[0] {
+			servers[strings.ToLower(tld)] = base
+		}
+	}
+	return servers, nil
+}
+
+// fetchWHOISServers asks whois.iana.org for the WHOIS server of each TLD.
+// TLDs without one, or whose query fails, are left out.
+func fetchWHOISServers(tlds []string, workers int) map[string]string {
+	var mu sync.Mutex
+	servers := make(map[string]string)
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tld := range queue {
+				server, err := whoisServer(tld)
+				if err != nil {
+					log.Printf("whois %s: %v", tld, err)
+					continue
+				}
+				if server != "" {
+					mu.Lock()
+					servers[tld] = server
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, tld := range tlds {
+		queue <- tld
+	}
+	close(queue)
+	wg.Wait()
+	return servers
+}
+
+// whoisServer returns the "whois:" field of IANA's answer for a TLD.
+func whoisServer(tld string) (string, error) {
+	conn, err := net.DialTimeout("tcp", ianaWHOIS, 10*time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if _, err := fmt.Fprintf(conn, "%s\r\n", tld); err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "whois:"); ok {
+			return strings.ToLower(strings.TrimSpace(value)), nil
+		}
+	}
+	return "", scanner.Err()
+}
@@ -0,0 +1,194 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// acePrefix marks an A-label, the ASCII (Punycode) form of a Unicode label.
+const acePrefix = "xn--"
+
+// IDN errors. They all wrap ErrInvalidFormat, so callers checking for an
+// invalid format keep working; the specific error tells users what to fix.
+var (
+	// ErrIDNDisallowed is returned for characters UTS #46 does not allow in
+	// domain names, or only allows in forms that cannot be mapped (e.g. "⒈")
+	ErrIDNDisallowed = fmt.Errorf("%w: disallowed character", ErrInvalidFormat)
+
+	// ErrIDNContext is returned for zero-width joiners outside the contexts
+	// RFC 5892 allows them in (after a virama, or between joining letters)
+	ErrIDNContext = fmt.Errorf("%w: character not allowed in this position", ErrInvalidFormat)
+
+	// ErrIDNLeadingMark is returned for labels starting with a combining mark
+	ErrIDNLeadingMark = fmt.Errorf("%w: label starts with a combining mark", ErrInvalidFormat)
+
+	// ErrIDNHyphen is returned for internationalized labels with a hyphen at
+	// the start, at the end, or in both the third and fourth position
+	ErrIDNHyphen = fmt.Errorf("%w: misplaced hyphen in internationalized label", ErrInvalidFormat)
+
+	// ErrIDNPunycode is returned for "xn--" labels that are not the valid
+	// Punycode encoding of a valid Unicode label
+	ErrIDNPunycode = fmt.Errorf("%w: invalid punycode label", ErrInvalidFormat)
+
+	// ErrIDNBidi is returned for names mixing right-to-left and left-to-right
+	// labels or characters in ways the bidi rule (RFC 5893) forbids
+	ErrIDNBidi = fmt.Errorf("%w: invalid mix of right-to-left and left-to-right text", ErrInvalidFormat)
+)
+
+// lookup maps and validates names as idna.Lookup does (UTS #46
+// non-transitional processing: case folding, compatibility characters such
+// as "ﬁ" → "fi", fullwidth forms, ideographic full stops, NFC; then the
+// hyphen, joiner, combining mark and bidi rules), except that ASCII syntax
+// is left to Normalize, which reports it with more specific errors.
+var lookup = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.StrictDomainName(false),
+	idna.BidiRule(),
+	idna.CheckHyphens(true),
+	idna.CheckJoiners(true),
+)
+
+// x/net/idna returns errors of its unexported types, so the rule a name broke
+// is found by running it through profiles enabling one check each on top of
+// mapOnly, which only rejects disallowed characters.
+var (
+	mapOnly = idna.New(
+		idna.MapForLookup(),
+		idna.Transitional(false),
+		idna.StrictDomainName(false),
+		idna.CheckHyphens(false),
+		idna.CheckJoiners(false),
+	)
+	hyphensOnly = idna.New(
+		idna.MapForLookup(),
+		idna.Transitional(false),
+		idna.StrictDomainName(false),
+		idna.CheckHyphens(true),
+		idna.CheckJoiners(false),
+	)
+	joinersOnly = idna.New(
+		idna.MapForLookup(),
+		idna.Transitional(false),
+		idna.StrictDomainName(false),
+		idna.CheckHyphens(false),
+		idna.CheckJoiners(true),
+	)
+)
+
+// ToASCII converts a domain name to its ASCII form for lookups.
+//
+// The name is mapped and validated as in UTS #46 (see lookup), so decomposed
+// input ("münchen") and compatibility characters resolve to the name a
+// browser would look up, and Unicode labels are converted to "xn--" A-labels
+// (RFC 3492 Punycode). A-labels in the input must be the canonical encoding
+// of a valid Unicode label. Invalid names are rejected with one of the IDN
+// errors above, never encoded.
+//
+// ASCII names without A-labels are only lowercased; checking their syntax
+// (and empty labels) is left to the caller.
+func ToASCII(name string) (string, error) {
+	lower := strings.ToLower(name)
+	if isASCII(name) && !strings.Contains(lower, acePrefix) {
+		return lower, nil
+	}
+
+	// A-labels are checked as given: UTS #46 would decode and re-encode
+	// non-canonical ones (e.g. "xn--abc-" → "abc") instead of rejecting them
+	for _, label := range strings.Split(lower, ".") {
+		if strings.HasPrefix(label, acePrefix) && isASCII(label) {
+			if _, err := decodeALabel(label); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	ascii, err := lookup.ToASCII(name)
+	if err != nil {
+		return "", classifyIDNError(name, err)
+	}
+	return ascii, nil
+}
+
+// classifyIDNError wraps err, returned by lookup for name, in the IDN error
+// of the rule the name broke.
+func classifyIDNError(name string, err error) error {
+	switch {
+	case !valid(mapOnly, name):
+		return fmt.Errorf("%w: %v", ErrIDNDisallowed, err)
+	case !valid(hyphensOnly, name):
+		return fmt.Errorf("%w: %v", ErrIDNHyphen, err)
+	case !valid(joinersOnly, name):
+		// The joiner check also rejects labels starting with a combining mark
+		if strings.ContainsAny(name, "\u200c\u200d") {
+			return fmt.Errorf("%w: %v", ErrIDNContext, err)
+		}
+		return fmt.Errorf("%w: %v", ErrIDNLeadingMark, err)
+	default:
+		// All that is left is the bidi rule
+		return fmt.Errorf("%w: %v", ErrIDNBidi, err)
+	}
+}
+
+// valid reports whether p converts name without error.
+func valid(p *idna.Profile, name string) bool {
+	_, err := p.ToASCII(name)
+	return err == nil
+}
+
+// ToUnicode converts the A-labels of an ASCII domain name to Unicode for
+// display (e.g. "xn--mnchen-3ya.de" → "münchen.de"). Labels that are not
+// valid A-labels are kept as they are and reported with ErrIDNPunycode.
+func ToUnicode(name string) (string, error) {
+	labels := strings.Split(name, ".")
+	var firstErr error
+	for i, label := range labels {
+		label = strings.ToLower(label)
+		if !strings.HasPrefix(label, acePrefix) {
+			continue
+		}
+		u, err := decodeALabel(label)
+		if err == nil {
+			if u, err = lookup.ToUnicode(u); err != nil {
+				err = fmt.Errorf("%w %q: %v", ErrIDNPunycode, label, err)
+			}
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		labels[i] = u
+	}
+	return strings.Join(labels, "."), firstErr
+}
+
+// decodeALabel decodes an A-label, checking it is the canonical encoding of
+// a non-ASCII label. Whether that label is valid is up to lookup.
+func decodeALabel(label string) (string, error) {
+	u, err := idna.Punycode.ToUnicode(label)
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %v", ErrIDNPunycode, label, err)
+	}
+	if isASCII(u) {
+		return "", fmt.Errorf("%w %q: encodes an ASCII label", ErrIDNPunycode, label)
+	}
+	if again, err := idna.Punycode.ToASCII(u); err != nil || again != label {
+		return "", fmt.Errorf("%w %q: not in canonical form", ErrIDNPunycode, label)
+	}
+	return u, nil
+}
+
+// isASCII reports whether s only holds ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
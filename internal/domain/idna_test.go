@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestToASCII(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"ascii unchanged", "example.com", "example.com", nil},
+		{"ascii lowercased", "Example.COM", "example.com", nil},
+		{"german", "München.de", "xn--mnchen-3ya.de", nil},
+		{"japanese", "日本.jp", "xn--wgv71a.jp", nil},
+		{"japanese full stop", "日本。jp", "xn--wgv71a.jp", nil},
+		{"fullwidth letters", "ｅｘａｍｐｌｅ.com", "example.com", nil},
+		{"capital I with dot", "İstanbul.tr", "xn--istanbul-o0e.tr", nil},
+		{"soft hyphen removed", "ex\u00adample.com", "example.com", nil},
+		{"unicode tld", "пример.рф", "xn--e1afmkfd.xn--p1ai", nil},
+		{"valid a-label kept", "xn--mnchen-3ya.de", "xn--mnchen-3ya.de", nil},
+		{"uppercase a-label", "XN--MNCHEN-3YA.de", "xn--mnchen-3ya.de", nil},
+		{"a-label of disallowed character", "xn--a.com", "", ErrIDNDisallowed},
+		{"arabic", "مثال.إختبار", "xn--mgbh0fb.xn--kgbechtv", nil},
+		{"catalan middle dot", "col·la.cat", "xn--colla-sja.cat", nil},
+		{"katakana middle dot", "ハロー・ワールド.jp", "xn--gdkl8fhk5egc.jp", nil},
+		{"decomposed umlaut", "Mu\u0308nchen.de", "xn--mnchen-3ya.de", nil},
+		{"decomposed a-ring", "a\u030angstr\u00f6m.se", "xn--ngstrm-hua5l.se", nil},
+		{"ligature", "ﬁsh.com", "fish.com", nil},
+		{"superscript digit", "x²y.com", "x2y.com", nil},
+		{"sharp s kept", "straße.de", "xn--strae-oqa.de", nil},
+
+		{"compatibility mapping to ascii punctuation", "⑴.com", "(1).com", nil}, // rejected by Normalize
+		{"unmappable compatibility character", "a⒈b.com", "", ErrIDNDisallowed},
+		{"tatweel", "a\u0640b.com", "", ErrIDNBidi},
+		{"zero width joiner", "a\u200db.com", "", ErrIDNContext},
+		{"zero width non-joiner", "a\u200cb.com", "", ErrIDNContext},
+		{"zero width joiner at label start", "\u200dक्ष.in", "", ErrIDNContext},
+		{"zero width joiner after virama", "क्\u200dष.in", "xn--11b2ezcw70k.in", nil},
+		{"zero width non-joiner after virama", "क्\u200cष.in", "xn--11b2ezcs70k.in", nil},
+		{"zero width non-joiner between joining letters", "\u0628\u200c\u0628.com", "xn--ngba799q.com", nil},
+		{"leading combining mark", "\u0301ab.com", "", ErrIDNLeadingMark},
+		{"leading hyphen", "-ü.com", "", ErrIDNHyphen},
+		{"hyphens in 3rd and 4th position", "ab--ü.com", "", ErrIDNHyphen},
+		{"ascii a-label", "xn--abc-.com", "", ErrIDNPunycode},
+		{"broken a-label", "xn--ab!.com", "", ErrIDNPunycode},
+		{"mixed direction label", "aمثال.com", "", ErrIDNBidi},
+		{"ltr label starting with digit next to rtl", "مثال.1a", "", ErrIDNBidi},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToASCII(tt.input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ToASCII(%q) = %q, %v, want %v", tt.input, got, err, tt.wantErr)
+				}
+				if !errors.Is(err, ErrInvalidFormat) {
+					t.Errorf("ToASCII(%q) error %v does not wrap ErrInvalidFormat", tt.input, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ToASCII(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestToUnicode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"example.com", "example.com", false},
+		{"xn--mnchen-3ya.de", "münchen.de", false},
+		{"XN--WGV71A.jp", "日本.jp", false},
+		{"xn--e1afmkfd.xn--p1ai", "пример.рф", false},
+		{"xn--ab!.xn--mnchen-3ya.de", "xn--ab!.münchen.de", true},
+	}
+	for _, tt := range tests {
+		got, err := ToUnicode(tt.input)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ToUnicode(%q) = %q, %v, want %q (error %v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNormalizeIDN(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Domain
+	}{
+		{"München.de", Domain{Full: "xn--mnchen-3ya.de", Name: "xn--mnchen-3ya", TLD: "de", Suffix: "de", Unicode: "münchen.de"}},
+		{" 日本 ", Domain{Full: "xn--wgv71a.com", Name: "xn--wgv71a", TLD: "com", Suffix: "com", Unicode: "日本.com"}},
+		{"Mu\u0308nchen.DE", Domain{Full: "xn--mnchen-3ya.de", Name: "xn--mnchen-3ya", TLD: "de", Suffix: "de", Unicode: "münchen.de"}},
+		{"xn--mnchen-3ya.de", Domain{Full: "xn--mnchen-3ya.de", Name: "xn--mnchen-3ya", TLD: "de", Suffix: "de", Unicode: "münchen.de"}},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %+v, %v, want %+v", tt.input, got, err, tt.want)
+		}
+		if got.Display() != tt.want.Unicode {
+			t.Errorf("Normalize(%q).Display() = %q, want %q", tt.input, got.Display(), tt.want.Unicode)
+		}
+	}
+
+	if _, err := Normalize("a⒈b.com"); !errors.Is(err, ErrIDNDisallowed) || !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Normalize(a⒈b.com) error = %v, want ErrIDNDisallowed wrapping ErrInvalidFormat", err)
+	}
+	if _, err := Normalize("⑴.com"); !errors.Is(err, ErrInvalidCharacter) {
+		t.Errorf("Normalize(⑴.com) error = %v, want ErrInvalidCharacter", err)
+	}
+	for _, d := range []Domain{tests[0].want, {Full: "example.com", Unicode: "example.com"}} {
+		data, err := json.Marshal(Result{Domain: d})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got struct{ Domain, Unicode string }
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := "" // omitted for ASCII names
+		if d.Unicode != d.Full {
+			want = d.Unicode
+		}
+		if got.Domain != d.Full || got.Unicode != want {
+			t.Errorf("Result JSON = %s, want domain %q, unicode %q", data, d.Full, want)
+		}
+	}
+	if got := (Domain{Full: "example.com"}).Display(); got != "example.com" {
+		t.Errorf("Display() without Unicode = %q, want Full", got)
+	}
+}
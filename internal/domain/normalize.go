@@ -3,7 +3,7 @@ package domain
 
 import (
 	"errors"
-	"regexp"
+	"fmt"
 	"strings"
 )
 
@@ -15,91 +15,170 @@ var (
 	ErrInvalidFormat = errors.New("invalid domain format")
 )
 
+// Maximum lengths of RFC 1035 section 2.3.4, in octets of the ASCII form.
+const (
+	maxLabelLength = 63
+	maxNameLength  = 253 // 255 on the wire, less the length octets of the first and root labels
+)
+
+// Syntax errors. They all wrap ErrInvalidFormat, so callers checking for an
+// invalid format keep working; the specific error tells users what to fix.
+var (
+	// ErrEmptyLabel is returned for names with an empty label ("example..com",
+	// ".example", or a trailing dot)
+	ErrEmptyLabel = fmt.Errorf("%w: empty label", ErrInvalidFormat)
+
+	// ErrLabelTooLong is returned for labels longer than 63 octets
+	ErrLabelTooLong = fmt.Errorf("%w: label longer than %d characters", ErrInvalidFormat, maxLabelLength)
+
+	// ErrNameTooLong is returned for names longer than 253 octets
+	ErrNameTooLong = fmt.Errorf("%w: name longer than %d characters", ErrInvalidFormat, maxNameLength)
+
+	// ErrInvalidCharacter is returned for labels with characters other than
+	// letters, digits and hyphens
+	ErrInvalidCharacter = fmt.Errorf("%w: invalid character", ErrInvalidFormat)
+
+	// ErrLabelHyphen is returned for labels starting or ending with a hyphen
+	ErrLabelHyphen = fmt.Errorf("%w: label starts or ends with a hyphen", ErrInvalidFormat)
+
+	// ErrReservedHyphen is returned for labels with hyphens in the third and
+	// fourth position that are not "xn--" A-labels: RFC 5891 reserves them
+	// for future encodings
+	ErrReservedHyphen = fmt.Errorf("%w: hyphens in 3rd and 4th position are reserved", ErrInvalidFormat)
+
+	// ErrInvalidTLD is returned for TLDs that cannot exist: all-numeric ones
+	// (they would make names look like IP addresses) and ones that are neither
+	// alphabetic nor an A-label
+	ErrInvalidTLD = fmt.Errorf("%w: invalid top-level domain", ErrInvalidFormat)
+)
+
 // Normalize converts a user input string into a validated Domain.
 //
 // Normalization rules:
 //   - Trims whitespace and converts to lowercase
+//   - Converts internationalized names to ASCII (see ToASCII); Full holds the
+//     A-label form used for lookups, Unicode the form to display
 //   - If input contains no dot, appends ".com"
 //   - If input contains a dot, uses as-is (preserves non-.com TLDs)
 //   - Validates the result is a plausible domain format
+//   - Rejects TLDs missing from the TLD registry (see SetTLDRegistry)
+//   - Reduces subdomains to the registrable domain, using the Public Suffix
+//     List for multi-label suffixes (see SetSuffixList)
 //
-// Examples:
+// Examples (Suffix and Unicode omitted when obvious):
 //   - "EXAMPLE"        → Domain{Full: "example.com", Name: "example", TLD: "com"}
 //   - "foo.io"         → Domain{Full: "foo.io", Name: "foo", TLD: "io"}
 //   - "example.org"    → Domain{Full: "example.org", Name: "example", TLD: "org"}
-//   - "sub.foo.io"     → Domain{Full: "sub.foo.io", Name: "sub.foo", TLD: "io"}
+//   - "sub.foo.io"     → Domain{Full: "foo.io", Name: "foo", TLD: "io"}
+//   - "shop.example.co.uk" → Domain{Full: "example.co.uk", Name: "example", TLD: "uk", Suffix: "co.uk"}
 //   - "  TruCore  "    → Domain{Full: "trucore.com", Name: "trucore", TLD: "com"}
+//   - "München.de"     → Domain{Full: "xn--mnchen-3ya.de", Name: "xn--mnchen-3ya", TLD: "de", Unicode: "münchen.de"}
 //   - ""               → ErrEmptyDomain
-//   - "example."       → ErrInvalidFormat
+//   - "example."       → ErrEmptyLabel (wraps ErrInvalidFormat)
+//   - "a⒈b.com"        → ErrIDNDisallowed (wraps ErrInvalidFormat)
+//   - "co.uk"          → ErrNoRegistrableDomain (wraps ErrInvalidFormat)
+//   - "example.notatld" → ErrUnknownTLD (wraps ErrInvalidFormat)
 //
 // This function uses the safer CLI normalization logic (add .com only if no dot)
 // instead of the old server logic (add .com if no .com suffix) which incorrectly
 // transformed "example.org" into "example.org.com".
 func Normalize(input string) (Domain, error) {
-	// Trim whitespace and convert to lowercase
-	input = strings.ToLower(strings.TrimSpace(input))
+	// Trim whitespace
+	input = strings.TrimSpace(input)
 
 	// Check for empty input
 	if input == "" {
 		return Domain{}, ErrEmptyDomain
 	}
 
+	// Convert to lowercase and internationalized labels to A-labels
+	input, err := ToASCII(input)
+	if err != nil {
+		return Domain{}, err
+	}
+
 	// SECURITY: Reject domains starting with '-' to prevent command injection
 	// This prevents inputs like "-h malicious.com" from being passed to whois
 	if strings.HasPrefix(input, "-") {
 		return Domain{}, ErrInvalidFormat
 	}
 
-	// SECURITY: Validate domain contains only allowed characters
-	// Valid domain characters: a-z, 0-9, hyphen, dot
-	// Domain labels must start and end with alphanumeric (not hyphen)
-	validChars := regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
-
 	// If no dot present, append .com (this is the safer CLI logic)
-	var fullDomain string
 	if !strings.Contains(input, ".") {
-		fullDomain = input + ".com"
-	} else {
-		fullDomain = input
-	}
-
-	// Validate the full domain format
-	if !validChars.MatchString(fullDomain) {
-		return Domain{}, ErrInvalidFormat
+		input += ".com"
 	}
 
-	input = fullDomain
-
-	// Validate format: must not end with a dot
-	if strings.HasSuffix(input, ".") {
-		return Domain{}, ErrInvalidFormat
+	// SECURITY: Validate the name against RFC 1035/5890 before it reaches any
+	// upstream: only a-z, 0-9 and inner hyphens, within the length limits
+	if err := validateName(input); err != nil {
+		return Domain{}, err
 	}
+	tld := input[strings.LastIndexByte(input, '.')+1:]
 
-	// Split by dots to extract TLD (last part)
-	parts := strings.Split(input, ".")
-	if len(parts) < 2 {
-		return Domain{}, ErrInvalidFormat
+	// Reject TLDs that are not delegated: no registry could answer for them
+	if _, ok := CurrentTLDRegistry().Lookup(tld); !ok {
+		return Domain{}, fmt.Errorf("%w: %q", ErrUnknownTLD, tld)
 	}
 
-	// Extract TLD (last part) and name (everything before TLD)
-	tld := parts[len(parts)-1]
-	if tld == "" {
-		return Domain{}, ErrInvalidFormat
+	// Reduce subdomains to the registrable domain (public suffix plus one label)
+	list := currentSuffixList()
+	suffix := list.PublicSuffix(input)
+	registrable, err := list.Registrable(input)
+	if err != nil {
+		return Domain{}, err
 	}
+	name := strings.TrimSuffix(registrable, "."+suffix)
 
-	// Name is everything before the TLD
-	name := strings.Join(parts[:len(parts)-1], ".")
-	if name == "" {
-		return Domain{}, ErrInvalidFormat
-	}
+	// Validated A-labels always decode
+	display, _ := ToUnicode(registrable)
 
 	return Domain{
-		Full: input,
-		Name: name,
-		TLD:  tld,
+		Full:    registrable,
+		Name:    name,
+		TLD:     tld,
+		Suffix:  suffix,
+		Unicode: display,
 	}, nil
 }
 
+// validateName checks the syntax of a lowercase ASCII name with at least two
+// labels: RFC 1035 lengths and LDH characters, RFC 5891 reserved hyphens and
+// RFC 3696 TLDs. A-labels have been validated by ToASCII.
+func validateName(name string) error {
+	if len(name) > maxNameLength {
+		return ErrNameTooLong
+	}
+	labels := strings.Split(name, ".")
+	for _, label := range labels {
+		switch {
+		case label == "":
+			return ErrEmptyLabel
+		case len(label) > maxLabelLength:
+			return fmt.Errorf("%w: %q", ErrLabelTooLong, label)
+		case strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-"):
+			return fmt.Errorf("%w: %q", ErrLabelHyphen, label)
+		case len(label) >= 4 && label[2:4] == "--" && !strings.HasPrefix(label, acePrefix):
+			return fmt.Errorf("%w: %q", ErrReservedHyphen, label)
+		}
+		for i := 0; i < len(label); i++ {
+			if c := label[i]; !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return fmt.Errorf("%w %q in label %q", ErrInvalidCharacter, c, label)
+			}
+		}
+	}
+
+	tld := labels[len(labels)-1]
+	if strings.HasPrefix(tld, acePrefix) {
+		return nil
+	}
+	for i := 0; i < len(tld); i++ {
+		if c := tld[i]; c < 'a' || c > 'z' {
+			return fmt.Errorf("%w: %q", ErrInvalidTLD, tld)
+		}
+	}
+	return nil
+}
+
 // NormalizeBatch normalizes multiple domain inputs.
 // Returns parallel slices of domains and errors.
 // Invalid domains will have their error in the corresponding position.
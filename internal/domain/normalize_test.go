@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -17,9 +19,11 @@ func TestNormalize(t *testing.T) {
 			name:  "bare name gets .com appended",
 			input: "trucore",
 			want: Domain{
-				Full: "trucore.com",
-				Name: "trucore",
-				TLD:  "com",
+				Full:        "trucore.com",
+				Name:        "trucore",
+				TLD:         "com",
+				Suffix:      "com",
+				Unicode:     "trucore.com",
 			},
 			wantErr: nil,
 		},
@@ -27,9 +31,11 @@ func TestNormalize(t *testing.T) {
 			name:  "domain with .io preserved",
 			input: "foo.io",
 			want: Domain{
-				Full: "foo.io",
-				Name: "foo",
-				TLD:  "io",
+				Full:        "foo.io",
+				Name:        "foo",
+				TLD:         "io",
+				Suffix:      "io",
+				Unicode:     "foo.io",
 			},
 			wantErr: nil,
 		},
@@ -37,9 +43,11 @@ func TestNormalize(t *testing.T) {
 			name:  "domain with .org preserved (BUG FIX TEST)",
 			input: "example.org",
 			want: Domain{
-				Full: "example.org",
-				Name: "example",
-				TLD:  "org",
+				Full:        "example.org",
+				Name:        "example",
+				TLD:         "org",
+				Suffix:      "org",
+				Unicode:     "example.org",
 			},
 			wantErr: nil,
 		},
@@ -47,9 +55,11 @@ func TestNormalize(t *testing.T) {
 			name:  "domain with .net preserved",
 			input: "test.net",
 			want: Domain{
-				Full: "test.net",
-				Name: "test",
-				TLD:  "net",
+				Full:        "test.net",
+				Name:        "test",
+				TLD:         "net",
+				Suffix:      "net",
+				Unicode:     "test.net",
 			},
 			wantErr: nil,
 		},
@@ -57,9 +67,11 @@ func TestNormalize(t *testing.T) {
 			name:  ".com domain preserved as-is",
 			input: "example.com",
 			want: Domain{
-				Full: "example.com",
-				Name: "example",
-				TLD:  "com",
+				Full:        "example.com",
+				Name:        "example",
+				TLD:         "com",
+				Suffix:      "com",
+				Unicode:     "example.com",
 			},
 			wantErr: nil,
 		},
@@ -69,9 +81,11 @@ func TestNormalize(t *testing.T) {
 			name:  "uppercase converted to lowercase",
 			input: "TRUCORE",
 			want: Domain{
-				Full: "trucore.com",
-				Name: "trucore",
-				TLD:  "com",
+				Full:        "trucore.com",
+				Name:        "trucore",
+				TLD:         "com",
+				Suffix:      "com",
+				Unicode:     "trucore.com",
 			},
 			wantErr: nil,
 		},
@@ -79,9 +93,11 @@ func TestNormalize(t *testing.T) {
 			name:  "mixed case converted to lowercase",
 			input: "TruCore.COM",
 			want: Domain{
-				Full: "trucore.com",
-				Name: "trucore",
-				TLD:  "com",
+				Full:        "trucore.com",
+				Name:        "trucore",
+				TLD:         "com",
+				Suffix:      "com",
+				Unicode:     "trucore.com",
 			},
 			wantErr: nil,
 		},
@@ -91,9 +107,11 @@ func TestNormalize(t *testing.T) {
 			name:  "leading whitespace trimmed",
 			input: "  trucore",
 			want: Domain{
-				Full: "trucore.com",
-				Name: "trucore",
-				TLD:  "com",
+				Full:        "trucore.com",
+				Name:        "trucore",
+				TLD:         "com",
+				Suffix:      "com",
+				Unicode:     "trucore.com",
 			},
 			wantErr: nil,
 		},
@@ -101,9 +119,11 @@ func TestNormalize(t *testing.T) {
 			name:  "trailing whitespace trimmed",
 			input: "trucore  ",
 			want: Domain{
-				Full: "trucore.com",
-				Name: "trucore",
-				TLD:  "com",
+				Full:        "trucore.com",
+				Name:        "trucore",
+				TLD:         "com",
+				Suffix:      "com",
+				Unicode:     "trucore.com",
 			},
 			wantErr: nil,
 		},
@@ -111,9 +131,11 @@ func TestNormalize(t *testing.T) {
 			name:  "leading and trailing whitespace trimmed",
 			input: "  trucore  ",
 			want: Domain{
-				Full: "trucore.com",
-				Name: "trucore",
-				TLD:  "com",
+				Full:        "trucore.com",
+				Name:        "trucore",
+				TLD:         "com",
+				Suffix:      "com",
+				Unicode:     "trucore.com",
 			},
 			wantErr: nil,
 		},
@@ -121,9 +143,11 @@ func TestNormalize(t *testing.T) {
 			name:  "whitespace around domain with TLD",
 			input: "  example.org  ",
 			want: Domain{
-				Full: "example.org",
-				Name: "example",
-				TLD:  "org",
+				Full:        "example.org",
+				Name:        "example",
+				TLD:         "org",
+				Suffix:      "org",
+				Unicode:     "example.org",
 			},
 			wantErr: nil,
 		},
@@ -133,9 +157,11 @@ func TestNormalize(t *testing.T) {
 			name:  "subdomain with .com",
 			input: "sub.example.com",
 			want: Domain{
-				Full: "sub.example.com",
-				Name: "sub.example",
-				TLD:  "com",
+				Full:        "example.com",
+				Name:        "example",
+				TLD:         "com",
+				Suffix:      "com",
+				Unicode:     "example.com",
 			},
 			wantErr: nil,
 		},
@@ -143,9 +169,11 @@ func TestNormalize(t *testing.T) {
 			name:  "subdomain with .org",
 			input: "api.service.org",
 			want: Domain{
-				Full: "api.service.org",
-				Name: "api.service",
-				TLD:  "org",
+				Full:        "service.org",
+				Name:        "service",
+				TLD:         "org",
+				Suffix:      "org",
+				Unicode:     "service.org",
 			},
 			wantErr: nil,
 		},
@@ -153,9 +181,11 @@ func TestNormalize(t *testing.T) {
 			name:  "deep subdomain",
 			input: "a.b.c.d.example.com",
 			want: Domain{
-				Full: "a.b.c.d.example.com",
-				Name: "a.b.c.d.example",
-				TLD:  "com",
+				Full:        "example.com",
+				Name:        "example",
+				TLD:         "com",
+				Suffix:      "com",
+				Unicode:     "example.com",
 			},
 			wantErr: nil,
 		},
@@ -177,25 +207,25 @@ func TestNormalize(t *testing.T) {
 			name:    "trailing dot returns error",
 			input:   "example.",
 			want:    Domain{},
-			wantErr: ErrInvalidFormat,
+			wantErr: ErrEmptyLabel,
 		},
 		{
 			name:    "trailing dot with TLD returns error",
 			input:   "example.com.",
 			want:    Domain{},
-			wantErr: ErrInvalidFormat,
+			wantErr: ErrEmptyLabel,
 		},
 		{
 			name:    "single dot returns error",
 			input:   ".",
 			want:    Domain{},
-			wantErr: ErrInvalidFormat,
+			wantErr: ErrEmptyLabel,
 		},
 		{
 			name:    "leading dot returns error (after .com append)",
 			input:   ".example",
 			want:    Domain{},
-			wantErr: ErrInvalidFormat,
+			wantErr: ErrEmptyLabel,
 		},
 
 		// Special characters (allowed in domains)
@@ -203,9 +233,11 @@ func TestNormalize(t *testing.T) {
 			name:  "hyphen in domain name",
 			input: "my-domain",
 			want: Domain{
-				Full: "my-domain.com",
-				Name: "my-domain",
-				TLD:  "com",
+				Full:        "my-domain.com",
+				Name:        "my-domain",
+				TLD:         "com",
+				Suffix:      "com",
+				Unicode:     "my-domain.com",
 			},
 			wantErr: nil,
 		},
@@ -213,9 +245,11 @@ func TestNormalize(t *testing.T) {
 			name:  "hyphen in domain with TLD",
 			input: "my-domain.io",
 			want: Domain{
-				Full: "my-domain.io",
-				Name: "my-domain",
-				TLD:  "io",
+				Full:        "my-domain.io",
+				Name:        "my-domain",
+				TLD:         "io",
+				Suffix:      "io",
+				Unicode:     "my-domain.io",
 			},
 			wantErr: nil,
 		},
@@ -223,9 +257,11 @@ func TestNormalize(t *testing.T) {
 			name:  "numbers in domain",
 			input: "domain123",
 			want: Domain{
-				Full: "domain123.com",
-				Name: "domain123",
-				TLD:  "com",
+				Full:        "domain123.com",
+				Name:        "domain123",
+				TLD:         "com",
+				Suffix:      "com",
+				Unicode:     "domain123.com",
 			},
 			wantErr: nil,
 		},
@@ -235,9 +271,11 @@ func TestNormalize(t *testing.T) {
 			name:  "country code TLD preserved (.uk)",
 			input: "example.co.uk",
 			want: Domain{
-				Full: "example.co.uk",
-				Name: "example.co",
-				TLD:  "uk",
+				Full:        "example.co.uk",
+				Name:        "example",
+				TLD:         "uk",
+				Suffix:      "co.uk",
+				Unicode:     "example.co.uk",
 			},
 			wantErr: nil,
 		},
@@ -245,9 +283,11 @@ func TestNormalize(t *testing.T) {
 			name:  "country code TLD preserved (.au)",
 			input: "example.com.au",
 			want: Domain{
-				Full: "example.com.au",
-				Name: "example.com",
-				TLD:  "au",
+				Full:        "example.com.au",
+				Name:        "example",
+				TLD:         "au",
+				Suffix:      "com.au",
+				Unicode:     "example.com.au",
 			},
 			wantErr: nil,
 		},
@@ -257,9 +297,11 @@ func TestNormalize(t *testing.T) {
 			name:  "new gTLD .dev preserved",
 			input: "myapp.dev",
 			want: Domain{
-				Full: "myapp.dev",
-				Name: "myapp",
-				TLD:  "dev",
+				Full:        "myapp.dev",
+				Name:        "myapp",
+				TLD:         "dev",
+				Suffix:      "dev",
+				Unicode:     "myapp.dev",
 			},
 			wantErr: nil,
 		},
@@ -267,9 +309,11 @@ func TestNormalize(t *testing.T) {
 			name:  "new gTLD .app preserved",
 			input: "myapp.app",
 			want: Domain{
-				Full: "myapp.app",
-				Name: "myapp",
-				TLD:  "app",
+				Full:        "myapp.app",
+				Name:        "myapp",
+				TLD:         "app",
+				Suffix:      "app",
+				Unicode:     "myapp.app",
 			},
 			wantErr: nil,
 		},
@@ -277,9 +321,11 @@ func TestNormalize(t *testing.T) {
 			name:  "new gTLD .ai preserved",
 			input: "startup.ai",
 			want: Domain{
-				Full: "startup.ai",
-				Name: "startup",
-				TLD:  "ai",
+				Full:        "startup.ai",
+				Name:        "startup",
+				TLD:         "ai",
+				Suffix:      "ai",
+				Unicode:     "startup.ai",
 			},
 			wantErr: nil,
 		},
@@ -326,9 +372,9 @@ func TestNormalizeBatch(t *testing.T) {
 			name:   "multiple valid domains",
 			inputs: []string{"trucore", "example.org", "foo.io"},
 			wantDomains: []Domain{
-				{Full: "trucore.com", Name: "trucore", TLD: "com"},
-				{Full: "example.org", Name: "example", TLD: "org"},
-				{Full: "foo.io", Name: "foo", TLD: "io"},
+				{Full: "trucore.com", Name: "trucore", TLD: "com", Suffix: "com", Unicode: "trucore.com"},
+				{Full: "example.org", Name: "example", TLD: "org", Suffix: "org", Unicode: "example.org"},
+				{Full: "foo.io", Name: "foo", TLD: "io", Suffix: "io", Unicode: "foo.io"},
 			},
 			wantErrs: []error{nil, nil, nil},
 		},
@@ -336,9 +382,9 @@ func TestNormalizeBatch(t *testing.T) {
 			name:   "mix of valid and invalid domains",
 			inputs: []string{"valid", "", "example.com"},
 			wantDomains: []Domain{
-				{Full: "valid.com", Name: "valid", TLD: "com"},
+				{Full: "valid.com", Name: "valid", TLD: "com", Suffix: "com", Unicode: "valid.com"},
 				{}, // empty domain
-				{Full: "example.com", Name: "example", TLD: "com"},
+				{Full: "example.com", Name: "example", TLD: "com", Suffix: "com", Unicode: "example.com"},
 			},
 			wantErrs: []error{nil, ErrEmptyDomain, nil},
 		},
@@ -356,15 +402,15 @@ func TestNormalizeBatch(t *testing.T) {
 				{}, // whitespace
 				{}, // trailing dot
 			},
-			wantErrs: []error{ErrEmptyDomain, ErrEmptyDomain, ErrInvalidFormat},
+			wantErrs: []error{ErrEmptyDomain, ErrEmptyDomain, ErrEmptyLabel},
 		},
 		{
 			name:   "case normalization in batch",
 			inputs: []string{"UPPER", "MiXeD.ORG", "  spaced  "},
 			wantDomains: []Domain{
-				{Full: "upper.com", Name: "upper", TLD: "com"},
-				{Full: "mixed.org", Name: "mixed", TLD: "org"},
-				{Full: "spaced.com", Name: "spaced", TLD: "com"},
+				{Full: "upper.com", Name: "upper", TLD: "com", Suffix: "com", Unicode: "upper.com"},
+				{Full: "mixed.org", Name: "mixed", TLD: "org", Suffix: "org", Unicode: "mixed.org"},
+				{Full: "spaced.com", Name: "spaced", TLD: "com", Suffix: "com", Unicode: "spaced.com"},
 			},
 			wantErrs: []error{nil, nil, nil},
 		},
@@ -473,7 +519,7 @@ func TestNormalizeSecurityInputs(t *testing.T) {
 				t.Errorf("Normalize(%q) should have returned an error for malicious input, but got nil", input)
 			}
 			// Verify it's the right kind of error
-			if err != ErrInvalidFormat && err != ErrEmptyDomain {
+			if !errors.Is(err, ErrInvalidFormat) && err != ErrEmptyDomain {
 				t.Errorf("Normalize(%q) returned unexpected error type: %v", input, err)
 			}
 		})
@@ -514,3 +560,45 @@ func TestNormalizeCommandInjectionProtection(t *testing.T) {
 		})
 	}
 }
+
+// TestNormalizeRFCValidation tests the RFC 1035/5890 syntax rules and their errors
+func TestNormalizeRFCValidation(t *testing.T) {
+	label := func(c string, n int) string { return strings.Repeat(c, n) }
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"63 character label", label("a", 63) + ".com", nil},
+		{"64 character label", label("a", 64) + ".com", ErrLabelTooLong},
+		{"long internationalized label", label("ü", 60) + ".de", ErrLabelTooLong},
+		{"253 character name", label("a", 63) + "." + label("b", 63) + "." + label("c", 63) + "." + label("d", 57) + ".com", nil},
+		{"254 character name", label("a", 63) + "." + label("b", 63) + "." + label("c", 63) + "." + label("d", 58) + ".com", ErrNameTooLong},
+		{"empty label", "example..com", ErrEmptyLabel},
+		{"trailing hyphen", "example-.com", ErrLabelHyphen},
+		{"leading hyphen in inner label", "www.-example.com", ErrLabelHyphen},
+		{"reserved hyphens", "ab--cd.com", ErrReservedHyphen},
+		{"a-label", "xn--mnchen-3ya.de", nil},
+		{"underscore", "exa_mple.com", ErrInvalidCharacter},
+		{"numeric TLD", "example.123", ErrInvalidTLD},
+		{"IP address", "192.168.0.1", ErrInvalidTLD},
+		{"TLD with digit", "example.c0m", ErrInvalidTLD},
+		{"TLD with hyphen", "example.co-m", ErrInvalidTLD},
+		{"a-label TLD", "example.xn--p1ai", nil},
+		{"digits in labels", "123.example4.com", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Normalize(tt.input)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Normalize(%q) unexpected error: %v", tt.input, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) || !errors.Is(err, ErrInvalidFormat) {
+				t.Errorf("Normalize(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
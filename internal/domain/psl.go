@@ -0,0 +1,200 @@
+package domain
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrNoRegistrableDomain is returned for names that are themselves a public
+// suffix (e.g. "co.uk"): nothing can be registered at that level.
+var ErrNoRegistrableDomain = fmt.Errorf("%w: name is a public suffix", ErrInvalidFormat)
+
+// embeddedSuffixList is the ICANN section of the Public Suffix List ("make
+// update-psl" refreshes it). It is used unless another list is loaded with
+// SetSuffixList.
+//
+//go:embed data/public_suffix_list.dat
+var embeddedSuffixList []byte
+
+// Section markers of the Public Suffix List. Only ICANN rules are used: names
+// below private suffixes (e.g. "github.io") are not sold by a registry, so
+// their availability cannot be checked.
+const (
+	pslEndICANN     = "// ===END ICANN DOMAINS==="
+	pslBeginPrivate = "// ===BEGIN PRIVATE DOMAINS==="
+)
+
+// Rule flags of a suffix in a SuffixList.
+const (
+	// pslSuffix marks a public suffix ("co.uk")
+	pslSuffix uint8 = 1 << iota
+
+	// pslWildcard marks a suffix whose every child is a public suffix ("*.ck")
+	pslWildcard
+
+	// pslException marks a name excluded from its parent's wildcard ("!www.ck")
+	pslException
+)
+
+// SuffixList is a parsed Public Suffix List (https://publicsuffix.org/).
+// A SuffixList is immutable after construction and safe for concurrent use.
+type SuffixList struct {
+	rules map[string]uint8 // A-label suffix → rule flags
+}
+
+// ParseSuffixList parses a list in the public_suffix_list.dat format.
+//
+// Rules of the private section are ignored, as are comments and blank lines.
+// Unicode rules are converted to A-labels, so lookups take ASCII names.
+func ParseSuffixList(r io.Reader) (*SuffixList, error) {
+	l := &SuffixList{rules: make(map[string]uint8)}
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, pslEndICANN) || strings.HasPrefix(line, pslBeginPrivate) {
+			break
+		}
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		// Only the first word is the rule
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			line = line[:i]
+		}
+
+		flag := pslSuffix
+		switch {
+		case strings.HasPrefix(line, "!"):
+			flag, line = pslException, line[1:]
+		case strings.HasPrefix(line, "*."):
+			flag, line = pslWildcard, line[2:]
+		}
+		suffix, err := ToASCII(line)
+		if err != nil || suffix == "" || strings.Contains(suffix, "*") {
+			return nil, fmt.Errorf("public suffix list line %d: invalid rule %q", lineNo, scanner.Text())
+		}
+		l.rules[suffix] |= flag
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read public suffix list: %w", err)
+	}
+	if len(l.rules) == 0 {
+		return nil, fmt.Errorf("public suffix list contains no rules")
+	}
+	return l, nil
+}
+
+// LoadSuffixList reads a public_suffix_list.dat file, such as the full list
+// downloaded from https://publicsuffix.org/list/public_suffix_list.dat.
+func LoadSuffixList(path string) (*SuffixList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open public suffix list: %w", err)
+	}
+	defer f.Close()
+	return ParseSuffixList(f)
+}
+
+// EmbeddedSuffixList returns the list compiled into the binary.
+func EmbeddedSuffixList() *SuffixList {
+	l, err := ParseSuffixList(bytes.NewReader(embeddedSuffixList))
+	if err != nil {
+		// The snapshot is validated by tests; a failure here is a build defect
+		panic(fmt.Sprintf("embedded public suffix list is invalid: %v", err))
+	}
+	return l
+}
+
+// Len returns the number of rules in the list.
+func (l *SuffixList) Len() int {
+	return len(l.rules)
+}
+
+// PublicSuffix returns the public suffix of an ASCII domain name, e.g.
+// "co.uk" for "shop.example.co.uk" and "com" for "example.com".
+//
+// Following the PSL algorithm, exception rules win over wildcard rules and
+// the longest matching rule wins otherwise. Names under no rule fall back to
+// the implicit "*" rule: their last label is the suffix.
+func (l *SuffixList) PublicSuffix(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for suffix := name; ; {
+		dot := strings.IndexByte(suffix, '.')
+		parent := ""
+		if dot >= 0 {
+			parent = suffix[dot+1:]
+		}
+
+		flags := l.rules[suffix]
+		switch {
+		case flags&pslException != 0:
+			return parent
+		case flags&pslSuffix != 0:
+			return suffix
+		case parent != "" && l.rules[parent]&pslWildcard != 0:
+			return suffix
+		case parent == "":
+			return suffix
+		}
+		suffix = parent
+	}
+}
+
+// Registrable returns the registrable domain of an ASCII domain name: its
+// public suffix plus one label (e.g. "example.co.uk" for "shop.example.co.uk").
+// It returns ErrNoRegistrableDomain when the name is a public suffix.
+func (l *SuffixList) Registrable(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	suffix := l.PublicSuffix(name)
+	if len(name) <= len(suffix) {
+		return "", ErrNoRegistrableDomain
+	}
+	rest := name[:len(name)-len(suffix)-1]
+	return rest[strings.LastIndexByte(rest, '.')+1:] + "." + suffix, nil
+}
+
+// suffixList holds the SuffixList set via SetSuffixList.
+var suffixList = struct {
+	sync.RWMutex
+	l *SuffixList
+}{
+	l: EmbeddedSuffixList(),
+}
+
+// SetSuffixList replaces the list used by Normalize, PublicSuffix and
+// Registrable; nil restores the embedded list. This should be called once
+// at startup.
+func SetSuffixList(l *SuffixList) {
+	if l == nil {
+		l = EmbeddedSuffixList()
+	}
+	suffixList.Lock()
+	suffixList.l = l
+	suffixList.Unlock()
+}
+
+// currentSuffixList returns the configured list.
+func currentSuffixList() *SuffixList {
+	suffixList.RLock()
+	defer suffixList.RUnlock()
+	return suffixList.l
+}
+
+// PublicSuffix returns the public suffix of a name with the configured list.
+// See SuffixList.PublicSuffix.
+func PublicSuffix(name string) string {
+	return currentSuffixList().PublicSuffix(name)
+}
+
+// Registrable returns the registrable domain of a name with the configured
+// list. See SuffixList.Registrable.
+func Registrable(name string) (string, error) {
+	return currentSuffixList().Registrable(name)
+}
@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedSuffixList(t *testing.T) {
+	l := EmbeddedSuffixList()
+	if l.Len() < 6000 {
+		t.Errorf("embedded list has %d rules, want the full ICANN section (over 6000)", l.Len())
+	}
+}
+
+func TestPublicSuffix(t *testing.T) {
+	tests := []struct {
+		name        string
+		suffix      string
+		registrable string
+	}{
+		{"example.com", "com", "example.com"},
+		{"www.example.com", "com", "example.com"},
+		{"EXAMPLE.COM.", "com", "example.com"},
+		{"example.co.uk", "co.uk", "example.co.uk"},
+		{"shop.example.co.uk", "co.uk", "example.co.uk"},
+		{"example.uk", "uk", "example.uk"},
+		{"a.b.example.com.au", "com.au", "example.com.au"},
+		{"example.unlisted", "unlisted", "example.unlisted"},
+
+		// Wildcard and exception rules
+		{"foo.bar.ck", "bar.ck", "foo.bar.ck"},
+		{"www.ck", "ck", "www.ck"},
+		{"a.www.ck", "ck", "www.ck"},
+		{"foo.bar.kawasaki.jp", "bar.kawasaki.jp", "foo.bar.kawasaki.jp"},
+		{"www.city.kawasaki.jp", "kawasaki.jp", "city.kawasaki.jp"},
+		{"school.sch.uk", "school.sch.uk", ""},
+
+		// Rules outside the registries users check most
+		{"example.k12.ma.us", "k12.ma.us", "example.k12.ma.us"},
+		{"example.gov.bd", "gov.bd", "example.gov.bd"},
+		{"example.ac.mz", "ac.mz", "example.ac.mz"},
+
+		// Unicode rules match their A-labels
+		{"example.xn--55qx5d.cn", "xn--55qx5d.cn", "example.xn--55qx5d.cn"},
+		{"xn--e1afmkfd.xn--p1ai", "xn--p1ai", "xn--e1afmkfd.xn--p1ai"},
+
+		// Public suffixes themselves
+		{"co.uk", "co.uk", ""},
+		{"com", "com", ""},
+	}
+	l := EmbeddedSuffixList()
+	for _, tt := range tests {
+		if got := l.PublicSuffix(tt.name); got != tt.suffix {
+			t.Errorf("PublicSuffix(%q) = %q, want %q", tt.name, got, tt.suffix)
+		}
+		got, err := l.Registrable(tt.name)
+		if tt.registrable == "" {
+			if !errors.Is(err, ErrNoRegistrableDomain) {
+				t.Errorf("Registrable(%q) = %q, %v, want ErrNoRegistrableDomain", tt.name, got, err)
+			}
+		} else if err != nil || got != tt.registrable {
+			t.Errorf("Registrable(%q) = %q, %v, want %q", tt.name, got, err, tt.registrable)
+		}
+	}
+}
+
+func TestParseSuffixList(t *testing.T) {
+	list := `// comment
+example
+*.wild.example   trailing words are ignored
+!keep.wild.example
+
+// ===END ICANN DOMAINS===
+// ===BEGIN PRIVATE DOMAINS===
+private.example
+`
+	l, err := ParseSuffixList(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("ParseSuffixList() error = %v", err)
+	}
+	if l.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", l.Len())
+	}
+	tests := map[string]string{
+		"a.example":                "example",
+		"a.private.example":        "example", // private rules ignored
+		"a.b.wild.example":         "b.wild.example",
+		"a.keep.wild.example":      "wild.example",
+		"deep.a.keep.wild.example": "wild.example",
+	}
+	for name, want := range tests {
+		if got := l.PublicSuffix(name); got != want {
+			t.Errorf("PublicSuffix(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	for _, bad := range []string{"", "// only comments\n", "a.*.example\n", "a⒈b.example\n"} {
+		if _, err := ParseSuffixList(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseSuffixList(%q) error = nil", bad)
+		}
+	}
+}
+
+func TestSetSuffixList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "public_suffix_list.dat")
+	if err := os.WriteFile(path, []byte("com\nblogspot.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := LoadSuffixList(path)
+	if err != nil {
+		t.Fatalf("LoadSuffixList() error = %v", err)
+	}
+	SetSuffixList(l)
+	defer SetSuffixList(nil)
+
+	d, err := Normalize("me.blogspot.com")
+	if err != nil || d.Full != "me.blogspot.com" || d.Suffix != "blogspot.com" {
+		t.Errorf("Normalize(me.blogspot.com) = %+v, %v, want suffix blogspot.com", d, err)
+	}
+	if got := PublicSuffix("example.co.uk"); got != "uk" {
+		t.Errorf("PublicSuffix(example.co.uk) = %q with a list without co.uk, want uk", got)
+	}
+
+	SetSuffixList(nil)
+	if got := PublicSuffix("example.co.uk"); got != "co.uk" {
+		t.Errorf("PublicSuffix(example.co.uk) = %q after reset, want co.uk", got)
+	}
+	if _, err := LoadSuffixList(filepath.Join(t.TempDir(), "missing.dat")); err == nil {
+		t.Error("LoadSuffixList(missing) error = nil")
+	}
+}
+
+func TestNormalizePublicSuffix(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Domain
+	}{
+		{"shop.example.co.uk", Domain{Full: "example.co.uk", Name: "example", TLD: "uk", Suffix: "co.uk", Unicode: "example.co.uk"}},
+		{"www.City.Kawasaki.jp", Domain{Full: "city.kawasaki.jp", Name: "city", TLD: "jp", Suffix: "kawasaki.jp", Unicode: "city.kawasaki.jp"}},
+		{"例え.公司.cn", Domain{Full: "xn--r8jz45g.xn--55qx5d.cn", Name: "xn--r8jz45g", TLD: "cn", Suffix: "xn--55qx5d.cn", Unicode: "例え.公司.cn"}},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %+v, %v, want %+v", tt.input, got, err, tt.want)
+		}
+		if got.Zone() != tt.want.Suffix {
+			t.Errorf("Normalize(%q).Zone() = %q, want %q", tt.input, got.Zone(), tt.want.Suffix)
+		}
+		if got.Registrable() != tt.want.Full || got.ASCII() != tt.want.Full {
+			t.Errorf("Normalize(%q) Registrable() = %q, ASCII() = %q, want %q", tt.input, got.Registrable(), got.ASCII(), tt.want.Full)
+		}
+	}
+
+	for _, input := range []string{"co.uk", "bar.ck", "foo.sch.uk"} {
+		if _, err := Normalize(input); !errors.Is(err, ErrNoRegistrableDomain) || !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("Normalize(%q) error = %v, want ErrNoRegistrableDomain", input, err)
+		}
+	}
+	if got := (Domain{Full: "example.test", TLD: "test"}).Zone(); got != "test" {
+		t.Errorf("Zone() without Suffix = %q, want the TLD", got)
+	}
+}
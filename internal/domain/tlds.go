@@ -0,0 +1,264 @@
+package domain
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownTLD is returned for names under a top-level domain that is not
+// delegated in the root zone (e.g. "example.notatld"): no registry exists to
+// ask, so such names are rejected before any lookup.
+var ErrUnknownTLD = fmt.Errorf("%w: unknown top-level domain", ErrInvalidFormat)
+
+// embeddedTLDRegistry is a snapshot of the delegated TLDs with their
+// metadata. It is used unless a newer registry is loaded with SetTLDRegistry.
+//
+// gen_tlds.go generates it from IANA's TLD list, root zone database, IDN
+// tables, WHOIS and RDAP bootstrap, and records the root zone version. Absent
+// fields mean unknown, not "none". The copy checked in was derived from the
+// ICANN section of the Public Suffix List (version "psl-2026-02-06") as IANA
+// was unreachable; its metadata stays sparse until it is regenerated.
+//
+//go:generate go run gen_tlds.go -o data/tlds.json
+//go:embed data/tlds.json
+var embeddedTLDRegistry []byte
+
+// TLDType is the IANA category of a top-level domain.
+type TLDType string
+
+// TLD categories, as listed in the IANA Root Zone Database.
+const (
+	// TLDGeneric is an unrestricted generic TLD ("com", "app")
+	TLDGeneric TLDType = "generic"
+
+	// TLDCountryCode is a country-code TLD, ASCII ("de") or internationalized ("рф")
+	TLDCountryCode TLDType = "country-code"
+
+	// TLDSponsored is a TLD run for a specific community ("edu", "museum")
+	TLDSponsored TLDType = "sponsored"
+
+	// TLDGenericRestricted is a generic TLD with eligibility rules ("biz", "name", "pro")
+	TLDGenericRestricted TLDType = "generic-restricted"
+
+	// TLDInfrastructure is the "arpa" TLD
+	TLDInfrastructure TLDType = "infrastructure"
+)
+
+// TLDInfo describes a delegated top-level domain.
+type TLDInfo struct {
+	// TLD is the A-label of the TLD, without a dot ("com", "xn--p1ai")
+	TLD string `json:"tld"`
+
+	// Unicode is the U-label of internationalized TLDs ("рф")
+	Unicode string `json:"unicode,omitempty"`
+
+	// Type is the IANA category of the TLD
+	Type TLDType `json:"type"`
+
+	// Operator is the registry operator, when known
+	Operator string `json:"operator,omitempty"`
+
+	// RDAP is the base URL of the registry's RDAP service, when known
+	RDAP string `json:"rdap,omitempty"`
+
+	// WHOIS is the host name of the registry's WHOIS server, when known
+	WHOIS string `json:"whois,omitempty"`
+
+	// IDN reports whether the registry accepts internationalized names;
+	// nil when unknown
+	IDN *bool `json:"idn,omitempty"`
+}
+
+// tldRegistryFile is the JSON format of data/tlds.json.
+type tldRegistryFile struct {
+	Version string    `json:"version"`
+	TLDs    []TLDInfo `json:"tlds"`
+}
+
+// TLDRegistry is a list of delegated top-level domains.
+// A TLDRegistry is immutable after construction and safe for concurrent use.
+type TLDRegistry struct {
+	version string
+	tlds    map[string]TLDInfo // A-label → metadata
+}
+
+// ParseTLDRegistry parses a TLD registry in either of two formats:
+//
+//   - the JSON format of the embedded snapshot, with metadata
+//   - IANA's tlds-alpha-by-domain.txt, one TLD per line after a "# Version"
+//     header (https://data.iana.org/TLD/tlds-alpha-by-domain.txt)
+//
+// The IANA list carries no metadata: TLDs also in the embedded snapshot keep
+// their metadata, new ones are typed country-code when they have two letters
+// and generic otherwise. TLDs missing from the list are dropped, so loading a
+// fresh list also forgets retired TLDs.
+func ParseTLDRegistry(data []byte) (*TLDRegistry, error) {
+	var file tldRegistryFile
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse TLD registry: %w", err)
+		}
+	} else {
+		var err error
+		if file, err = parseIANATLDList(data); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &TLDRegistry{version: file.Version, tlds: make(map[string]TLDInfo, len(file.TLDs))}
+	for _, info := range file.TLDs {
+		tld, err := ToASCII(info.TLD)
+		if err != nil || tld == "" || strings.Contains(tld, ".") {
+			return nil, fmt.Errorf("TLD registry: invalid TLD %q", info.TLD)
+		}
+		info.TLD = tld
+		if info.Unicode, _ = ToUnicode(tld); info.Unicode == tld {
+			info.Unicode = ""
+		}
+		if info.Type == "" {
+			info.Type = TLDGeneric
+			if len(tld) == 2 {
+				info.Type = TLDCountryCode
+			}
+		}
+		r.tlds[tld] = info
+	}
+	if len(r.tlds) == 0 {
+		return nil, fmt.Errorf("TLD registry contains no TLDs")
+	}
+	return r, nil
+}
+
+// parseIANATLDList parses IANA's tlds-alpha-by-domain.txt, taking metadata
+// from the embedded snapshot.
+func parseIANATLDList(data []byte) (tldRegistryFile, error) {
+	var known tldRegistryFile
+	if err := json.Unmarshal(embeddedTLDRegistry, &known); err != nil {
+		return known, fmt.Errorf("embedded TLD registry is invalid: %w", err)
+	}
+	metadata := make(map[string]TLDInfo, len(known.TLDs))
+	for _, info := range known.TLDs {
+		metadata[info.TLD] = info
+	}
+
+	var file tldRegistryFile
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			// "# Version 2026101600, Last Updated Fri Oct 16 07:07:01 2026 UTC"
+			if fields := strings.Fields(strings.TrimPrefix(line, "#")); file.Version == "" && len(fields) >= 2 && fields[0] == "Version" {
+				file.Version = strings.TrimSuffix(fields[1], ",")
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if strings.ContainsAny(line, " \t") {
+			return file, fmt.Errorf("TLD list line %d: invalid TLD %q", lineNo, line)
+		}
+		tld := strings.ToLower(line)
+		info, ok := metadata[tld]
+		if !ok {
+			info = TLDInfo{TLD: tld}
+		}
+		file.TLDs = append(file.TLDs, info)
+	}
+	if err := scanner.Err(); err != nil {
+		return file, fmt.Errorf("failed to read TLD list: %w", err)
+	}
+	return file, nil
+}
+
+// LoadTLDRegistry reads a TLD registry file, such as a fresh copy of
+// https://data.iana.org/TLD/tlds-alpha-by-domain.txt. See ParseTLDRegistry.
+func LoadTLDRegistry(path string) (*TLDRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLD registry: %w", err)
+	}
+	return ParseTLDRegistry(data)
+}
+
+// EmbeddedTLDRegistry returns the registry compiled into the binary.
+func EmbeddedTLDRegistry() *TLDRegistry {
+	r, err := ParseTLDRegistry(embeddedTLDRegistry)
+	if err != nil {
+		// The snapshot is validated by tests; a failure here is a build defect
+		panic(fmt.Sprintf("embedded TLD registry is invalid: %v", err))
+	}
+	return r
+}
+
+// Lookup returns the metadata of a TLD, given as an A-label or U-label with
+// or without a leading dot. It reports false for TLDs that are not delegated.
+func (r *TLDRegistry) Lookup(tld string) (TLDInfo, bool) {
+	tld, err := ToASCII(strings.TrimPrefix(tld, "."))
+	if err != nil {
+		return TLDInfo{}, false
+	}
+	info, ok := r.tlds[tld]
+	return info, ok
+}
+
+// All returns every TLD of the registry, sorted by A-label.
+func (r *TLDRegistry) All() []TLDInfo {
+	all := make([]TLDInfo, 0, len(r.tlds))
+	for _, info := range r.tlds {
+		all = append(all, info)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].TLD < all[j].TLD })
+	return all
+}
+
+// Len returns the number of TLDs in the registry.
+func (r *TLDRegistry) Len() int {
+	return len(r.tlds)
+}
+
+// Version returns the version of the registry (IANA's "YYYYMMDDNN" root zone
+// serial), or "" when the source did not carry one.
+func (r *TLDRegistry) Version() string {
+	return r.version
+}
+
+// tldRegistry holds the TLDRegistry set via SetTLDRegistry.
+var tldRegistry = struct {
+	sync.RWMutex
+	r *TLDRegistry
+}{
+	r: EmbeddedTLDRegistry(),
+}
+
+// SetTLDRegistry replaces the registry used by Normalize and LookupTLD; nil
+// restores the embedded registry. It may be called at any time, e.g. after
+// downloading a fresh list.
+func SetTLDRegistry(r *TLDRegistry) {
+	if r == nil {
+		r = EmbeddedTLDRegistry()
+	}
+	tldRegistry.Lock()
+	tldRegistry.r = r
+	tldRegistry.Unlock()
+}
+
+// CurrentTLDRegistry returns the configured registry.
+func CurrentTLDRegistry() *TLDRegistry {
+	tldRegistry.RLock()
+	defer tldRegistry.RUnlock()
+	return tldRegistry.r
+}
+
+// LookupTLD returns the metadata of a TLD with the configured registry.
+// See TLDRegistry.Lookup.
+func LookupTLD(tld string) (TLDInfo, bool) {
+	return CurrentTLDRegistry().Lookup(tld)
+}
@@ -0,0 +1,154 @@
+package domain
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedTLDRegistry(t *testing.T) {
+	r := EmbeddedTLDRegistry()
+	if r.Len() < 1000 || r.Version() == "" {
+		t.Fatalf("embedded registry has %d TLDs, version %q", r.Len(), r.Version())
+	}
+	all := r.All()
+	if len(all) != r.Len() || !sort.SliceIsSorted(all, func(i, j int) bool { return all[i].TLD < all[j].TLD }) {
+		t.Errorf("All() returned %d unsorted or missing TLDs, want %d sorted", len(all), r.Len())
+	}
+	for _, info := range all {
+		switch info.Type {
+		case TLDGeneric, TLDCountryCode, TLDSponsored, TLDGenericRestricted, TLDInfrastructure:
+		default:
+			t.Errorf("%s has unknown type %q", info.TLD, info.Type)
+		}
+		if info.RDAP != "" && (!strings.HasPrefix(info.RDAP, "https://") || !strings.HasSuffix(info.RDAP, "/")) {
+			t.Errorf("%s has RDAP URL %q, want an HTTPS base URL", info.TLD, info.RDAP)
+		}
+		if strings.HasPrefix(info.TLD, "xn--") && (info.IDN == nil || !*info.IDN) {
+			t.Errorf("%s is internationalized but IDN = %v", info.TLD, info.IDN)
+		}
+	}
+}
+
+func TestTLDRegistryLookup(t *testing.T) {
+	tests := []struct {
+		tld      string
+		wantType TLDType
+		wantOK   bool
+	}{
+		{"com", TLDGeneric, true},
+		{".COM", TLDGeneric, true},
+		{"de", TLDCountryCode, true},
+		{"museum", TLDSponsored, true},
+		{"biz", TLDGenericRestricted, true},
+		{"arpa", TLDInfrastructure, true},
+		{"app", TLDGeneric, true},
+		{"xn--p1ai", TLDCountryCode, true},
+		{"рф", TLDCountryCode, true},
+		{"ck", TLDCountryCode, true},
+		{"hotel", TLDGeneric, true}, // delegated since the 2023 snapshot
+		{"travel", TLDSponsored, true},
+		{"notatld", "", false},
+		{"test", "", false},
+		{"onion", "", false},     // special-use, not delegated
+		{"alfaromeo", "", false}, // retired since the 2023 snapshot
+		{"a⒈b", "", false},
+	}
+	r := EmbeddedTLDRegistry()
+	for _, tt := range tests {
+		info, ok := r.Lookup(tt.tld)
+		if ok != tt.wantOK || info.Type != tt.wantType {
+			t.Errorf("Lookup(%q) = %+v, %v, want type %q, %v", tt.tld, info, ok, tt.wantType, tt.wantOK)
+		}
+	}
+
+	com, _ := r.Lookup("com")
+	if com.Operator == "" || com.RDAP == "" || com.WHOIS == "" || com.IDN == nil || !*com.IDN {
+		t.Errorf("Lookup(com) = %+v, want operator, RDAP, WHOIS and IDN support", com)
+	}
+	if rf, _ := r.Lookup("xn--p1ai"); rf.Unicode != "рф" || rf.IDN == nil || !*rf.IDN {
+		t.Errorf("Lookup(xn--p1ai) = %+v, want unicode рф and IDN support", rf)
+	}
+	if bar, _ := r.Lookup("bar"); bar.IDN != nil {
+		t.Errorf("Lookup(bar) IDN = %v, want unknown", *bar.IDN)
+	}
+}
+
+func TestParseTLDRegistry(t *testing.T) {
+	// IANA's tlds-alpha-by-domain.txt: metadata of known TLDs is kept
+	list := "# Version 2026101600, Last Updated Fri Oct 16 07:07:01 2026 UTC\nCOM\nZZ\nNEWTLD\nXN--P1AI\n"
+	r, err := ParseTLDRegistry([]byte(list))
+	if err != nil {
+		t.Fatalf("ParseTLDRegistry() error = %v", err)
+	}
+	if r.Len() != 4 || r.Version() != "2026101600" {
+		t.Errorf("Len() = %d, Version() = %q, want 4, 2026101600", r.Len(), r.Version())
+	}
+	if com, _ := r.Lookup("com"); com.Operator == "" || com.Type != TLDGeneric {
+		t.Errorf("Lookup(com) = %+v, want embedded metadata", com)
+	}
+	if zz, _ := r.Lookup("zz"); zz.Type != TLDCountryCode {
+		t.Errorf("Lookup(zz) type = %q, want country-code", zz.Type)
+	}
+	if newTLD, _ := r.Lookup("newtld"); newTLD.Type != TLDGeneric {
+		t.Errorf("Lookup(newtld) type = %q, want generic", newTLD.Type)
+	}
+	if rf, _ := r.Lookup("рф"); rf.Unicode != "рф" {
+		t.Errorf("Lookup(рф) = %+v, want unicode рф", rf)
+	}
+	if _, ok := r.Lookup("de"); ok {
+		t.Error("Lookup(de) found a TLD missing from the list")
+	}
+
+	// JSON in the embedded format
+	r, err = ParseTLDRegistry([]byte(`{"version": "1", "tlds": [{"tld": "example", "type": "sponsored", "operator": "Example Registry", "rdap": "https://rdap.example/", "whois": "whois.example", "idn": false}, {"tld": "other"}]}`))
+	if err != nil {
+		t.Fatalf("ParseTLDRegistry(json) error = %v", err)
+	}
+	if info, ok := r.Lookup("example"); !ok || info.Type != TLDSponsored || info.Operator != "Example Registry" || info.RDAP != "https://rdap.example/" || info.WHOIS != "whois.example" || info.IDN == nil || *info.IDN {
+		t.Errorf("Lookup(example) = %+v, %v", info, ok)
+	}
+	if info, ok := r.Lookup("other"); !ok || info.Operator != "" || info.IDN != nil {
+		t.Errorf("Lookup(other) = %+v, %v, want no metadata", info, ok)
+	}
+
+	for _, bad := range []string{"", "# Version 1\n", "COM NET\n", "a.b\n", "{", `{"tlds": [{"tld": "a⒈b"}]}`} {
+		if _, err := ParseTLDRegistry([]byte(bad)); err == nil {
+			t.Errorf("ParseTLDRegistry(%q) error = nil", bad)
+		}
+	}
+}
+
+func TestSetTLDRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tlds-alpha-by-domain.txt")
+	if err := os.WriteFile(path, []byte("# Version 1\nCOM\nTEST\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := LoadTLDRegistry(path)
+	if err != nil {
+		t.Fatalf("LoadTLDRegistry() error = %v", err)
+	}
+	SetTLDRegistry(r)
+	defer SetTLDRegistry(nil)
+
+	if d, err := Normalize("example.test"); err != nil || d.Full != "example.test" {
+		t.Errorf("Normalize(example.test) = %+v, %v with test registered", d, err)
+	}
+	if _, err := Normalize("example.org"); !errors.Is(err, ErrUnknownTLD) {
+		t.Errorf("Normalize(example.org) error = %v with org unregistered, want ErrUnknownTLD", err)
+	}
+	if _, ok := LookupTLD("test"); !ok {
+		t.Error("LookupTLD(test) = false with test registered")
+	}
+
+	SetTLDRegistry(nil)
+	if _, err := Normalize("example.test"); !errors.Is(err, ErrUnknownTLD) || !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Normalize(example.test) error = %v after reset, want ErrUnknownTLD", err)
+	}
+	if _, err := LoadTLDRegistry(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadTLDRegistry(missing) error = nil")
+	}
+}
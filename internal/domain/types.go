@@ -9,14 +9,53 @@ import (
 // Domain represents a validated, normalized domain name.
 // It contains both the full domain string and its parsed components.
 type Domain struct {
-	// Full is the complete normalized domain (e.g., "example.com")
+	// Full is the complete normalized domain (e.g., "example.com",
+	// "example.co.uk"); Normalize reduces subdomains to the registrable domain
 	Full string
 
-	// Name is the second-level domain (e.g., "example")
+	// Name is the label registered under the public suffix (e.g., "example")
 	Name string
 
-	// TLD is the top-level domain (e.g., "com")
+	// TLD is the top-level domain (e.g., "com", "uk")
 	TLD string
+
+	// Suffix is the public suffix the name is registered under (e.g., "com",
+	// "co.uk"), per the Public Suffix List
+	Suffix string
+
+	// Unicode is the human-readable form of the name (e.g., "münchen.de");
+	// it equals Full for names without internationalized labels
+	Unicode string
+}
+
+// Registrable returns the registrable domain: Name plus Suffix (e.g.,
+// "example.co.uk"). Normalize reduces names to it, so it is Full.
+func (d Domain) Registrable() string {
+	return d.Full
+}
+
+// ASCII returns the name in ASCII, with internationalized labels as Punycode
+// A-labels (e.g., "xn--mnchen-3ya.de"). Lookups use this form, so it is Full.
+func (d Domain) ASCII() string {
+	return d.Full
+}
+
+// Display returns the name to show users: the Unicode form when known,
+// Full otherwise.
+func (d Domain) Display() string {
+	if d.Unicode != "" {
+		return d.Unicode
+	}
+	return d.Full
+}
+
+// Zone returns the zone the name is registered in, which routes its checks
+// to a registry: the public suffix when known, the TLD otherwise.
+func (d Domain) Zone() string {
+	if d.Suffix != "" {
+		return d.Suffix
+	}
+	return d.TLD
 }
 
 // Status represents the availability status of a domain.
@@ -34,6 +73,25 @@ const (
 
 	// StatusError indicates an error occurred during checking
 	StatusError
+
+	// StatusReserved indicates the registry has reserved the name; it cannot be registered
+	StatusReserved
+
+	// StatusPremium indicates the name is available but priced as a premium name
+	StatusPremium
+
+	// StatusPendingDelete indicates a registered name that will be released after deletion
+	StatusPendingDelete
+
+	// StatusRedemptionPeriod indicates a deleted name the previous owner can still restore
+	StatusRedemptionPeriod
+
+	// StatusBlocked indicates the name is blocked from registration (e.g. trademark blocks)
+	StatusBlocked
+
+	// StatusConflict indicates sources disagreed on whether the name can be
+	// registered (only reported by consensus checks)
+	StatusConflict
 )
 
 // String returns the string representation of the Status.
@@ -45,11 +103,45 @@ func (s Status) String() string {
 		return "taken"
 	case StatusError:
 		return "error"
+	case StatusReserved:
+		return "reserved"
+	case StatusPremium:
+		return "premium"
+	case StatusPendingDelete:
+		return "pending_delete"
+	case StatusRedemptionPeriod:
+		return "redemption_period"
+	case StatusBlocked:
+		return "blocked"
+	case StatusConflict:
+		return "conflict"
 	default:
 		return "unknown"
 	}
 }
 
+// ParseStatus returns the Status whose String() is s.
+func ParseStatus(s string) (Status, bool) {
+	for st := StatusUnknown; st <= StatusConflict; st++ {
+		if st.String() == s {
+			return st, true
+		}
+	}
+	return StatusUnknown, false
+}
+
+// Registrable reports whether a name with this status can be registered now.
+// Premium names are registrable, at a higher price.
+func (s Status) Registrable() bool {
+	return s == StatusAvailable || s == StatusPremium
+}
+
+// Registered reports whether a name with this status is held by a registrant,
+// including names on their way to deletion.
+func (s Status) Registered() bool {
+	return s == StatusTaken || s == StatusPendingDelete || s == StatusRedemptionPeriod
+}
+
 // Result contains the complete check result for a domain.
 // It maintains backward compatibility with the existing API while
 // adding new fields for enhanced functionality.
@@ -60,12 +152,16 @@ type Result struct {
 	// Status indicates the availability status
 	Status Status
 
-	// Available is a convenience field (true when Status == StatusAvailable)
+	// Available is a convenience field (true when Status.Registrable())
 	Available bool
 
 	// Error contains error details when Status == StatusError
 	Error string
 
+	// ErrorCode classifies Error for programs (e.g. "rate_limited");
+	// empty when the error is not classified
+	ErrorCode string
+
 	// Source indicates which protocol provided the answer (rdap, whois, dns)
 	Source string
 
@@ -74,6 +170,178 @@ type Result struct {
 
 	// Duration is how long the check took
 	Duration time.Duration
+
+	// Registration holds registry data for taken domains when the source provides it (RDAP, WHOIS)
+	Registration *Registration
+
+	// Attempts lists every source queried, in order, with its outcome
+	Attempts []Attempt
+
+	// Confidence rates how trustworthy the final Status is
+	Confidence Confidence
+
+	// Cached is true when the result was served from the result cache
+	Cached bool
+
+	// Age is how long ago a cached result was checked (zero when not cached)
+	Age time.Duration
+}
+
+// Attempt records a single source query made while checking a domain.
+type Attempt struct {
+	// Source is the source name (dns, rdap, whois, ...)
+	Source string
+
+	// Outcome is the status the source reported; StatusUnknown when it could
+	// not decide and StatusError when the query failed
+	Outcome Status
+
+	// Duration is how long the query took
+	Duration time.Duration
+
+	// Error contains the failure message when Outcome == StatusError
+	Error string
+
+	// Signal is the raw evidence behind the outcome, such as "HTTP 404",
+	// "A/AAAA records" or the matched WHOIS indicator
+	Signal string
+}
+
+// MarshalJSON implements custom JSON marshaling for Attempt.
+func (a Attempt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Source   string `json:"source"`
+		Outcome  string `json:"outcome"`
+		Duration int64  `json:"duration_ms"`
+		Error    string `json:"error,omitempty"`
+		Signal   string `json:"signal,omitempty"`
+	}{
+		Source:   a.Source,
+		Outcome:  a.Outcome.String(),
+		Duration: a.Duration.Milliseconds(),
+		Error:    a.Error,
+		Signal:   a.Signal,
+	})
+}
+
+// Confidence rates how much a Result's Status can be trusted.
+type Confidence int
+
+const (
+	// ConfidenceNone is used for error results
+	ConfidenceNone Confidence = iota
+
+	// ConfidenceLow means the status is a guess (e.g. unrecognized WHOIS text assumed taken)
+	ConfidenceLow
+
+	// ConfidenceMedium means the status comes from a heuristic source (WHOIS text matching)
+	ConfidenceMedium
+
+	// ConfidenceHigh means the status comes from authoritative data (RDAP, DNS records)
+	ConfidenceHigh
+)
+
+// String returns the string representation of the Confidence.
+func (c Confidence) String() string {
+	switch c {
+	case ConfidenceLow:
+		return "low"
+	case ConfidenceMedium:
+		return "medium"
+	case ConfidenceHigh:
+		return "high"
+	default:
+		return "none"
+	}
+}
+
+// ParseConfidence returns the Confidence whose String() is s.
+func ParseConfidence(s string) (Confidence, bool) {
+	for c := ConfidenceNone; c <= ConfidenceHigh; c++ {
+		if c.String() == s {
+			return c, true
+		}
+	}
+	return ConfidenceNone, false
+}
+
+// Registration contains the registration details of a taken domain.
+// Fields are left empty when the registry does not publish them.
+type Registration struct {
+	// Registrar is the sponsoring registrar's name
+	Registrar string
+
+	// RegistrarIANAID is the registrar's IANA registrar ID, if published
+	RegistrarIANAID string
+
+	// Created is when the domain was first registered
+	Created time.Time
+
+	// Expires is when the current registration period ends
+	Expires time.Time
+
+	// Updated is when the registration was last changed
+	Updated time.Time
+
+	// Nameservers lists the delegated nameservers (lowercase)
+	Nameservers []string
+
+	// DNSSEC is true when the delegation is signed
+	DNSSEC bool
+
+	// Statuses contains the raw EPP/RDAP status values (e.g. "client transfer prohibited")
+	Statuses []string
+
+	// Entities lists the contacts attached to the domain (registrant, registrar, abuse, ...)
+	Entities []Entity
+}
+
+// Entity is a contact attached to a registration.
+type Entity struct {
+	// Handle is the registry's identifier for the entity
+	Handle string `json:"handle,omitempty"`
+
+	// Roles are the RDAP roles such as "registrant", "registrar" or "abuse"
+	Roles []string `json:"roles,omitempty"`
+
+	// Name is the formatted name from the entity's vCard (often redacted)
+	Name string `json:"name,omitempty"`
+
+	// Email is the contact email from the entity's vCard, if published
+	Email string `json:"email,omitempty"`
+}
+
+// MarshalJSON implements custom JSON marshaling for Registration.
+// Dates are formatted as RFC 3339 and omitted when unknown.
+func (r Registration) MarshalJSON() ([]byte, error) {
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	return json.Marshal(&struct {
+		Registrar       string   `json:"registrar,omitempty"`
+		RegistrarIANAID string   `json:"registrar_iana_id,omitempty"`
+		Created         string   `json:"created,omitempty"`
+		Expires         string   `json:"expires,omitempty"`
+		Updated         string   `json:"updated,omitempty"`
+		Nameservers     []string `json:"nameservers,omitempty"`
+		DNSSEC          bool     `json:"dnssec"`
+		Statuses        []string `json:"statuses,omitempty"`
+		Entities        []Entity `json:"entities,omitempty"`
+	}{
+		Registrar:       r.Registrar,
+		RegistrarIANAID: r.RegistrarIANAID,
+		Created:         formatTime(r.Created),
+		Expires:         formatTime(r.Expires),
+		Updated:         formatTime(r.Updated),
+		Nameservers:     r.Nameservers,
+		DNSSEC:          r.DNSSEC,
+		Statuses:        r.Statuses,
+		Entities:        r.Entities,
+	})
 }
 
 // MarshalJSON implements custom JSON marshaling for Result.
@@ -81,21 +349,47 @@ type Result struct {
 // - Outputting Domain.Full as a simple "domain" string field
 // - Formatting Duration as milliseconds instead of nanoseconds
 // - Using existing field names from the original API
+// - Adding "status" with the lifecycle state (see Status.String)
+// - Adding "registration" only when registration details are known
+// - Adding the "attempts" evidence trail and derived "confidence"
+// - Adding "cached" and, for cache hits, "age" in whole seconds
+// - Adding "error_code" when the error is classified
+// - Adding "unicode" with the human-readable form of internationalized names
 func (r Result) MarshalJSON() ([]byte, error) {
+	unicodeName := ""
+	if r.Domain.Unicode != r.Domain.Full {
+		unicodeName = r.Domain.Unicode
+	}
 	return json.Marshal(&struct {
-		Domain    string `json:"domain"`
-		Available bool   `json:"available"`
-		Error     string `json:"error,omitempty"`
-		Source    string `json:"source,omitempty"`
-		CheckedAt string `json:"checked_at,omitempty"`
-		Duration  int64  `json:"duration_ms,omitempty"`
+		Domain       string        `json:"domain"`
+		Unicode      string        `json:"unicode,omitempty"`
+		Available    bool          `json:"available"`
+		Status       string        `json:"status"`
+		Error        string        `json:"error,omitempty"`
+		ErrorCode    string        `json:"error_code,omitempty"`
+		Source       string        `json:"source,omitempty"`
+		CheckedAt    string        `json:"checked_at,omitempty"`
+		Duration     int64         `json:"duration_ms,omitempty"`
+		Registration *Registration `json:"registration,omitempty"`
+		Confidence   string        `json:"confidence"`
+		Attempts     []Attempt     `json:"attempts,omitempty"`
+		Cached       bool          `json:"cached"`
+		Age          int64         `json:"age,omitempty"`
 	}{
-		Domain:    r.Domain.Full,
-		Available: r.Available,
-		Error:     r.Error,
-		Source:    r.Source,
-		CheckedAt: r.CheckedAt.Format(time.RFC3339),
-		Duration:  r.Duration.Milliseconds(),
+		Domain:       r.Domain.Full,
+		Unicode:      unicodeName,
+		Available:    r.Available,
+		Status:       r.Status.String(),
+		Error:        r.Error,
+		ErrorCode:    r.ErrorCode,
+		Source:       r.Source,
+		CheckedAt:    r.CheckedAt.Format(time.RFC3339),
+		Duration:     r.Duration.Milliseconds(),
+		Registration: r.Registration,
+		Confidence:   r.Confidence.String(),
+		Attempts:     r.Attempts,
+		Cached:       r.Cached,
+		Age:          int64(r.Age / time.Second),
 	})
 }
 
@@ -222,9 +222,10 @@ func TestBackwardCompatibility_DomainNormalization(t *testing.T) {
 		{"example.org", "example.org", true},
 		{"EXAMPLE.ORG", "example.org", true},
 
-		// Multi-label domains
-		{"sub.example.com", "sub.example.com", true},
-		{"deep.sub.example.com", "deep.sub.example.com", true},
+		// Multi-label domains are reduced to the registrable domain
+		{"sub.example.com", "example.com", true},
+		{"deep.sub.example.com", "example.com", true},
+		{"shop.example.co.uk", "example.co.uk", true},
 
 		// Invalid inputs (v1.x would reject these too)
 		{"invalid..domain", "", false},
@@ -3,7 +3,9 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -13,20 +15,135 @@ import (
 
 	"domaincheck/internal/checker"
 	"domaincheck/internal/domain"
+	"domaincheck/internal/store"
 )
 
 const (
 	// maxDomainsPerRequest limits bulk domain checks to prevent abuse
 	maxDomainsPerRequest = 100
 
-	// maxConcurrent limits parallel domain checks to prevent resource exhaustion
+	// maxConcurrent limits the parallel checks of one bulk request; the
+	// server-wide limit is the scheduler's (see SetScheduler)
 	maxConcurrent = 10
 
 	// requestTimeout is the maximum time allowed for a bulk check request
 	requestTimeout = 60 * time.Second
 )
 
+// checkFunc is the signature shared by checker.Check and checker.CheckConsensus.
+type checkFunc func(ctx context.Context, d domain.Domain) (domain.Result, error)
+
+// checkFuncFor selects how domains are checked from the "mode" query parameter.
+//
+// Modes:
+//   - "" or "fast" → checker.Check (stops at the first answer)
+//   - "consensus"  → checker.CheckConsensus (queries every source, flags conflicts)
+func checkFuncFor(r *http.Request) (checkFunc, error) {
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "", "fast":
+		return checker.Check, nil
+	case "consensus":
+		return checker.CheckConsensus, nil
+	default:
+		return nil, fmt.Errorf("unknown mode: %q", mode)
+	}
+}
+
+// invalidDomainDetail returns ": <reason>" for inputs Normalize rejected for
+// a specific reason (e.g. "disallowed character: idna: disallowed rune
+// U+2488" for an internationalized name), or "" for a plain format error.
+func invalidDomainDetail(err error) string {
+	if err == domain.ErrInvalidFormat || !errors.Is(err, domain.ErrInvalidFormat) {
+		return ""
+	}
+	return strings.TrimPrefix(err.Error(), domain.ErrInvalidFormat.Error())
+}
+
+// consensusMode reports whether the request selects ?mode=consensus.
+// The mode must have been validated with checkFuncFor.
+func consensusMode(r *http.Request) bool {
+	return r.URL.Query().Get("mode") == "consensus"
+}
+
+// withFresh makes checks bypass the result cache when the request asks for it
+// with ?fresh=1 (or ?fresh=true).
+func withFresh(ctx context.Context, r *http.Request) context.Context {
+	switch r.URL.Query().Get("fresh") {
+	case "1", "true":
+		return checker.WithFresh(ctx)
+	default:
+		return ctx
+	}
+}
+
+// resultStore holds the store set via SetStore; nil disables persistence.
+var resultStore = struct {
+	sync.RWMutex
+	store store.Store
+}{}
+
+// SetStore configures where the handlers persist check results.
+// This should be called once at startup; nil disables persistence.
+func SetStore(s store.Store) {
+	resultStore.Lock()
+	resultStore.store = s
+	resultStore.Unlock()
+}
+
+// adminToken holds the token set via SetAdminToken.
+var adminToken = struct {
+	sync.RWMutex
+	token string
+}{}
+
+// SetAdminToken sets the bearer token the /admin endpoints require
+// ("Authorization: Bearer <token>"). They expose upstream failures and
+// per-client load, so without a token (the default) they answer 404.
+func SetAdminToken(token string) {
+	adminToken.Lock()
+	adminToken.token = token
+	adminToken.Unlock()
+}
+
+// authorizeAdmin checks the admin token of a request to an /admin endpoint.
+// It writes the error response and returns false when the request may not
+// proceed.
+func authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	adminToken.RLock()
+	token := adminToken.token
+	adminToken.RUnlock()
+
+	if token == "" {
+		http.NotFound(w, r)
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// persist saves a freshly checked result to the configured store.
+// Results served from the cache are already stored and are skipped.
+func persist(result domain.Result) {
+	resultStore.RLock()
+	s := resultStore.store
+	resultStore.RUnlock()
+
+	if s == nil || result.Cached || result.Domain.Full == "" {
+		return
+	}
+	if err := s.Put(result); err != nil {
+		log.Printf("Failed to persist result for %s: %v", result.Domain.Full, err)
+	}
+}
+
 // CheckDomainsHandler handles POST /check for bulk domain availability checking.
+// Add ?mode=consensus to cross-check every domain against all sources and
+// ?fresh=1 to bypass cached results.
 //
 // Request Body:
 //
@@ -47,10 +164,19 @@ const (
 //	  "errors": 0
 //	}
 //
+// With ?stream=1 the response is newline-delimited JSON
+// (application/x-ndjson) written as checks complete, one line per domain in
+// completion order, followed by the counts:
+//
+//	{"index": 1, "result": {"domain": "test.org", "available": true, ...}}
+//	{"index": 0, "result": {"domain": "example.com", "available": false, ...}}
+//	{"summary": {"checked": 2, "available": 1, "taken": 1, "errors": 0}}
+//
 // The handler:
-//   - Validates the request (max 100 domains)
+//   - Validates the request (max 100 domains, known mode)
 //   - Normalizes domain inputs
-//   - Checks domains concurrently (max 10 parallel)
+//   - Checks domains concurrently with checker.CheckStream (max 10 parallel,
+//     queued fairly with other requests by the shared scheduler)
 //   - Returns aggregated results with counts
 func CheckDomainsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -58,6 +184,11 @@ func CheckDomainsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, err := checkFuncFor(r); err != nil {
+		http.Error(w, "Invalid mode", http.StatusBadRequest)
+		return
+	}
+
 	// SECURITY: Validate CSRF token for dashboard form submissions
 	// API clients without CSRF tokens are still allowed (backward compatibility)
 	csrfToken := r.Header.Get("X-CSRF-Token")
@@ -89,65 +220,82 @@ func CheckDomainsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// SECURITY: Add explicit request timeout to prevent long-running requests
-	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	ctx, cancel := context.WithTimeout(withFresh(r.Context(), r), requestTimeout)
 	defer cancel()
 
-	// Normalize domains first
-	normalizedDomains := make([]domain.Domain, len(req.Domains))
-	normErrors := make([]error, len(req.Domains))
-	for i, input := range req.Domains {
-		normalizedDomains[i], normErrors[i] = domain.Normalize(input)
-	}
-
-	// Check domains concurrently with semaphore
+	// Normalize domains first; only valid ones are checked
 	results := make([]domain.Result, len(req.Domains))
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, maxConcurrent)
-
-	for i := range req.Domains {
-		wg.Add(1)
-		go func(idx int) {
-			defer wg.Done()
-
-			// SECURITY: Respect context cancellation at semaphore acquisition
-			select {
-			case sem <- struct{}{}: // Acquire
-				defer func() { <-sem }() // Release
-			case <-ctx.Done():
-				// Context cancelled while waiting for semaphore
-				results[idx] = domain.Result{
-					Domain:    normalizedDomains[idx],
-					Status:    domain.StatusError,
-					Available: false,
-					Error:     "request cancelled",
-				}
-				return
+	done := make([]bool, len(req.Domains))
+	domains := make([]domain.Domain, len(req.Domains))
+	valid := make([]int, 0, len(req.Domains)) // stream index → request index
+	for i, input := range req.Domains {
+		var err error
+		domains[i], err = domain.Normalize(input)
+		if err != nil {
+			results[i] = domain.Result{
+				Domain:    domain.Domain{Full: input},
+				Status:    domain.StatusError,
+				Available: false,
+				Error:     "invalid domain format" + invalidDomainDetail(err),
+				ErrorCode: checker.CodeInvalidDomain,
 			}
+			done[i] = true
+			continue
+		}
+		valid = append(valid, i)
+	}
 
-			// If normalization failed, create error result
-			if normErrors[idx] != nil {
-				results[idx] = domain.Result{
-					Domain:    domain.Domain{Full: req.Domains[idx]},
-					Status:    domain.StatusError,
-					Available: false,
-					Error:     "invalid domain format",
-				}
-				return
+	var stream *resultStream
+	if streaming(r) {
+		stream = newResultStream(w)
+		for i := range results {
+			if done[i] {
+				stream.send(i, results[i])
 			}
+		}
+	}
 
-			// Perform the check with request context
-			result, err := checker.Check(ctx, normalizedDomains[idx])
-			if err != nil {
-				// Check failed - result already has error info
-				results[idx] = result
-				return
-			}
+	in := make(chan domain.Domain, len(valid))
+	for _, i := range valid {
+		in <- domains[i]
+	}
+	close(in)
 
-			results[idx] = result
-		}(i)
+	// Check domains concurrently (max 10 parallel), each in a bulk slot of the
+	// shared scheduler. Duplicate inputs share one in-flight check but each
+	// keeps its own position in results.
+	sched, client := currentScheduler(), clientID(r)
+	opts := checker.StreamOptions{
+		Concurrency: maxConcurrent,
+		Consensus:   consensusMode(r),
+		Acquire: func(ctx context.Context) (func(), error) {
+			return sched.Acquire(ctx, client, PriorityBulk)
+		},
+	}
+	for sr := range checker.CheckStream(ctx, in, opts) {
+		persist(sr.Result)
+		idx := valid[sr.Index]
+		results[idx], done[idx] = sr.Result, true
+		if stream != nil {
+			stream.send(idx, sr.Result)
+		}
 	}
 
-	wg.Wait()
+	// SECURITY: Domains not checked before the request timeout are reported as cancelled
+	for i := range results {
+		if !done[i] {
+			results[i] = domain.Result{
+				Domain:    domains[i],
+				Status:    domain.StatusError,
+				Available: false,
+				Error:     "request cancelled",
+				ErrorCode: checker.CodeCancelled,
+			}
+			if stream != nil {
+				stream.send(i, results[i])
+			}
+		}
+	}
 
 	// Build response with counts
 	response := domain.CheckResponse{
@@ -165,6 +313,11 @@ func CheckDomainsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if stream != nil {
+		stream.finish(response)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		// Log encoding error (headers already sent, can't change status)
@@ -172,9 +325,77 @@ func CheckDomainsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// streaming reports whether the request asks for results as they complete
+// with ?stream=1 (or ?stream=true).
+func streaming(r *http.Request) bool {
+	switch r.URL.Query().Get("stream") {
+	case "1", "true":
+		return true
+	default:
+		return false
+	}
+}
+
+// streamLine is one line of a streamed bulk check: a result and its
+// position in the request's domains.
+type streamLine struct {
+	Index  int           `json:"index"`
+	Result domain.Result `json:"result"`
+}
+
+// streamSummary is the last line of a streamed bulk check.
+type streamSummary struct {
+	Checked   int `json:"checked"`
+	Available int `json:"available"`
+	Taken     int `json:"taken"`
+	Errors    int `json:"errors"`
+}
+
+// resultStream writes newline-delimited JSON, flushing every line so
+// clients see progress while the batch is checked.
+type resultStream struct {
+	enc     *json.Encoder
+	flusher http.Flusher
+}
+
+// newResultStream starts a 200 NDJSON response.
+func newResultStream(w http.ResponseWriter) *resultStream {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return &resultStream{enc: json.NewEncoder(w), flusher: flusher}
+}
+
+// send writes one result line.
+func (s *resultStream) send(index int, result domain.Result) {
+	s.write(streamLine{Index: index, Result: result})
+}
+
+// finish writes the summary line ({"summary": {...}}).
+func (s *resultStream) finish(response domain.CheckResponse) {
+	s.write(map[string]streamSummary{"summary": {
+		Checked:   response.Checked,
+		Available: response.Available,
+		Taken:     response.Taken,
+		Errors:    response.Errors,
+	}})
+}
+
+func (s *resultStream) write(v interface{}) {
+	if err := s.enc.Encode(v); err != nil {
+		// Headers already sent, can't change status
+		log.Printf("Failed to encode stream line: %v", err)
+		return
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+}
+
 // CheckSingleDomainHandler handles GET /check/{domain} for single domain checks.
 //
-// URL: /check/example.com
+// URL: /check/example.com (add ?mode=consensus to cross-check all sources,
+// ?fresh=1 to bypass cached results)
 //
 // Response:
 //
@@ -196,6 +417,12 @@ func CheckSingleDomainHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	check, err := checkFuncFor(r)
+	if err != nil {
+		http.Error(w, "Invalid mode", http.StatusBadRequest)
+		return
+	}
+
 	// Extract domain from URL path (/check/{domain})
 	path := strings.TrimPrefix(r.URL.Path, "/check/")
 	if path == "" || path == r.URL.Path {
@@ -206,12 +433,20 @@ func CheckSingleDomainHandler(w http.ResponseWriter, r *http.Request) {
 	// Normalize domain
 	d, err := domain.Normalize(path)
 	if err != nil {
-		http.Error(w, "Invalid domain format", http.StatusBadRequest)
+		http.Error(w, "Invalid domain format"+invalidDomainDetail(err), http.StatusBadRequest)
 		return
 	}
 
-	// Perform check
-	result, err := checker.Check(r.Context(), d)
+	// Perform check in an interactive slot, ahead of queued bulk checks
+	ctx := withFresh(r.Context(), r)
+	release, err := currentScheduler().Acquire(ctx, clientID(r), PriorityInteractive)
+	if err != nil {
+		http.Error(w, "Request cancelled", http.StatusServiceUnavailable)
+		return
+	}
+	result, err := check(ctx, d)
+	release()
+	persist(result)
 	if err != nil {
 		// Result already contains error info
 		w.Header().Set("Content-Type", "application/json")
@@ -248,3 +483,129 @@ func HealthHandler(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Failed to encode health response: %v", err)
 	}
 }
+
+// TLDsHandler handles GET /tlds, listing the top-level domains names can be
+// checked under, with their registry metadata. The optional "type" query
+// parameter filters by category (generic, country-code, sponsored,
+// generic-restricted, infrastructure).
+//
+// Response:
+//
+//	{
+//	  "version": "2026101600",
+//	  "count": 2,
+//	  "tlds": [
+//	    {"tld": "com", "type": "generic", "operator": "VeriSign Global Registry Services",
+//	     "rdap": "https://rdap.verisign.com/com/v1/", "whois": "whois.verisign-grs.com", "idn": true},
+//	    {"tld": "xn--p1ai", "unicode": "рф", "type": "country-code", ...}
+//	  ]
+//	}
+//
+// "count" is the number of TLDs listed. Metadata the registry does not know
+// (operator, rdap, whois, idn) is omitted.
+//
+// Names under any other TLD are rejected as invalid before being checked.
+func TLDsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	registry := domain.CurrentTLDRegistry()
+	tlds := registry.All()
+	if typ := domain.TLDType(r.URL.Query().Get("type")); typ != "" {
+		switch typ {
+		case domain.TLDGeneric, domain.TLDCountryCode, domain.TLDSponsored, domain.TLDGenericRestricted, domain.TLDInfrastructure:
+		default:
+			http.Error(w, fmt.Sprintf("Unknown TLD type %q", typ), http.StatusBadRequest)
+			return
+		}
+		filtered := tlds[:0]
+		for _, info := range tlds {
+			if info.Type == typ {
+				filtered = append(filtered, info)
+			}
+		}
+		tlds = filtered
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Version string           `json:"version,omitempty"`
+		Count   int              `json:"count"`
+		TLDs    []domain.TLDInfo `json:"tlds"`
+	}{registry.Version(), len(tlds), tlds}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode TLDs response: %v", err)
+	}
+}
+
+// BreakersHandler handles GET /admin/breakers, reporting the circuit breaker
+// of every upstream that has failed since startup.
+//
+// Response:
+//
+//	{
+//	  "breakers": [
+//	    {
+//	      "upstream": "whois/whois.nic.example",
+//	      "state": "open",
+//	      "failures": 5,
+//	      "opened_at": "2026-01-01T12:00:00Z",
+//	      "last_error": "whois timeout: ..."
+//	    }
+//	  ]
+//	}
+//
+// States are "closed", "open" (queries fail fast) and "half-open" (the next
+// query probes whether the upstream recovered).
+//
+// Requires the admin token (see SetAdminToken).
+func BreakersHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := map[string][]checker.BreakerStatus{"breakers": checker.BreakerStatuses()}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode breakers response: %v", err)
+	}
+}
+
+// SchedulerHandler handles GET /admin/scheduler, reporting the shared check
+// scheduler's load and queues.
+//
+// Response:
+//
+//	{
+//	  "capacity": 20,
+//	  "running": 20,
+//	  "queued": 135,
+//	  "classes": [
+//	    {"priority": "interactive", "queued": 1, "clients": 1, "granted": 42,
+//	     "avg_wait_ms": 3.2, "max_wait_ms": 180, "oldest_wait_ms": 12},
+//	    {"priority": "bulk", "queued": 134, "clients": 3, "granted": 5120,
+//	     "avg_wait_ms": 850.4, "max_wait_ms": 9400, "oldest_wait_ms": 2100}
+//	  ]
+//	}
+//
+// Requires the admin token (see SetAdminToken).
+func SchedulerHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(currentScheduler().Stats()); err != nil {
+		log.Printf("Failed to encode scheduler response: %v", err)
+	}
+}
@@ -1,13 +1,18 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"domaincheck/internal/checker"
 	"domaincheck/internal/domain"
+	"domaincheck/internal/store"
 )
 
 // testResult matches the JSON output structure from Result.MarshalJSON
@@ -169,6 +174,13 @@ func TestCheckSingleDomainHandler(t *testing.T) {
 			wantStatus: http.StatusMethodNotAllowed,
 			wantError:  true,
 		},
+		{
+			name:       "unknown mode",
+			method:     http.MethodGet,
+			path:       "/check/example?mode=bogus",
+			wantStatus: http.StatusBadRequest,
+			wantError:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -202,6 +214,79 @@ func TestCheckSingleDomainHandler(t *testing.T) {
 	}
 }
 
+func TestCheckFuncFor(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{"", false},
+		{"?mode=fast", false},
+		{"?mode=consensus", false},
+		{"?mode=CONSENSUS", true},
+		{"?mode=all", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/check/example.com"+tt.query, nil)
+			check, err := checkFuncFor(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkFuncFor() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && check == nil {
+				t.Error("checkFuncFor() returned nil check function")
+			}
+		})
+	}
+}
+
+func TestInvalidDomainDetail(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"-example.com", ""},
+		{"a⒈b.com", ": disallowed character"},
+		{"xn--ab!.com", ": invalid punycode label"},
+		{"ab--cd.com", ": hyphens in 3rd and 4th position are reserved"},
+		{"example.123", ": invalid top-level domain"},
+		{"example..com", ": empty label"},
+		{"example.notatld", ": unknown top-level domain"},
+	}
+	for _, tt := range tests {
+		_, err := domain.Normalize(tt.input)
+		if err == nil {
+			t.Fatalf("Normalize(%q) error = nil", tt.input)
+		}
+		if got := invalidDomainDetail(err); !strings.HasPrefix(got, tt.want) || (tt.want == "") != (got == "") {
+			t.Errorf("invalidDomainDetail(%v) = %q, want prefix %q", err, got, tt.want)
+		}
+	}
+}
+
+func TestWithFresh(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", false},
+		{"?fresh=1", true},
+		{"?fresh=true", true},
+		{"?fresh=0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/check/example.com"+tt.query, nil)
+			ctx := withFresh(req.Context(), req)
+			if got := ctx != req.Context(); got != tt.want {
+				t.Errorf("withFresh() marked = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestHealthHandler(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -287,3 +372,218 @@ func TestCheckDomainsHandlerConcurrency(t *testing.T) {
 		}
 	}
 }
+
+func TestPersist(t *testing.T) {
+	s, err := store.Open(filepath.Join(t.TempDir(), "results.jsonl"))
+	if err != nil {
+		t.Fatalf("store.Open() error = %v", err)
+	}
+	defer s.Close()
+	SetStore(s)
+	defer SetStore(nil)
+
+	fresh := domain.Result{Domain: domain.Domain{Full: "fresh.com", Name: "fresh", TLD: "com"}, Status: domain.StatusTaken}
+	cached := domain.Result{Domain: domain.Domain{Full: "cached.com", Name: "cached", TLD: "com"}, Status: domain.StatusTaken, Cached: true}
+	persist(fresh)
+	persist(cached)
+	persist(domain.Result{Status: domain.StatusError})
+
+	if _, ok, _ := s.Get("fresh.com"); !ok {
+		t.Error("fresh result was not persisted")
+	}
+	if _, ok, _ := s.Get("cached.com"); ok {
+		t.Error("cached result was persisted again")
+	}
+	if s.Len() != 1 {
+		t.Errorf("store Len() = %d, want 1", s.Len())
+	}
+}
+
+func TestBreakersHandler(t *testing.T) {
+	breakers := checker.NewBreakers(checker.BreakerConfig{Threshold: 1})
+	breakers.Record("whois", "whois.example", errors.New("connection refused"))
+	checker.SetBreakers(breakers)
+	defer checker.SetBreakers(checker.NewBreakers(checker.BreakerConfig{}))
+	SetAdminToken("secret")
+	defer SetAdminToken("")
+
+	w := httptest.NewRecorder()
+	BreakersHandler(w, adminRequest(http.MethodGet, "/admin/breakers"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("BreakersHandler() status = %v, want 200", w.Code)
+	}
+
+	var resp struct {
+		Breakers []checker.BreakerStatus `json:"breakers"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Breakers) != 1 || resp.Breakers[0].Upstream != "whois/whois.example" || resp.Breakers[0].State != "open" {
+		t.Errorf("BreakersHandler() breakers = %+v", resp.Breakers)
+	}
+
+	w = httptest.NewRecorder()
+	BreakersHandler(w, adminRequest(http.MethodPost, "/admin/breakers"))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %v, want 405", w.Code)
+	}
+}
+
+// adminRequest returns a request to an /admin endpoint with the token the
+// tests set ("secret").
+func adminRequest(method, target string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	return req
+}
+
+func TestAuthorizeAdmin(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		want          int
+	}{
+		{"disabled without token", "", "Bearer secret", http.StatusNotFound},
+		{"missing header", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer guess", http.StatusUnauthorized},
+		{"wrong scheme", "secret", "Basic secret", http.StatusUnauthorized},
+		{"valid token", "secret", "Bearer secret", http.StatusOK},
+	}
+	defer SetAdminToken("")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetAdminToken(tt.token)
+			req := httptest.NewRequest(http.MethodGet, "/admin/breakers", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			BreakersHandler(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %v, want %v", w.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestTLDsHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		wantStatus int
+		wantType   domain.TLDType
+	}{
+		{"all", http.MethodGet, "", http.StatusOK, ""},
+		{"filtered", http.MethodGet, "?type=country-code", http.StatusOK, domain.TLDCountryCode},
+		{"unknown type", http.MethodGet, "?type=brand", http.StatusBadRequest, ""},
+		{"POST method not allowed", http.MethodPost, "", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			TLDsHandler(w, httptest.NewRequest(tt.method, "/tlds"+tt.query, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("TLDsHandler() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Version string           `json:"version"`
+				Count   int              `json:"count"`
+				TLDs    []domain.TLDInfo `json:"tlds"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Version == "" || resp.Count == 0 || resp.Count != len(resp.TLDs) {
+				t.Errorf("TLDsHandler() version %q, count %d, %d TLDs", resp.Version, resp.Count, len(resp.TLDs))
+			}
+			all := domain.CurrentTLDRegistry().Len()
+			if tt.wantType == "" && resp.Count != all {
+				t.Errorf("TLDsHandler() count = %d, want %d", resp.Count, all)
+			}
+			for _, info := range resp.TLDs {
+				if tt.wantType != "" && info.Type != tt.wantType {
+					t.Errorf("TLDsHandler(%s) returned %s of type %s", tt.query, info.TLD, info.Type)
+				}
+				if info.TLD == "com" && (info.RDAP == "" || info.WHOIS == "") {
+					t.Errorf("TLDsHandler() com = %+v, want RDAP and WHOIS servers", info)
+				}
+			}
+		})
+	}
+}
+
+// prefixSource reports names starting with "taken" as taken and others as available.
+type prefixSource struct{}
+
+func (prefixSource) Name() string { return "prefix" }
+
+func (prefixSource) Supports(tld string) bool { return true }
+
+func (prefixSource) Check(ctx context.Context, d domain.Domain) (checker.Verdict, error) {
+	if strings.HasPrefix(d.Name, "taken") {
+		return checker.Verdict{Status: domain.StatusTaken}, nil
+	}
+	return checker.Verdict{Status: domain.StatusAvailable}, nil
+}
+
+// TestCheckDomainsHandlerStream verifies ?stream=1 writes one NDJSON line per
+// domain, tagged with its request index, and a summary line
+func TestCheckDomainsHandlerStream(t *testing.T) {
+	checker.SetDefault(checker.New(
+		checker.WithPipeline(checker.NewPipeline(checker.Stage{Source: prefixSource{}})),
+		checker.WithCache(nil),
+	))
+	t.Cleanup(func() { checker.SetDefault(checker.New()) })
+
+	body := `{"domains": ["taken1.com", "free1.com", "not a domain!", "free2.com"]}`
+	req := httptest.NewRequest(http.MethodPost, "/check?stream=1", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	CheckDomainsHandler(w, req)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("status = %d, Content-Type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want 5:\n%s", len(lines), w.Body.String())
+	}
+	want := map[int]string{0: "taken1.com", 1: "free1.com", 2: "not a domain!", 3: "free2.com"}
+	for _, line := range lines[:4] {
+		var got struct {
+			Index  int        `json:"index"`
+			Result testResult `json:"result"`
+		}
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		if got.Result.Domain != want[got.Index] {
+			t.Errorf("index %d is %q, want %q", got.Index, got.Result.Domain, want[got.Index])
+		}
+		delete(want, got.Index)
+	}
+	if len(want) != 0 {
+		t.Errorf("no lines for %v", want)
+	}
+
+	var summary struct {
+		Summary testCheckResponse `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(lines[4]), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if s := summary.Summary; s.Checked != 4 || s.Available != 2 || s.Taken != 1 || s.Errors != 1 {
+		t.Errorf("summary = %+v, want 4 checked, 2 available, 1 taken, 1 error", s)
+	}
+}
@@ -0,0 +1,441 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSchedulerConcurrency is the default number of checks running at
+// once across all requests.
+const defaultSchedulerConcurrency = 20
+
+// Priority is the scheduling class of a check. Queued checks of a higher
+// class always start before those of a lower one.
+type Priority int
+
+const (
+	// PriorityInteractive is for single-domain checks a user is waiting on
+	PriorityInteractive Priority = iota
+
+	// PriorityBulk is for the domains of bulk requests
+	PriorityBulk
+
+	numPriorities
+)
+
+// String returns the priority's name ("interactive" or "bulk").
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBulk:
+		return "bulk"
+	default:
+		return "unknown"
+	}
+}
+
+// SchedulerConfig configures a Scheduler.
+type SchedulerConfig struct {
+	// Concurrency is the number of checks running at once across all
+	// requests (default 20)
+	Concurrency int
+}
+
+// Scheduler is a server-wide pool of check slots shared by all requests.
+//
+// When every slot is busy, checks queue per client and priority class. A
+// freed slot goes to the highest class with queued checks; within a class,
+// clients take turns (round-robin), each client's checks in arrival order.
+// A client submitting 100 domains therefore delays another client's single
+// domain by at most one turn, not by 100 checks.
+//
+// A Scheduler is safe for concurrent use.
+type Scheduler struct {
+	capacity int
+
+	mu      sync.Mutex
+	running int
+	classes [numPriorities]schedClass
+}
+
+// schedClass holds the queued checks of one priority class.
+type schedClass struct {
+	queues map[string][]*schedWaiter // client → FIFO of waiters
+	order  []string                  // clients with queued checks, in turn order
+	next   int                       // position in order of the next turn
+	queued int
+
+	granted   uint64
+	totalWait time.Duration
+	maxWait   time.Duration
+}
+
+// schedWaiter is one queued check.
+type schedWaiter struct {
+	ready    chan struct{}
+	granted  bool
+	enqueued time.Time
+}
+
+// NewScheduler creates a scheduler. See SchedulerConfig for defaults.
+func NewScheduler(cfg SchedulerConfig) *Scheduler {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultSchedulerConcurrency
+	}
+	s := &Scheduler{capacity: cfg.Concurrency}
+	for i := range s.classes {
+		s.classes[i].queues = make(map[string][]*schedWaiter)
+	}
+	return s
+}
+
+// Acquire waits for a check slot for client at priority p. The returned
+// release function frees the slot and must be called exactly once when the
+// check is done. Acquire fails with ctx's error when ctx is done first.
+func (s *Scheduler) Acquire(ctx context.Context, client string, p Priority) (release func(), err error) {
+	if p < 0 || p >= numPriorities {
+		p = PriorityBulk
+	}
+
+	s.mu.Lock()
+	if s.running < s.capacity && s.queuedLocked() == 0 {
+		s.running++
+		s.classes[p].granted++
+		s.mu.Unlock()
+		return s.releaseFunc(), nil
+	}
+	w := &schedWaiter{ready: make(chan struct{}), enqueued: time.Now()}
+	s.classes[p].push(client, w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return s.releaseFunc(), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if w.granted {
+			// Granted while giving up: hand the slot on
+			s.running--
+			s.dispatchLocked()
+		} else {
+			s.classes[p].remove(client, w)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// releaseFunc returns a function freeing one slot, at most once.
+func (s *Scheduler) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.running--
+			s.dispatchLocked()
+			s.mu.Unlock()
+		})
+	}
+}
+
+// dispatchLocked hands free slots to queued checks. s.mu must be held.
+func (s *Scheduler) dispatchLocked() {
+	for s.running < s.capacity {
+		var w *schedWaiter
+		for p := range s.classes {
+			c := &s.classes[p]
+			if w = c.pop(); w != nil {
+				wait := time.Since(w.enqueued)
+				c.granted++
+				c.totalWait += wait
+				if wait > c.maxWait {
+					c.maxWait = wait
+				}
+				break
+			}
+		}
+		if w == nil {
+			return
+		}
+		s.running++
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+// queuedLocked returns the number of queued checks. s.mu must be held.
+func (s *Scheduler) queuedLocked() int {
+	n := 0
+	for p := range s.classes {
+		n += s.classes[p].queued
+	}
+	return n
+}
+
+// push queues w at the end of client's queue.
+func (c *schedClass) push(client string, w *schedWaiter) {
+	q, ok := c.queues[client]
+	if !ok {
+		// New clients wait for their turn behind those already queued
+		c.order = append(c.order, client)
+	}
+	c.queues[client] = append(q, w)
+	c.queued++
+}
+
+// pop takes the first check of the client whose turn it is, or returns nil.
+func (c *schedClass) pop() *schedWaiter {
+	if c.queued == 0 {
+		return nil
+	}
+	if c.next >= len(c.order) {
+		c.next = 0
+	}
+	client := c.order[c.next]
+	q := c.queues[client]
+	w := q[0]
+	q[0] = nil
+	c.queued--
+	if len(q) == 1 {
+		c.dropClient(c.next)
+	} else {
+		c.queues[client] = q[1:]
+		c.next++
+	}
+	return w
+}
+
+// remove takes a cancelled waiter out of client's queue.
+func (c *schedClass) remove(client string, w *schedWaiter) {
+	q := c.queues[client]
+	for i, qw := range q {
+		if qw != w {
+			continue
+		}
+		c.queued--
+		if len(q) > 1 {
+			c.queues[client] = append(q[:i:i], q[i+1:]...)
+			return
+		}
+		for pos, name := range c.order {
+			if name == client {
+				c.dropClient(pos)
+				break
+			}
+		}
+		return
+	}
+}
+
+// dropClient removes the client at position pos of the turn order, whose
+// queue is empty, keeping the turn with the client after it.
+func (c *schedClass) dropClient(pos int) {
+	delete(c.queues, c.order[pos])
+	c.order = append(c.order[:pos], c.order[pos+1:]...)
+	if pos < c.next {
+		c.next--
+	}
+}
+
+// SchedulerStats is a snapshot of the scheduler for monitoring.
+type SchedulerStats struct {
+	// Capacity is the number of checks that may run at once
+	Capacity int `json:"capacity"`
+
+	// Running is the number of checks holding a slot
+	Running int `json:"running"`
+
+	// Queued is the number of checks waiting for a slot, all classes together
+	Queued int `json:"queued"`
+
+	// Classes breaks the queue down by priority, highest first
+	Classes []PriorityStats `json:"classes"`
+}
+
+// PriorityStats describes the queue of one priority class.
+type PriorityStats struct {
+	// Priority is the class name ("interactive" or "bulk")
+	Priority string `json:"priority"`
+
+	// Queued is the number of checks of the class waiting for a slot
+	Queued int `json:"queued"`
+
+	// Clients is the number of clients with queued checks
+	Clients int `json:"clients"`
+
+	// Granted counts the slots handed out since startup
+	Granted uint64 `json:"granted"`
+
+	// AvgWait and MaxWait are the mean and longest queueing delays since
+	// startup, in milliseconds; checks started immediately count as no wait
+	AvgWait float64 `json:"avg_wait_ms"`
+	MaxWait int64   `json:"max_wait_ms"`
+
+	// OldestWait is how long the longest-queued check has waited so far, in milliseconds
+	OldestWait int64 `json:"oldest_wait_ms"`
+}
+
+// Stats returns the scheduler's current queue depths and wait times.
+func (s *Scheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := SchedulerStats{Capacity: s.capacity, Running: s.running, Queued: s.queuedLocked()}
+	now := time.Now()
+	for p := range s.classes {
+		c := &s.classes[p]
+		ps := PriorityStats{
+			Priority: Priority(p).String(),
+			Queued:   c.queued,
+			Clients:  len(c.order),
+			Granted:  c.granted,
+			MaxWait:  c.maxWait.Milliseconds(),
+		}
+		if c.granted > 0 {
+			ps.AvgWait = float64(c.totalWait.Microseconds()) / float64(c.granted) / 1000
+		}
+		for _, q := range c.queues {
+			if wait := now.Sub(q[0].enqueued).Milliseconds(); wait > ps.OldestWait {
+				ps.OldestWait = wait
+			}
+		}
+		stats.Classes = append(stats.Classes, ps)
+	}
+	return stats
+}
+
+// scheduler holds the Scheduler set via SetScheduler.
+var scheduler = struct {
+	sync.RWMutex
+	s *Scheduler
+}{
+	s: NewScheduler(SchedulerConfig{}),
+}
+
+// SetScheduler replaces the scheduler shared by the check handlers.
+// This should be called once at startup.
+func SetScheduler(s *Scheduler) {
+	if s == nil {
+		s = NewScheduler(SchedulerConfig{})
+	}
+	scheduler.Lock()
+	scheduler.s = s
+	scheduler.Unlock()
+}
+
+// currentScheduler returns the configured scheduler.
+func currentScheduler() *Scheduler {
+	scheduler.RLock()
+	defer scheduler.RUnlock()
+	return scheduler.s
+}
+
+// clientIPHeader holds the header set via SetClientIPHeader and the proxies
+// set via SetTrustedProxies.
+var clientIPHeader = struct {
+	sync.RWMutex
+	name    string
+	proxies []netip.Prefix
+}{}
+
+// SetClientIPHeader makes the scheduler identify clients by a header set by
+// a trusted reverse proxy (e.g. "X-Forwarded-For" or "X-Real-IP") instead of
+// the connection's remote address. The header is only read from requests
+// whose remote address is one of the proxies set with SetTrustedProxies:
+// clients could otherwise claim any identity. An empty name disables it.
+func SetClientIPHeader(name string) {
+	clientIPHeader.Lock()
+	clientIPHeader.name = name
+	clientIPHeader.Unlock()
+}
+
+// SetTrustedProxies sets the reverse proxies whose client IP header is
+// trusted (see SetClientIPHeader). nil trusts none.
+func SetTrustedProxies(proxies []netip.Prefix) {
+	clientIPHeader.Lock()
+	clientIPHeader.proxies = proxies
+	clientIPHeader.Unlock()
+}
+
+// ParseTrustedProxies parses a comma-separated list of IP addresses and
+// CIDR prefixes (e.g. "10.0.0.0/8, 192.0.2.7") for SetTrustedProxies.
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if strings.Contains(field, "/") {
+			prefix, err := netip.ParsePrefix(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", field, err)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", field, err)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
+
+// clientID identifies the client of a request for fair scheduling: the
+// remote IP address or, for requests from a trusted proxy, the address that
+// proxy put in the client IP header.
+func clientID(r *http.Request) string {
+	clientIPHeader.RLock()
+	header, proxies := clientIPHeader.name, clientIPHeader.proxies
+	clientIPHeader.RUnlock()
+
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if header == "" || !isTrustedProxy(proxies, remote) {
+		return remote
+	}
+
+	// Each proxy appends the address it got the request from, so entries on
+	// the left are whatever the client sent: walk back from the right past
+	// the trusted proxies to the first address they did not vouch for
+	entries := strings.Split(strings.Join(r.Header.Values(header), ","), ",")
+	for i := len(entries) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(entries[i])
+		if ip == "" {
+			continue
+		}
+		if _, err := netip.ParseAddr(ip); err != nil {
+			break
+		}
+		if !isTrustedProxy(proxies, ip) {
+			return ip
+		}
+	}
+	return remote
+}
+
+// isTrustedProxy reports whether ip belongs to one of proxies.
+func isTrustedProxy(proxies []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range proxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
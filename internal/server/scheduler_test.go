@@ -0,0 +1,329 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// queueCheck starts an Acquire in the background; the returned channel
+// receives the release function once the slot is granted.
+func queueCheck(t *testing.T, s *Scheduler, client string, p Priority) <-chan func() {
+	t.Helper()
+	granted := make(chan func(), 1)
+	go func() {
+		release, err := s.Acquire(context.Background(), client, p)
+		if err != nil {
+			t.Errorf("Acquire(%s) error = %v", client, err)
+			return
+		}
+		granted <- release
+	}()
+	return granted
+}
+
+// waitQueued waits until n checks are queued.
+func waitQueued(t *testing.T, s *Scheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for s.Stats().Queued != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued = %d, want %d", s.Stats().Queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// grantOrder releases the running slot and records which queued check gets
+// the freed slot, one at a time.
+func grantOrder(t *testing.T, release func(), queued map[string]<-chan func()) []string {
+	t.Helper()
+	var order []string
+	for len(order) < len(queued) {
+		release()
+		deadline := time.After(2 * time.Second)
+	wait:
+		for {
+			for name, ch := range queued {
+				select {
+				case next := <-ch:
+					order = append(order, name)
+					release = next
+					break wait
+				default:
+				}
+			}
+			select {
+			case <-deadline:
+				t.Fatalf("no check granted after %v", order)
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}
+	release()
+	return order
+}
+
+func TestSchedulerCapacity(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{Concurrency: 2})
+
+	r1, err := s.Acquire(context.Background(), "a", PriorityBulk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, _ := s.Acquire(context.Background(), "a", PriorityBulk)
+
+	third := queueCheck(t, s, "b", PriorityBulk)
+	waitQueued(t, s, 1)
+	if st := s.Stats(); st.Running != 2 || st.Capacity != 2 {
+		t.Errorf("Stats() = %+v, want 2 of 2 running", st)
+	}
+
+	r1()
+	r1() // releasing twice must not free a second slot
+	r3 := <-third
+	if st := s.Stats(); st.Running != 2 || st.Queued != 0 {
+		t.Errorf("Stats() after release = %+v, want 2 running, none queued", st)
+	}
+	r2()
+	r3()
+	if st := s.Stats(); st.Running != 0 {
+		t.Errorf("Running = %d after all releases", st.Running)
+	}
+}
+
+// TestSchedulerFairness verifies clients take turns and interactive checks go first
+func TestSchedulerFairness(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{Concurrency: 1})
+	release, _ := s.Acquire(context.Background(), "busy", PriorityBulk)
+
+	queued := make(map[string]<-chan func())
+	for _, name := range []string{"heavy-1", "heavy-2", "heavy-3"} {
+		queued[name] = queueCheck(t, s, "heavy", PriorityBulk)
+		waitQueued(t, s, len(queued))
+	}
+	queued["light-1"] = queueCheck(t, s, "light", PriorityBulk)
+	waitQueued(t, s, len(queued))
+	queued["single"] = queueCheck(t, s, "light", PriorityInteractive)
+	waitQueued(t, s, len(queued))
+
+	order := grantOrder(t, release, queued)
+
+	// Interactive first, then heavy and light alternate
+	if order[0] != "single" {
+		t.Errorf("first grant = %s, want the interactive check", order[0])
+	}
+	lightAt := -1
+	for i, name := range order {
+		if name == "light-1" {
+			lightAt = i
+		}
+	}
+	if lightAt != 2 {
+		t.Errorf("grant order = %v, want light-1 third (right after one heavy check)", order)
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{Concurrency: 1})
+	release, _ := s.Acquire(context.Background(), "a", PriorityBulk)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.Acquire(ctx, "b", PriorityBulk)
+		errc <- err
+	}()
+	waitQueued(t, s, 1)
+	if st := s.Stats(); st.Classes[PriorityBulk].Clients != 1 {
+		t.Errorf("bulk clients = %d, want 1", st.Classes[PriorityBulk].Clients)
+	}
+
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire() error = %v, want context.Canceled", err)
+	}
+	if st := s.Stats(); st.Queued != 0 || st.Classes[PriorityBulk].Clients != 0 {
+		t.Errorf("Stats() after cancel = %+v, want empty queue", st)
+	}
+
+	// The slot is free again once released; nobody is left to take it
+	release()
+	r, err := s.Acquire(context.Background(), "c", PriorityInteractive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r()
+}
+
+// TestSchedulerConcurrent hammers the scheduler and checks the capacity holds
+func TestSchedulerConcurrent(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{Concurrency: 3})
+	var (
+		mu           sync.Mutex
+		running, max int
+		wg           sync.WaitGroup
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(i%5)*time.Millisecond+time.Millisecond)
+			defer cancel()
+			if i%2 == 0 {
+				ctx = context.Background()
+			}
+			release, err := s.Acquire(ctx, []string{"a", "b", "c"}[i%3], Priority(i%2))
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			running++
+			if running > max {
+				max = running
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			release()
+		}(i)
+	}
+	wg.Wait()
+
+	if max > 3 {
+		t.Errorf("peak running = %d, want <= 3", max)
+	}
+	if st := s.Stats(); st.Running != 0 || st.Queued != 0 {
+		t.Errorf("Stats() = %+v, want idle", st)
+	}
+}
+
+func TestClientID(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		proxies string
+		value   string
+		want    string
+	}{
+		{"remote address", "", "", "", "192.0.2.1"},
+		{"header not configured", "", "192.0.2.0/24", "203.0.113.9", "192.0.2.1"},
+		{"proxy not trusted", "X-Forwarded-For", "", "203.0.113.9", "192.0.2.1"},
+		{"other proxy trusted", "X-Forwarded-For", "10.0.0.0/8", "203.0.113.9", "192.0.2.1"},
+		{"forwarded for", "X-Forwarded-For", "192.0.2.1", "203.0.113.9", "203.0.113.9"},
+		{"spoofed entries ignored", "X-Forwarded-For", "192.0.2.1", "198.51.100.1, 203.0.113.9", "203.0.113.9"},
+		{"chain of trusted proxies", "X-Forwarded-For", "192.0.2.0/24, 10.0.0.0/8", "198.51.100.1, 203.0.113.9, 10.0.0.2", "203.0.113.9"},
+		{"garbage entry", "X-Forwarded-For", "192.0.2.1", "203.0.113.9, unknown", "192.0.2.1"},
+		{"header missing", "X-Forwarded-For", "192.0.2.1", "", "192.0.2.1"},
+	}
+	t.Cleanup(func() {
+		SetClientIPHeader("")
+		SetTrustedProxies(nil)
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxies, err := ParseTrustedProxies(tt.proxies)
+			if err != nil {
+				t.Fatal(err)
+			}
+			SetClientIPHeader(tt.header)
+			SetTrustedProxies(proxies)
+			req := httptest.NewRequest(http.MethodGet, "/check/example.com", nil)
+			req.RemoteAddr = "192.0.2.1:4321"
+			if tt.value != "" {
+				req.Header.Set("X-Forwarded-For", tt.value)
+			}
+			if got := clientID(req); got != tt.want {
+				t.Errorf("clientID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestClientIDSpoofedHeader checks that a client cannot get a queue of its
+// own per request by sending a different X-Forwarded-For each time.
+func TestClientIDSpoofedHeader(t *testing.T) {
+	SetClientIPHeader("X-Forwarded-For")
+	t.Cleanup(func() { SetClientIPHeader("") })
+
+	s := NewScheduler(SchedulerConfig{Concurrency: 1})
+	release, _ := s.Acquire(context.Background(), "other", PriorityBulk)
+	queued := make(map[string]<-chan func())
+	for _, spoofed := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+		req := httptest.NewRequest(http.MethodPost, "/check", nil)
+		req.RemoteAddr = "198.51.100.7:5555"
+		req.Header.Set("X-Forwarded-For", spoofed)
+		queued[spoofed] = queueCheck(t, s, clientID(req), PriorityBulk)
+	}
+	waitQueued(t, s, 3)
+	if clients := s.Stats().Classes[PriorityBulk].Clients; clients != 1 {
+		t.Errorf("bulk clients = %d, want 1 queue for the spoofing client", clients)
+	}
+	grantOrder(t, release, queued)
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies(" 10.0.0.0/8, 192.0.2.7 ,2001:db8::/32,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/8", "192.0.2.7/32", "2001:db8::/32"}
+	if len(proxies) != len(want) {
+		t.Fatalf("ParseTrustedProxies() = %v, want %v", proxies, want)
+	}
+	for i, p := range proxies {
+		if p.String() != want[i] {
+			t.Errorf("proxy %d = %s, want %s", i, p, want[i])
+		}
+	}
+	for _, bad := range []string{"10.0.0.0/33", "proxy.example", "10.0.0"} {
+		if _, err := ParseTrustedProxies(bad); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) error = nil", bad)
+		}
+	}
+}
+
+func TestSchedulerHandler(t *testing.T) {
+	SetScheduler(NewScheduler(SchedulerConfig{Concurrency: 7}))
+	t.Cleanup(func() { SetScheduler(nil) })
+
+	// Per-client queue stats are not public
+	w := httptest.NewRecorder()
+	SchedulerHandler(w, httptest.NewRequest(http.MethodGet, "/admin/scheduler", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status without ADMIN_TOKEN = %d, want 404", w.Code)
+	}
+	SetAdminToken("secret")
+	t.Cleanup(func() { SetAdminToken("") })
+	w = httptest.NewRecorder()
+	SchedulerHandler(w, httptest.NewRequest(http.MethodGet, "/admin/scheduler", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status without token = %d, want 401", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	SchedulerHandler(w, adminRequest(http.MethodGet, "/admin/scheduler"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var stats SchedulerStats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Capacity != 7 || len(stats.Classes) != 2 || stats.Classes[0].Priority != "interactive" {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	w = httptest.NewRecorder()
+	SchedulerHandler(w, adminRequest(http.MethodPost, "/admin/scheduler"))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", w.Code)
+	}
+}
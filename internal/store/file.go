@@ -0,0 +1,357 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+const (
+	// compactMinRecords is the log size below which Open never compacts
+	compactMinRecords = 1024
+
+	// maxRecordSize bounds a single log line when replaying
+	maxRecordSize = 4 << 20
+)
+
+// ErrClosed is returned by operations on a closed FileStore.
+var ErrClosed = errors.New("store: closed")
+
+// FileStore is a Store backed by an append-only JSON-lines log file.
+//
+// Each Put appends one line, so a crash can lose at most the line being
+// written; a torn last line is cut off when the file is reopened. The file
+// is indexed in memory on Open:
+//   - latest: domain → position of its most recent record (for Get)
+//   - byTime: positions of every record sorted by check time (for Scan)
+//
+// Records are read back from disk on demand, so memory use is proportional
+// to the number of records, not their size.
+//
+// Superseded records are only removed by Compact, which rewrites the log with
+// the latest record of each domain (older history is dropped). Open compacts
+// automatically when more than half of a large log is superseded.
+type FileStore struct {
+	path string
+
+	mu      sync.RWMutex
+	file    *os.File
+	size    int64
+	latest  map[string]position
+	byTime  []position
+	records int
+	closed  bool
+}
+
+// position locates one record in the log file.
+type position struct {
+	offset    int64
+	length    int
+	checkedAt time.Time
+}
+
+// Open opens (or creates) the log file at path and indexes it.
+func Open(path string) (*FileStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("store: %w", err)
+		}
+	}
+
+	s := &FileStore{path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	if s.records >= compactMinRecords && s.records > 2*len(s.latest) {
+		if err := s.Compact(); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// load opens the file and rebuilds the in-memory index.
+func (s *FileStore) load() error {
+	f, err := os.OpenFile(s.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+
+	s.file = f
+	s.size = 0
+	s.latest = make(map[string]position)
+	s.byTime = nil
+	s.records = 0
+
+	reader := bufio.NewReaderSize(f, 64<<10)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A line without newline is a torn write from a crash: drop it
+			break
+		}
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("store: reading %s: %w", s.path, err)
+		}
+		if len(line) > maxRecordSize {
+			f.Close()
+			return fmt.Errorf("store: record at offset %d exceeds %d bytes", offset, maxRecordSize)
+		}
+
+		r, err := decodeRecord(bytes.TrimSpace(line))
+		if err == nil {
+			s.index(r.Domain.Full, position{offset: offset, length: len(line), checkedAt: r.CheckedAt})
+		}
+		// Undecodable lines (e.g. from a newer version) are skipped but kept
+		offset += int64(len(line))
+	}
+
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return fmt.Errorf("store: %w", err)
+	}
+	s.size = offset
+	return nil
+}
+
+// index adds a record to the in-memory indexes. The caller must hold s.mu
+// (or be loading).
+func (s *FileStore) index(name string, pos position) {
+	s.records++
+	if prev, ok := s.latest[name]; !ok || !pos.checkedAt.Before(prev.checkedAt) {
+		s.latest[name] = pos
+	}
+
+	// Keep byTime sorted; results almost always arrive in time order
+	i := len(s.byTime)
+	for i > 0 && s.byTime[i-1].checkedAt.After(pos.checkedAt) {
+		i--
+	}
+	s.byTime = append(s.byTime, position{})
+	copy(s.byTime[i+1:], s.byTime[i:])
+	s.byTime[i] = pos
+}
+
+// Put appends a result to the log.
+func (s *FileStore) Put(r domain.Result) error {
+	line, err := encodeRecord(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if _, err := s.file.WriteAt(line, s.size); err != nil {
+		return fmt.Errorf("store: write: %w", err)
+	}
+	s.index(r.Domain.Full, position{offset: s.size, length: len(line), checkedAt: r.CheckedAt.UTC()})
+	s.size += int64(len(line))
+
+	return nil
+}
+
+// Get returns the most recent result stored for a domain.
+func (s *FileStore) Get(name string) (domain.Result, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return domain.Result{}, false, ErrClosed
+	}
+
+	pos, ok := s.latest[name]
+	if !ok {
+		return domain.Result{}, false, nil
+	}
+	r, err := s.read(pos)
+	if err != nil {
+		return domain.Result{}, false, err
+	}
+	return r, true, nil
+}
+
+// Scan calls fn for every result checked in [from, to), oldest first.
+//
+// fn runs with the store's read lock held, so it must not call Put.
+func (s *FileStore) Scan(from, to time.Time, fn func(domain.Result) bool) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	start := 0
+	if !from.IsZero() {
+		start = sort.Search(len(s.byTime), func(i int) bool {
+			return !s.byTime[i].checkedAt.Before(from)
+		})
+	}
+
+	for _, pos := range s.byTime[start:] {
+		if !to.IsZero() && !pos.checkedAt.Before(to) {
+			break
+		}
+		r, err := s.read(pos)
+		if err != nil {
+			return err
+		}
+		if !fn(r) {
+			break
+		}
+	}
+	return nil
+}
+
+// Len returns the number of domains with at least one stored result.
+func (s *FileStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.latest)
+}
+
+// read loads one record from disk. The caller must hold s.mu.
+func (s *FileStore) read(pos position) (domain.Result, error) {
+	buf := make([]byte, pos.length)
+	if _, err := s.file.ReadAt(buf, pos.offset); err != nil {
+		return domain.Result{}, fmt.Errorf("store: read: %w", err)
+	}
+	r, err := decodeRecord(bytes.TrimSpace(buf))
+	if err != nil {
+		return domain.Result{}, fmt.Errorf("store: corrupt record at offset %d: %w", pos.offset, err)
+	}
+	return r, nil
+}
+
+// Compact rewrites the log keeping only the latest record of each domain.
+//
+// The new log is written to a temporary file, synced and atomically renamed
+// over the old one, and the directory is synced after the rename, so a crash
+// during compaction leaves either log intact. On error before the rename the
+// store keeps using the old log.
+func (s *FileStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	type record struct {
+		name string
+		pos  position
+	}
+	live := make([]record, 0, len(s.latest))
+	for name, pos := range s.latest {
+		live = append(live, record{name, pos})
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].pos.offset < live[j].pos.offset })
+
+	// The new log is written next to the old one and kept open: once renamed
+	// over the old log it becomes the store's file, so nothing can fail
+	// between replacing the log and indexing it
+	dir := filepath.Dir(s.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".compact-*")
+	if err != nil {
+		return fmt.Errorf("store: compact: %w", err)
+	}
+	fail := func(err error) error {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("store: compact: %w", err)
+	}
+	if info, err := s.file.Stat(); err == nil {
+		if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+			return fail(err)
+		}
+	}
+
+	moved := make([]position, len(live))
+	var offset int64
+	w := bufio.NewWriter(tmp)
+	for i, r := range live {
+		pos := r.pos
+		buf := make([]byte, pos.length)
+		if _, err := s.file.ReadAt(buf, pos.offset); err != nil {
+			return fail(err)
+		}
+		if _, err := w.Write(buf); err != nil {
+			return fail(err)
+		}
+		moved[i] = position{offset: offset, length: pos.length, checkedAt: pos.checkedAt}
+		offset += int64(pos.length)
+	}
+	if err := w.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fail(err)
+	}
+
+	// Swap in the new log and its index
+	s.file.Close()
+	s.file = tmp
+	s.size = offset
+	s.latest = make(map[string]position, len(moved))
+	s.byTime = make([]position, 0, len(moved))
+	s.records = 0
+	for i, pos := range moved {
+		s.index(live[i].name, pos)
+	}
+
+	// Persist the rename itself: without syncing the directory, a crash
+	// could bring back the old log
+	if err := syncDir(dir); err != nil {
+		return fmt.Errorf("store: compact: %w", err)
+	}
+	return nil
+}
+
+// syncDir fsyncs a directory, making the renames in it durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Close syncs and closes the log file.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	syncErr := s.file.Sync()
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	return syncErr
+}
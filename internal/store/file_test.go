@@ -0,0 +1,302 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+var baseTime = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func testResult(name string, status domain.Status, minute int) domain.Result {
+	return domain.Result{
+		Domain:     domain.Domain{Full: name + ".com", Name: name, TLD: "com", Suffix: "com", Unicode: name + ".com"},
+		Status:     status,
+		Available:  status.Registrable(),
+		Source:     "rdap",
+		CheckedAt:  baseTime.Add(time.Duration(minute) * time.Minute),
+		Duration:   150 * time.Millisecond,
+		Confidence: domain.ConfidenceHigh,
+	}
+}
+
+func openTemp(t *testing.T) (*FileStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "results", "log.jsonl")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, path
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	s, path := openTemp(t)
+
+	want := testResult("example", domain.StatusTaken, 0)
+	want.Registration = &domain.Registration{
+		Registrar:   "Example Registrar",
+		Created:     baseTime.AddDate(-10, 0, 0),
+		Nameservers: []string{"ns1.example.net"},
+		Entities:    []domain.Entity{{Handle: "292", Roles: []string{"registrar"}}},
+	}
+	want.Attempts = []domain.Attempt{
+		{Source: "dns", Outcome: domain.StatusUnknown, Duration: time.Millisecond, Signal: "no A/AAAA/MX/NS records"},
+		{Source: "rdap", Outcome: domain.StatusTaken, Duration: 2 * time.Millisecond, Signal: "HTTP 200"},
+	}
+
+	if err := s.Put(want); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	s.Close()
+
+	// Reopen to make sure everything came from disk
+	s2, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s2.Close()
+
+	got, ok, err := s2.Get("example.com")
+	if err != nil || !ok {
+		t.Fatalf("Get() = %v, %v", ok, err)
+	}
+	if got.Domain != want.Domain || got.Status != want.Status || got.Source != want.Source ||
+		!got.CheckedAt.Equal(want.CheckedAt) || got.Duration != want.Duration || got.Confidence != want.Confidence {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+	if got.Registration == nil || got.Registration.Registrar != "Example Registrar" ||
+		!got.Registration.Created.Equal(want.Registration.Created) || len(got.Registration.Entities) != 1 {
+		t.Errorf("Get() Registration = %+v", got.Registration)
+	}
+	if len(got.Attempts) != 2 || got.Attempts[1] != want.Attempts[1] {
+		t.Errorf("Get() Attempts = %+v", got.Attempts)
+	}
+}
+
+// TestFileStoreUnicode verifies internationalized names keep their display form.
+func TestFileStoreUnicode(t *testing.T) {
+	s, _ := openTemp(t)
+
+	want := testResult("xn--mnchen-3ya", domain.StatusAvailable, 0)
+	want.Domain.Unicode = "münchen.com"
+	if err := s.Put(want); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	got, ok, err := s.Get("xn--mnchen-3ya.com")
+	if err != nil || !ok {
+		t.Fatalf("Get() = %v, %v", ok, err)
+	}
+	if got.Domain != want.Domain {
+		t.Errorf("Get() Domain = %+v, want %+v", got.Domain, want.Domain)
+	}
+}
+
+func TestFileStoreGetLatest(t *testing.T) {
+	s, _ := openTemp(t)
+
+	s.Put(testResult("example", domain.StatusAvailable, 0))
+	s.Put(testResult("example", domain.StatusTaken, 10))
+	s.Put(testResult("example", domain.StatusError, 5)) // arrives late, older check
+
+	got, ok, err := s.Get("example.com")
+	if err != nil || !ok {
+		t.Fatalf("Get() = %v, %v", ok, err)
+	}
+	if got.Status != domain.StatusTaken {
+		t.Errorf("Get() Status = %v, want the most recently checked (taken)", got.Status)
+	}
+
+	if _, ok, _ := s.Get("missing.com"); ok {
+		t.Error("Get(missing.com) reported a result")
+	}
+}
+
+func TestFileStoreScan(t *testing.T) {
+	s, _ := openTemp(t)
+
+	for i, minute := range []int{30, 0, 10, 20} {
+		s.Put(testResult(fmt.Sprintf("d%d", i), domain.StatusTaken, minute))
+	}
+
+	var got []int
+	err := s.Scan(baseTime.Add(10*time.Minute), baseTime.Add(30*time.Minute), func(r domain.Result) bool {
+		got = append(got, int(r.CheckedAt.Sub(baseTime)/time.Minute))
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if fmt.Sprint(got) != "[10 20]" {
+		t.Errorf("Scan([10m, 30m)) minutes = %v, want [10 20]", got)
+	}
+
+	count := 0
+	s.Scan(time.Time{}, time.Time{}, func(domain.Result) bool {
+		count++
+		return count < 2
+	})
+	if count != 2 {
+		t.Errorf("Scan() did not stop when fn returned false (count = %d)", count)
+	}
+}
+
+// TestFileStoreTornWrite verifies a partial last line from a crash is discarded
+func TestFileStoreTornWrite(t *testing.T) {
+	s, path := openTemp(t)
+	s.Put(testResult("first", domain.StatusTaken, 0))
+	s.Close()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"full":"torn.com","status":"ta`)
+	f.Close()
+
+	s2, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s2.Close()
+
+	if s2.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", s2.Len())
+	}
+	if err := s2.Put(testResult("second", domain.StatusTaken, 1)); err != nil {
+		t.Fatalf("Put() after recovery error = %v", err)
+	}
+	if _, ok, err := s2.Get("second.com"); !ok || err != nil {
+		t.Errorf("Get(second.com) after recovery = %v, %v", ok, err)
+	}
+}
+
+func TestFileStoreCompact(t *testing.T) {
+	s, path := openTemp(t)
+
+	for i := 0; i < 10; i++ {
+		s.Put(testResult("a", domain.StatusTaken, i))
+		s.Put(testResult("b", domain.StatusAvailable, i))
+	}
+	before, _ := os.Stat(path)
+
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("Compact() size %d → %d, want smaller", before.Size(), after.Size())
+	}
+
+	got, ok, err := s.Get("a.com")
+	if err != nil || !ok || !got.CheckedAt.Equal(baseTime.Add(9*time.Minute)) {
+		t.Errorf("Get(a.com) after Compact() = %+v, %v, %v; want latest record", got, ok, err)
+	}
+
+	count := 0
+	s.Scan(time.Time{}, time.Time{}, func(domain.Result) bool { count++; return true })
+	if count != 2 {
+		t.Errorf("Scan() after Compact() saw %d records, want 2", count)
+	}
+
+	// The store stays writable after compaction, into the compacted log
+	if err := s.Put(testResult("c", domain.StatusTaken, 20)); err != nil {
+		t.Errorf("Put() after Compact() error = %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != before.Mode().Perm() {
+		t.Errorf("Compact() mode %v → %v, want unchanged", before.Mode().Perm(), info.Mode().Perm())
+	}
+	s.Close()
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() after Compact() error = %v", err)
+	}
+	defer reopened.Close()
+	if reopened.Len() != 3 {
+		t.Errorf("Len() after reopening = %d, want 3", reopened.Len())
+	}
+}
+
+func TestFileStoreCompactFailure(t *testing.T) {
+	s, path := openTemp(t)
+	s.Put(testResult("a", domain.StatusTaken, 0))
+	s.Put(testResult("a", domain.StatusTaken, 1))
+
+	// Replace the log's name with a non-empty directory: the rename fails
+	// after the new log was written
+	moved := path + ".moved"
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(path, "blocker"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Compact(); err == nil {
+		t.Fatal("Compact() error = nil, want rename failure")
+	}
+
+	// The store keeps serving and writing the old log, and no temporary file is left
+	if got, ok, err := s.Get("a.com"); err != nil || !ok || !got.CheckedAt.Equal(baseTime.Add(time.Minute)) {
+		t.Errorf("Get(a.com) after failed Compact() = %+v, %v, %v", got, ok, err)
+	}
+	if err := s.Put(testResult("b", domain.StatusAvailable, 2)); err != nil {
+		t.Errorf("Put() after failed Compact() error = %v", err)
+	}
+	if leftovers, _ := filepath.Glob(path + ".compact-*"); len(leftovers) != 0 {
+		t.Errorf("temporary files left: %v", leftovers)
+	}
+}
+
+func TestFileStoreConcurrentPut(t *testing.T) {
+	s, path := openTemp(t)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if err := s.Put(testResult(fmt.Sprintf("w%d-%d", w, i), domain.StatusTaken, i)); err != nil {
+					t.Errorf("Put() error = %v", err)
+				}
+				s.Get(fmt.Sprintf("w%d-%d.com", w, i))
+			}
+		}(w)
+	}
+	wg.Wait()
+	s.Close()
+
+	s2, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer s2.Close()
+	if s2.Len() != 400 {
+		t.Errorf("Len() after reopen = %d, want 400", s2.Len())
+	}
+}
+
+func TestFileStoreClosed(t *testing.T) {
+	s, _ := openTemp(t)
+	s.Close()
+
+	if err := s.Put(testResult("x", domain.StatusTaken, 0)); err != ErrClosed {
+		t.Errorf("Put() on closed store = %v, want ErrClosed", err)
+	}
+	if _, _, err := s.Get("x.com"); err != ErrClosed {
+		t.Errorf("Get() on closed store = %v, want ErrClosed", err)
+	}
+}
+
+func TestFileStoreRejectsEmptyDomain(t *testing.T) {
+	s, _ := openTemp(t)
+	if err := s.Put(domain.Result{Status: domain.StatusError}); err == nil {
+		t.Error("Put() without domain expected error")
+	}
+}
@@ -0,0 +1,181 @@
+// Package store persists domain check results across server restarts.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"domaincheck/internal/domain"
+)
+
+// Store is a persistent collection of check results.
+//
+// Every Put is kept (a domain's history), and Get returns the most recent
+// result for a domain. Implementations must be safe for concurrent use.
+type Store interface {
+	// Put persists a result. Results without a domain name are rejected.
+	Put(r domain.Result) error
+
+	// Get returns the most recent result for a normalized domain (e.g. "example.com").
+	// ok is false when the domain was never stored.
+	Get(name string) (r domain.Result, ok bool, err error)
+
+	// Scan calls fn for every result checked in [from, to), oldest first,
+	// until fn returns false. A zero from or to leaves that side unbounded.
+	Scan(from, to time.Time, fn func(domain.Result) bool) error
+
+	// Close flushes and releases the store. The store must not be used afterwards.
+	Close() error
+}
+
+// record is the lossless on-disk form of a domain.Result.
+//
+// domain.Result's own JSON is the API format, which drops fields (Name, TLD)
+// and rounds durations; records keep everything needed to restore a Result.
+type record struct {
+	Full         string          `json:"full"`
+	Name         string          `json:"name,omitempty"`
+	TLD          string          `json:"tld,omitempty"`
+	Suffix       string          `json:"suffix,omitempty"`
+	Unicode      string          `json:"unicode,omitempty"`
+	Status       string          `json:"status"`
+	Error        string          `json:"error,omitempty"`
+	ErrorCode    string          `json:"error_code,omitempty"`
+	Source       string          `json:"source,omitempty"`
+	CheckedAt    time.Time       `json:"checked_at"`
+	Duration     time.Duration   `json:"duration_ns,omitempty"`
+	Confidence   string          `json:"confidence,omitempty"`
+	Registration *registration   `json:"registration,omitempty"`
+	Attempts     []attemptRecord `json:"attempts,omitempty"`
+}
+
+// registration mirrors domain.Registration with plain JSON encoding.
+type registration struct {
+	Registrar       string          `json:"registrar,omitempty"`
+	RegistrarIANAID string          `json:"registrar_iana_id,omitempty"`
+	Created         time.Time       `json:"created"`
+	Expires         time.Time       `json:"expires"`
+	Updated         time.Time       `json:"updated"`
+	Nameservers     []string        `json:"nameservers,omitempty"`
+	DNSSEC          bool            `json:"dnssec,omitempty"`
+	Statuses        []string        `json:"statuses,omitempty"`
+	Entities        []domain.Entity `json:"entities,omitempty"`
+}
+
+// attemptRecord mirrors domain.Attempt with plain JSON encoding.
+type attemptRecord struct {
+	Source   string        `json:"source"`
+	Outcome  string        `json:"outcome"`
+	Duration time.Duration `json:"duration_ns,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Signal   string        `json:"signal,omitempty"`
+}
+
+// encodeRecord converts a result into its on-disk JSON line (without newline).
+func encodeRecord(r domain.Result) ([]byte, error) {
+	if r.Domain.Full == "" {
+		return nil, fmt.Errorf("store: result has no domain")
+	}
+
+	rec := record{
+		Full:       r.Domain.Full,
+		Name:       r.Domain.Name,
+		TLD:        r.Domain.TLD,
+		Suffix:     r.Domain.Suffix,
+		Status:     r.Status.String(),
+		Error:      r.Error,
+		ErrorCode:  r.ErrorCode,
+		Source:     r.Source,
+		CheckedAt:  r.CheckedAt.UTC(),
+		Duration:   r.Duration,
+		Confidence: r.Confidence.String(),
+	}
+	if reg := r.Registration; reg != nil {
+		rec.Registration = &registration{
+			Registrar:       reg.Registrar,
+			RegistrarIANAID: reg.RegistrarIANAID,
+			Created:         reg.Created,
+			Expires:         reg.Expires,
+			Updated:         reg.Updated,
+			Nameservers:     reg.Nameservers,
+			DNSSEC:          reg.DNSSEC,
+			Statuses:        reg.Statuses,
+			Entities:        reg.Entities,
+		}
+	}
+	if r.Domain.Unicode != r.Domain.Full {
+		rec.Unicode = r.Domain.Unicode
+	}
+	for _, a := range r.Attempts {
+		rec.Attempts = append(rec.Attempts, attemptRecord{
+			Source:   a.Source,
+			Outcome:  a.Outcome.String(),
+			Duration: a.Duration,
+			Error:    a.Error,
+			Signal:   a.Signal,
+		})
+	}
+
+	return json.Marshal(rec)
+}
+
+// decodeRecord parses an on-disk JSON line back into a result.
+func decodeRecord(line []byte) (domain.Result, error) {
+	var rec record
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return domain.Result{}, err
+	}
+	if rec.Full == "" {
+		return domain.Result{}, fmt.Errorf("record has no domain")
+	}
+
+	if rec.Unicode == "" {
+		rec.Unicode = rec.Full
+	}
+
+	status, _ := domain.ParseStatus(rec.Status)
+	confidence, _ := domain.ParseConfidence(rec.Confidence)
+	r := domain.Result{
+		Domain: domain.Domain{
+			Full:    rec.Full,
+			Name:    rec.Name,
+			TLD:     rec.TLD,
+			Suffix:  rec.Suffix,
+			Unicode: rec.Unicode,
+		},
+		Status:     status,
+		Available:  status.Registrable(),
+		Error:      rec.Error,
+		ErrorCode:  rec.ErrorCode,
+		Source:     rec.Source,
+		CheckedAt:  rec.CheckedAt,
+		Duration:   rec.Duration,
+		Confidence: confidence,
+	}
+	if reg := rec.Registration; reg != nil {
+		r.Registration = &domain.Registration{
+			Registrar:       reg.Registrar,
+			RegistrarIANAID: reg.RegistrarIANAID,
+			Created:         reg.Created,
+			Expires:         reg.Expires,
+			Updated:         reg.Updated,
+			Nameservers:     reg.Nameservers,
+			DNSSEC:          reg.DNSSEC,
+			Statuses:        reg.Statuses,
+			Entities:        reg.Entities,
+		}
+	}
+	for _, a := range rec.Attempts {
+		outcome, _ := domain.ParseStatus(a.Outcome)
+		r.Attempts = append(r.Attempts, domain.Attempt{
+			Source:   a.Source,
+			Outcome:  outcome,
+			Duration: a.Duration,
+			Error:    a.Error,
+			Signal:   a.Signal,
+		})
+	}
+
+	return r, nil
+}
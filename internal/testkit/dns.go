@@ -0,0 +1,286 @@
+package testkit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"domaincheck/internal/checker"
+)
+
+// DNSResponse is a scripted answer of the fake DNS server for one name.
+type DNSResponse struct {
+	// Rcode is the response code (e.g. checker.DNSRcodeServerFailure).
+	// A response with neither Rcode nor Records is NXDOMAIN.
+	Rcode int
+
+	// Records are the name's records; a query gets those of its type
+	// (NODATA when there are none). Records without a name are owned by the
+	// fixture's name.
+	Records []checker.DNSRecord
+
+	// Drop leaves queries unanswered, so clients time out
+	Drop bool
+}
+
+// RegisteredDNS returns the default records of a registered name: an A
+// record, an MX record and two NS records.
+func RegisteredDNS(name string) DNSResponse {
+	return DNSResponse{Records: []checker.DNSRecord{
+		{Name: name, Type: checker.DNSTypeA, TTL: 300, Data: "192.0.2.1"},
+		{Name: name, Type: checker.DNSTypeMX, TTL: 300, Data: "mail." + name},
+		{Name: name, Type: checker.DNSTypeNS, TTL: 3600, Data: "ns1." + name},
+		{Name: name, Type: checker.DNSTypeNS, TTL: 3600, Data: "ns2." + name},
+	}}
+}
+
+// DNSServer is a fake authoritative DNS server listening on UDP and TCP on
+// the same port. It serves both the system-resolver lookups of the DNS
+// pre-filter and the delegation queries of the authoritative check.
+type DNSServer struct {
+	// Addr is the server's "ip:port"
+	Addr string
+
+	fixtures map[string]DNSResponse
+
+	mu      sync.Mutex
+	queries []checker.DNSQuestion
+}
+
+// NewDNSServer starts a fake DNS server. Names missing from fixtures get
+// NXDOMAIN. The server is closed when the test ends.
+func NewDNSServer(t testing.TB, fixtures map[string]DNSResponse) *DNSServer {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("testkit: DNS server: %v", err)
+	}
+	ln, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		t.Skipf("testkit: DNS server: cannot listen on TCP port of UDP socket: %v", err)
+	}
+	t.Cleanup(func() {
+		pc.Close()
+		ln.Close()
+	})
+
+	s := &DNSServer{
+		Addr:     pc.LocalAddr().String(),
+		fixtures: make(map[string]DNSResponse, len(fixtures)),
+	}
+	for name, resp := range fixtures {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		// Copy: the caller's records may be shared between fixtures
+		resp.Records = append([]checker.DNSRecord(nil), resp.Records...)
+		for i := range resp.Records {
+			if resp.Records[i].Name == "" {
+				resp.Records[i].Name = name
+			}
+		}
+		s.fixtures[name] = resp
+	}
+
+	go s.serveUDP(pc)
+	go s.serveTCP(ln)
+	return s
+}
+
+// serveUDP answers datagrams until the socket is closed.
+func (s *DNSServer) serveUDP(pc net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if resp := s.respond(buf[:n]); resp != nil {
+			pc.WriteTo(resp, addr)
+		}
+	}
+}
+
+// serveTCP answers length-prefixed queries until the listener is closed.
+func (s *DNSServer) serveTCP(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			for {
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err != nil {
+					return
+				}
+				resp := s.respond(query)
+				if resp == nil {
+					return
+				}
+				framed := binary.BigEndian.AppendUint16(nil, uint16(len(resp)))
+				if _, err := conn.Write(append(framed, resp...)); err != nil {
+					return
+				}
+			}
+		}(conn)
+	}
+}
+
+// respond builds the response to a query, or returns nil to drop it.
+func (s *DNSServer) respond(query []byte) []byte {
+	q, err := checker.ParseDNSMessage(query)
+	if err != nil || len(q.Question) != 1 {
+		return nil
+	}
+	question := q.Question[0]
+
+	s.mu.Lock()
+	s.queries = append(s.queries, question)
+	s.mu.Unlock()
+
+	msg := &checker.DNSMessage{
+		ID:            q.ID,
+		Authoritative: true,
+		Question:      q.Question,
+	}
+	fixture, ok := s.fixtures[strings.TrimSuffix(question.Name, ".")]
+	switch {
+	case fixture.Drop:
+		return nil
+	case !ok || (fixture.Rcode == checker.DNSRcodeSuccess && len(fixture.Records) == 0):
+		msg.Rcode = checker.DNSRcodeNameError
+	default:
+		msg.Rcode = fixture.Rcode
+		for _, rr := range fixture.Records {
+			if rr.Type == question.Type {
+				msg.Answer = append(msg.Answer, rr)
+			}
+		}
+	}
+
+	resp, err := packDNSMessage(msg)
+	if err != nil {
+		// Malformed fixture records: answer SERVFAIL rather than nothing
+		msg.Rcode, msg.Answer = checker.DNSRcodeServerFailure, nil
+		resp, _ = packDNSMessage(msg)
+	}
+	return resp
+}
+
+// packDNSMessage encodes the server's response. DNSRecord only holds one value
+// per record, so MX records get preference 10 and SOA records a placeholder
+// contact and zero timers.
+func packDNSMessage(m *checker.DNSMessage) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:            m.ID,
+		Response:      true,
+		Authoritative: m.Authoritative,
+		Truncated:     m.Truncated,
+		RCode:         dnsmessage.RCode(m.Rcode),
+	})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	for _, q := range m.Question {
+		name, err := dnsName(q.Name)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.Question(dnsmessage.Question{Name: name, Type: dnsmessage.Type(q.Type), Class: dnsmessage.ClassINET}); err != nil {
+			return nil, err
+		}
+	}
+
+	sections := []struct {
+		start   func() error
+		records []checker.DNSRecord
+	}{
+		{b.StartAnswers, m.Answer},
+		{b.StartAuthorities, m.Authority},
+		{b.StartAdditionals, m.Additional},
+	}
+	for _, section := range sections {
+		if err := section.start(); err != nil {
+			return nil, err
+		}
+		for _, rr := range section.records {
+			if err := addDNSRecord(&b, rr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b.Finish()
+}
+
+// addDNSRecord adds rr to the current section of b.
+func addDNSRecord(b *dnsmessage.Builder, rr checker.DNSRecord) error {
+	name, err := dnsName(rr.Name)
+	if err != nil {
+		return err
+	}
+	h := dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: rr.TTL}
+
+	switch rr.Type {
+	case checker.DNSTypeA:
+		ip := net.ParseIP(rr.Data).To4()
+		if ip == nil {
+			return fmt.Errorf("invalid A record data: %q", rr.Data)
+		}
+		var a dnsmessage.AResource
+		copy(a.A[:], ip)
+		return b.AResource(h, a)
+	case checker.DNSTypeAAAA:
+		ip := net.ParseIP(rr.Data)
+		if ip == nil || ip.To4() != nil {
+			return fmt.Errorf("invalid AAAA record data: %q", rr.Data)
+		}
+		var aaaa dnsmessage.AAAAResource
+		copy(aaaa.AAAA[:], ip.To16())
+		return b.AAAAResource(h, aaaa)
+	}
+
+	target, err := dnsName(rr.Data)
+	if err != nil {
+		return err
+	}
+	switch rr.Type {
+	case checker.DNSTypeNS:
+		return b.NSResource(h, dnsmessage.NSResource{NS: target})
+	case checker.DNSTypeMX:
+		return b.MXResource(h, dnsmessage.MXResource{Pref: 10, MX: target})
+	case checker.DNSTypeSOA:
+		mbox, err := dnsName("hostmaster." + strings.TrimSuffix(rr.Data, "."))
+		if err != nil {
+			return err
+		}
+		return b.SOAResource(h, dnsmessage.SOAResource{NS: target, MBox: mbox})
+	default:
+		return fmt.Errorf("unsupported record type %d", rr.Type)
+	}
+}
+
+// dnsName converts a name with or without the trailing dot.
+func dnsName(name string) (dnsmessage.Name, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return dnsmessage.NewName(name)
+}
+
+// Queries returns the questions received so far, in order.
+func (s *DNSServer) Queries() []checker.DNSQuestion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]checker.DNSQuestion(nil), s.queries...)
+}
@@ -0,0 +1,63 @@
+package testkit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"domaincheck/internal/checker"
+)
+
+func TestDNSServer(t *testing.T) {
+	s := NewDNSServer(t, map[string]DNSResponse{
+		"taken.test":  RegisteredDNS("taken.test"),
+		"broken.test": {Rcode: checker.DNSRcodeServerFailure},
+		"mx.test":     {Records: []checker.DNSRecord{{Type: checker.DNSTypeMX, Data: "mail.example.net"}}},
+	})
+	Isolate(t)
+	client := &checker.DNSClient{Timeout: time.Second}
+
+	tests := []struct {
+		name       string
+		qtype      uint16
+		wantRcode  int
+		wantAnswer int
+	}{
+		{"taken.test", checker.DNSTypeA, checker.DNSRcodeSuccess, 1},
+		{"taken.test", checker.DNSTypeNS, checker.DNSRcodeSuccess, 2},
+		{"taken.test", checker.DNSTypeAAAA, checker.DNSRcodeSuccess, 0},
+		{"mx.test", checker.DNSTypeMX, checker.DNSRcodeSuccess, 1},
+		{"broken.test", checker.DNSTypeA, checker.DNSRcodeServerFailure, 0},
+		{"unknown.test", checker.DNSTypeA, checker.DNSRcodeNameError, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := client.Exchange(context.Background(), s.Addr, tt.name, tt.qtype)
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if msg.Rcode != tt.wantRcode || len(msg.Answer) != tt.wantAnswer {
+				t.Errorf("Exchange() = %s with %d answers, want rcode %d with %d", msg.RcodeName(), len(msg.Answer), tt.wantRcode, tt.wantAnswer)
+			}
+			for _, rr := range msg.Answer {
+				if rr.Name != tt.name || rr.Type != tt.qtype {
+					t.Errorf("answer %+v does not match the question", rr)
+				}
+			}
+		})
+	}
+
+	if got := len(s.Queries()); got != len(tests) {
+		t.Errorf("Queries() = %d, want %d", got, len(tests))
+	}
+}
+
+func TestDNSServerDrop(t *testing.T) {
+	s := NewDNSServer(t, map[string]DNSResponse{"dropped.test": {Drop: true}})
+	Isolate(t)
+	client := &checker.DNSClient{Timeout: 20 * time.Millisecond}
+
+	if _, err := client.Exchange(context.Background(), s.Addr, "dropped.test", checker.DNSTypeA); err == nil {
+		t.Error("Exchange() expected a timeout")
+	}
+}
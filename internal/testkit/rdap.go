@@ -0,0 +1,127 @@
+package testkit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"domaincheck/internal/checker"
+)
+
+// RDAPResponse is a scripted answer of the fake RDAP server.
+type RDAPResponse struct {
+	// Status is the HTTP status code (default 200)
+	Status int
+
+	// Body is the response body, usually an RDAP domain object in JSON
+	Body string
+
+	// Header holds extra response headers (e.g. Retry-After)
+	Header http.Header
+
+	// Delay holds the answer back, e.g. to trigger client timeouts
+	Delay time.Duration
+}
+
+// RegisteredRDAP returns the default answer for a registered name: 200 with
+// status "active", a registrar and registration/expiration events.
+func RegisteredRDAP(name string) RDAPResponse {
+	return RDAPResponse{
+		Status: http.StatusOK,
+		Body: fmt.Sprintf(`{
+  "objectClassName": "domain",
+  "ldhName": %q,
+  "status": ["active"],
+  "events": [
+    {"eventAction": "registration", "eventDate": "2020-01-02T03:04:05Z"},
+    {"eventAction": "expiration", "eventDate": "2030-01-02T03:04:05Z"}
+  ],
+  "entities": [{"roles": ["registrar"], "vcardArray": ["vcard", [["fn", {}, "text", "Testkit Registrar"]]]}],
+  "nameservers": [{"ldhName": "ns1.%s"}]
+}`, name, name),
+	}
+}
+
+// notFoundRDAP is the answer for names without a fixture.
+var notFoundRDAP = RDAPResponse{
+	Status: http.StatusNotFound,
+	Body:   `{"errorCode": 404, "title": "Not Found"}`,
+}
+
+// RDAPServer is a fake RDAP server answering GET <url>/domain/<name>.
+type RDAPServer struct {
+	*httptest.Server
+
+	fixtures map[string]RDAPResponse
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+// NewRDAPServer starts a fake RDAP server. Names missing from fixtures get a
+// 404 (available). The server is closed when the test ends.
+func NewRDAPServer(t testing.TB, fixtures map[string]RDAPResponse) *RDAPServer {
+	t.Helper()
+	s := &RDAPServer{fixtures: make(map[string]RDAPResponse, len(fixtures))}
+	for name, resp := range fixtures {
+		s.fixtures[strings.ToLower(name)] = resp
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// serve answers one RDAP query from the fixtures.
+func (s *RDAPServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Clone(r.Context()))
+	s.mu.Unlock()
+
+	name, ok := strings.CutPrefix(r.URL.Path, "/domain/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	resp, ok := s.fixtures[strings.ToLower(name)]
+	if !ok {
+		resp = notFoundRDAP
+	}
+
+	sleep(r.Context(), resp.Delay)
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.Header().Set("Content-Type", "application/rdap+json")
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	fmt.Fprint(w, resp.Body)
+}
+
+// Bootstrap returns an RDAP bootstrap registry sending the given TLDs to the server.
+func (s *RDAPServer) Bootstrap(tlds ...string) *checker.Bootstrap {
+	quoted := make([]string, len(tlds))
+	for i, tld := range tlds {
+		quoted[i] = fmt.Sprintf("%q", tld)
+	}
+	b, err := checker.ParseBootstrap([]byte(fmt.Sprintf(
+		`{"services": [[[%s], [%q]]]}`, strings.Join(quoted, ", "), s.URL+"/")))
+	if err != nil {
+		// Only reachable with malformed TLDs, a bug in the calling test
+		panic(fmt.Sprintf("testkit: bootstrap for %v: %v", tlds, err))
+	}
+	return b
+}
+
+// Requests returns the requests received so far, in order.
+func (s *RDAPServer) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
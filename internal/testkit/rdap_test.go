@@ -0,0 +1,50 @@
+package testkit
+
+import (
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestRDAPServer(t *testing.T) {
+	s := NewRDAPServer(t, map[string]RDAPResponse{
+		"taken.test": RegisteredRDAP("taken.test"),
+		"busy.test":  {Status: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"7"}}},
+	})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantHeader string
+	}{
+		{"/domain/taken.test", http.StatusOK, ""},
+		{"/domain/TAKEN.test", http.StatusOK, ""},
+		{"/domain/free.test", http.StatusNotFound, ""},
+		{"/domain/busy.test", http.StatusTooManyRequests, "7"},
+		{"/nameserver/ns1.test", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := s.Client().Get(s.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get("Retry-After"); got != tt.wantHeader {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantHeader)
+			}
+		})
+	}
+
+	if got := len(s.Requests()); got != len(tests) {
+		t.Errorf("Requests() = %d, want %d", got, len(tests))
+	}
+	base, ok := s.Bootstrap("test").Lookup("test")
+	if !ok || base != s.URL+"/" {
+		t.Errorf("Bootstrap().Lookup(test) = %q, %v, want %s/", base, ok, s.URL)
+	}
+}
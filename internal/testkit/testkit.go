@@ -0,0 +1,181 @@
+// Package testkit runs local stand-ins for the upstreams the checker queries
+// (an RDAP HTTP server, a port-43 WHOIS server and a UDP/TCP DNS server) so
+// that checks can be tested offline and deterministically.
+//
+// Each server answers from declarative fixtures mapping domain names to
+// responses. Start runs all three from one set of per-domain fixtures and
+// wires them into a checker.Checker:
+//
+//	u := testkit.Start(t, testkit.Fixtures{
+//	    "taken.test": {Registered: true},
+//	    "free.test":  {},
+//	    "flaky.test": {RDAP: &testkit.RDAPResponse{Status: 500}},
+//	})
+//	result, err := u.Checker().Check(ctx, domain.Domain{Full: "taken.test", Name: "taken", TLD: "test"})
+//
+// The servers can also be started on their own (NewRDAPServer, NewWHOISServer,
+// NewDNSServer) to test a single client.
+package testkit
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"domaincheck/internal/checker"
+)
+
+// Fixture describes how every fake upstream answers for one domain.
+//
+// Registered selects the default answers: a registered name has an A record,
+// an MX record and an NS delegation, RDAP answers 200 with status "active" and
+// WHOIS prints a registration record. An unregistered name gets NXDOMAIN, an
+// RDAP 404 and a WHOIS "No match". The other fields override single servers.
+type Fixture struct {
+	// Registered selects the default answers (see Fixture)
+	Registered bool
+
+	// DNS overrides the DNS answers, for the resolver and the authoritative check
+	DNS *DNSResponse
+
+	// RDAP overrides the RDAP answer
+	RDAP *RDAPResponse
+
+	// WHOIS overrides the WHOIS answer
+	WHOIS *WHOISResponse
+}
+
+// Fixtures maps domain names (e.g. "taken.test") to their fixture.
+// Names without a fixture are unregistered.
+type Fixtures map[string]Fixture
+
+// Upstreams is a set of running fake servers serving the same fixtures.
+type Upstreams struct {
+	RDAP  *RDAPServer
+	WHOIS *WHOISServer
+	DNS   *DNSServer
+
+	tlds []string
+}
+
+// Start runs fake RDAP, WHOIS and DNS servers answering from fixtures, for
+// every TLD used in fixtures plus the extra tlds given. The servers are
+// stopped when the test ends.
+//
+// Start also disables the process-wide rate limiter, installs fresh circuit
+// breakers and shortens retry backoff, since every fake server shares the
+// 127.0.0.1 host (see Isolate).
+func Start(t testing.TB, fixtures Fixtures, tlds ...string) *Upstreams {
+	t.Helper()
+	Isolate(t)
+
+	rdap := make(map[string]RDAPResponse, len(fixtures))
+	whois := make(map[string]WHOISResponse, len(fixtures))
+	dns := make(map[string]DNSResponse, len(fixtures))
+	seen := make(map[string]bool)
+	for _, tld := range tlds {
+		seen[strings.ToLower(tld)] = true
+	}
+
+	for name, f := range fixtures {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			seen[name[i+1:]] = true
+		}
+
+		switch {
+		case f.RDAP != nil:
+			rdap[name] = *f.RDAP
+		case f.Registered:
+			rdap[name] = RegisteredRDAP(name)
+		}
+		switch {
+		case f.WHOIS != nil:
+			whois[name] = *f.WHOIS
+		case f.Registered:
+			whois[name] = RegisteredWHOIS(name)
+		}
+		switch {
+		case f.DNS != nil:
+			dns[name] = *f.DNS
+		case f.Registered:
+			dns[name] = RegisteredDNS(name)
+		}
+	}
+
+	u := &Upstreams{
+		RDAP:  NewRDAPServer(t, rdap),
+		WHOIS: NewWHOISServer(t, whois),
+		DNS:   NewDNSServer(t, dns),
+	}
+	for tld := range seen {
+		u.tlds = append(u.tlds, tld)
+	}
+	sort.Strings(u.tlds)
+	return u
+}
+
+// TLDs returns the TLDs the servers are wired for, sorted.
+func (u *Upstreams) TLDs() []string {
+	return append([]string(nil), u.tlds...)
+}
+
+// Options returns the checker options pointing every stage at the fake
+// servers, with caching disabled.
+func (u *Upstreams) Options() []checker.Option {
+	dnsServers := make(map[string][]string, len(u.tlds))
+	whoisServers := make(map[string]string, len(u.tlds))
+	for _, tld := range u.tlds {
+		dnsServers[tld] = []string{u.DNS.Addr}
+		whoisServers[tld] = u.WHOIS.Addr
+	}
+
+	return []checker.Option{
+		checker.WithDNSServer(u.DNS.Addr),
+		checker.WithDNSClient(&checker.DNSClient{Timeout: time.Second, Servers: dnsServers}),
+		checker.WithBootstrap(u.RDAP.Bootstrap(u.tlds...)),
+		checker.WithHTTPClient(u.RDAP.Client()),
+		checker.WithWHOISClient(&checker.WHOISClient{Timeout: 2 * time.Second, Servers: whoisServers}),
+		checker.WithCache(nil),
+	}
+}
+
+// Checker returns a Checker using the fake servers (see Options); opts are
+// applied after them.
+func (u *Upstreams) Checker(opts ...checker.Option) *checker.Checker {
+	return checker.New(append(u.Options(), opts...)...)
+}
+
+// Isolate protects a test from the process-wide upstream protection, which
+// is keyed by host and would treat every local fake server as one upstream:
+// it disables rate limiting, installs fresh circuit breakers and shortens
+// retry backoff to milliseconds. The defaults are restored when the test ends.
+//
+// Tests calling Isolate must not run in parallel with checks that rely on
+// the defaults.
+func Isolate(t testing.TB) {
+	checker.SetRateLimiter(nil)
+	checker.SetBreakers(checker.NewBreakers(checker.BreakerConfig{}))
+	checker.SetRetryConfig(checker.RetryConfig{BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})
+
+	t.Cleanup(func() {
+		checker.SetRateLimiter(checker.NewRateLimiter(checker.RateLimitConfig{}))
+		checker.SetBreakers(checker.NewBreakers(checker.BreakerConfig{}))
+		checker.SetRetryConfig(checker.RetryConfig{})
+	})
+}
+
+// sleep waits for d or until ctx ends, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
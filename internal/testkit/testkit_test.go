@@ -0,0 +1,156 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"domaincheck/internal/checker"
+	"domaincheck/internal/domain"
+)
+
+// testDomain builds the domain for a "label.test" name.
+func testDomain(name string) domain.Domain {
+	label, tld, _ := strings.Cut(name, ".")
+	return domain.Domain{Full: name, Name: label, TLD: tld}
+}
+
+// TestCheckPaths runs the default pipeline against the fake upstreams
+func TestCheckPaths(t *testing.T) {
+	u := Start(t, Fixtures{
+		"taken.test": {Registered: true},
+		"free.test":  {},
+		"nodns.test": {Registered: true, DNS: &DNSResponse{}},
+		"redemption.test": {DNS: &DNSResponse{}, RDAP: &RDAPResponse{
+			Body: `{"status": ["redemption period"]}`,
+		}},
+		"rdapdown.test": {
+			RDAP:  &RDAPResponse{Status: http.StatusBadGateway},
+			WHOIS: &WHOISResponse{Text: RegisteredWHOIS("rdapdown.test").Text},
+		},
+		"alldown.test": {
+			DNS:   &DNSResponse{Rcode: checker.DNSRcodeServerFailure},
+			RDAP:  &RDAPResponse{Status: http.StatusServiceUnavailable},
+			WHOIS: &WHOISResponse{},
+		},
+	})
+	c := u.Checker()
+
+	tests := []struct {
+		name         string
+		wantStatus   domain.Status
+		wantSource   string
+		wantCode     string
+		wantAttempts int
+	}{
+		{"taken.test", domain.StatusTaken, "dns", "", 1},
+		{"free.test", domain.StatusAvailable, "rdap", "", 3},
+		{"nodns.test", domain.StatusTaken, "rdap", "", 3},
+		{"redemption.test", domain.StatusRedemptionPeriod, "rdap", "", 3},
+		{"rdapdown.test", domain.StatusTaken, "whois", "", 4},
+		{"alldown.test", domain.StatusError, "whois", checker.CodeParse, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := c.Check(context.Background(), testDomain(tt.name))
+			if tt.wantStatus == domain.StatusError {
+				if err == nil {
+					t.Fatalf("Check() = %s, want error", result.Status)
+				}
+			} else if err != nil {
+				t.Fatalf("Check() error = %v (attempts %+v)", err, result.Attempts)
+			}
+
+			if result.Status != tt.wantStatus || result.Source != tt.wantSource {
+				t.Errorf("Check() = %s from %q, want %s from %q (attempts %+v)",
+					result.Status, result.Source, tt.wantStatus, tt.wantSource, result.Attempts)
+			}
+			if result.ErrorCode != tt.wantCode {
+				t.Errorf("ErrorCode = %q, want %q", result.ErrorCode, tt.wantCode)
+			}
+			if len(result.Attempts) != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d: %+v", len(result.Attempts), tt.wantAttempts, result.Attempts)
+			}
+		})
+	}
+}
+
+// TestCheckRegistration verifies RDAP registration details reach the result
+func TestCheckRegistration(t *testing.T) {
+	u := Start(t, Fixtures{"owned.test": {Registered: true, DNS: &DNSResponse{}}})
+
+	result, err := u.Checker().Check(context.Background(), testDomain("owned.test"))
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	reg := result.Registration
+	if reg == nil {
+		t.Fatal("Registration = nil")
+	}
+	if reg.Registrar != "Testkit Registrar" || reg.Created.Year() != 2020 || len(reg.Nameservers) != 1 {
+		t.Errorf("Registration = %+v", reg)
+	}
+}
+
+// TestCheckConsensusConflict verifies sources disagreeing are flagged
+func TestCheckConsensusConflict(t *testing.T) {
+	u := Start(t, Fixtures{
+		"disputed.test": {
+			DNS:   &DNSResponse{},
+			WHOIS: &WHOISResponse{Text: RegisteredWHOIS("disputed.test").Text},
+		},
+	})
+
+	result, err := u.Checker().CheckConsensus(context.Background(), testDomain("disputed.test"))
+	if err != nil {
+		t.Fatalf("CheckConsensus() error = %v", err)
+	}
+	if result.Status != domain.StatusConflict {
+		t.Errorf("CheckConsensus() = %s, want conflict (attempts %+v)", result.Status, result.Attempts)
+	}
+}
+
+// TestCheckTimeouts verifies slow upstreams fail within the configured timeouts
+func TestCheckTimeouts(t *testing.T) {
+	u := Start(t, Fixtures{
+		"slow.test": {
+			DNS:   &DNSResponse{Drop: true},
+			RDAP:  &RDAPResponse{Status: http.StatusOK, Body: `{"status": ["active"]}`, Delay: time.Second},
+			WHOIS: &WHOISResponse{Text: "No match for \"SLOW.TEST\".\r\n", Delay: time.Second},
+		},
+	})
+	c := u.Checker(
+		checker.WithDNSTimeout(50*time.Millisecond),
+		checker.WithDNSClient(&checker.DNSClient{Timeout: 20 * time.Millisecond, Servers: map[string][]string{"test": {u.DNS.Addr}}}),
+		checker.WithRDAPTimeout(50*time.Millisecond),
+		checker.WithWHOISTimeout(50*time.Millisecond),
+	)
+
+	start := time.Now()
+	result, err := c.Check(context.Background(), testDomain("slow.test"))
+	if err == nil {
+		t.Fatalf("Check() = %s, want timeout error", result.Status)
+	}
+	if !errors.Is(err, checker.ErrTimeout) || result.ErrorCode != checker.CodeTimeout {
+		t.Errorf("Check() error = %v (code %q), want timeout", err, result.ErrorCode)
+	}
+	if took := time.Since(start); took > 900*time.Millisecond {
+		t.Errorf("Check() took %v, want the stage timeouts to apply", took)
+	}
+}
+
+// TestStartTLDs verifies the TLDs are collected from the fixtures
+func TestStartTLDs(t *testing.T) {
+	u := Start(t, Fixtures{"a.test": {}, "B.Example": {}}, "dev")
+
+	got := strings.Join(u.TLDs(), ",")
+	if got != "dev,example,test" {
+		t.Errorf("TLDs() = %s, want dev,example,test", got)
+	}
+	if _, ok := u.RDAP.Bootstrap(u.TLDs()...).Lookup("dev"); !ok {
+		t.Error("Bootstrap() does not cover the extra TLD")
+	}
+}
@@ -0,0 +1,116 @@
+package testkit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// WHOISResponse is a scripted answer of the fake WHOIS server.
+type WHOISResponse struct {
+	// Text is written back verbatim before the connection is closed.
+	// An empty Text closes the connection without an answer.
+	Text string
+
+	// Delay holds the answer back, e.g. to trigger client timeouts
+	Delay time.Duration
+}
+
+// RegisteredWHOIS returns the default answer for a registered name, in the
+// registry format used by .com.
+func RegisteredWHOIS(name string) WHOISResponse {
+	upper := strings.ToUpper(name)
+	return WHOISResponse{Text: fmt.Sprintf(
+		"   Domain Name: %s\r\n"+
+			"   Registry Domain ID: 1_DOMAIN_TEST-VRSN\r\n"+
+			"   Registrar: Testkit Registrar\r\n"+
+			"   Creation Date: 2020-01-02T03:04:05Z\r\n"+
+			"   Domain Status: clientTransferProhibited\r\n"+
+			"   Name Server: NS1.%s\r\n", upper, upper)}
+}
+
+// WHOISServer is a fake RFC 3912 WHOIS server: it reads one query line per
+// connection and answers from the fixtures.
+type WHOISServer struct {
+	// Addr is the server's "host:port"
+	Addr string
+
+	fixtures map[string]WHOISResponse
+	ln       net.Listener
+
+	mu      sync.Mutex
+	queries []string
+}
+
+// NewWHOISServer starts a fake WHOIS server. Names missing from fixtures get a
+// "No match" answer (available). The server is closed when the test ends.
+func NewWHOISServer(t testing.TB, fixtures map[string]WHOISResponse) *WHOISServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("testkit: WHOIS server: %v", err)
+	}
+
+	s := &WHOISServer{
+		Addr:     ln.Addr().String(),
+		fixtures: make(map[string]WHOISResponse, len(fixtures)),
+		ln:       ln,
+	}
+	for name, resp := range fixtures {
+		s.fixtures[strings.ToLower(name)] = resp
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		ln.Close()
+	})
+	go s.serve(ctx)
+	return s
+}
+
+// serve accepts connections until the listener is closed.
+func (s *WHOISServer) serve(ctx context.Context) {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.answer(ctx, conn)
+	}
+}
+
+// answer handles one connection.
+func (s *WHOISServer) answer(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	query := strings.ToLower(strings.TrimSpace(line))
+
+	s.mu.Lock()
+	s.queries = append(s.queries, query)
+	s.mu.Unlock()
+
+	resp, ok := s.fixtures[query]
+	if !ok {
+		resp = WHOISResponse{Text: fmt.Sprintf("No match for \"%s\".\r\n", strings.ToUpper(query))}
+	}
+	sleep(ctx, resp.Delay)
+	io.WriteString(conn, resp.Text)
+}
+
+// Queries returns the queries received so far, in order.
+func (s *WHOISServer) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
@@ -0,0 +1,42 @@
+package testkit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"domaincheck/internal/checker"
+)
+
+func TestWHOISServer(t *testing.T) {
+	s := NewWHOISServer(t, map[string]WHOISResponse{
+		"taken.test": RegisteredWHOIS("taken.test"),
+		"odd.test":   {Text: "Some unrecognized banner\r\n"},
+	})
+	Isolate(t)
+	client := &checker.WHOISClient{DisableReferrals: true}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"taken.test", "Domain Name: TAKEN.TEST"},
+		{"Odd.Test", "unrecognized banner"},
+		{"free.test", `No match for "FREE.TEST"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := client.Query(context.Background(), s.Addr, tt.query)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("Query() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := strings.Join(s.Queries(), ","); got != "taken.test,odd.test,free.test" {
+		t.Errorf("Queries() = %s", got)
+	}
+}